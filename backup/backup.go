@@ -11,6 +11,7 @@ import (
 
 	"github.com/greenplum-db/gp-common-go-libs/cluster"
 	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gp-common-go-libs/operating"
 	"github.com/greenplum-db/gpbackup/backup_filepath"
 	"github.com/greenplum-db/gpbackup/backup_history"
 	"github.com/greenplum-db/gpbackup/utils"
@@ -26,17 +27,17 @@ import (
 func initializeFlags(cmd *cobra.Command) {
 	SetFlagDefaults(cmd.Flags())
 
-	_ = cmd.MarkFlagRequired(utils.DBNAME)
-
 	cmdFlags = cmd.Flags()
 }
 
 func SetFlagDefaults(flagSet *pflag.FlagSet) {
 	flagSet.String(utils.BACKUP_DIR, "", "The absolute path of the directory to which all backup files will be written")
 	flagSet.Int(utils.COMPRESSION_LEVEL, 1, "Level of compression to use during data backup. Valid values are between 1 and 9.")
+	flagSet.String(utils.CONFIG, "", "A YAML file containing values for any of these flags, which are used for flags not specified on the command line")
 	flagSet.Bool(utils.DATA_ONLY, false, "Only back up data, do not back up metadata")
 	flagSet.String(utils.DBNAME, "", "The database to be backed up")
 	flagSet.Bool(utils.DEBUG, false, "Print verbose and debug log messages")
+	flagSet.String(utils.EMAIL_CONFIG, "", "The configuration file listing the SMTP server and contacts to email when the backup completes")
 	flagSet.StringSlice(utils.EXCLUDE_SCHEMA, []string{}, "Back up all metadata except objects in the specified schema(s). --exclude-schema can be specified multiple times.")
 	flagSet.StringSlice(utils.EXCLUDE_RELATION, []string{}, "Back up all metadata except the specified table(s). --exclude-table can be specified multiple times.")
 	flagSet.String(utils.EXCLUDE_RELATION_FILE, "", "A file containing a list of fully-qualified tables to be excluded from the backup")
@@ -47,14 +48,21 @@ func SetFlagDefaults(flagSet *pflag.FlagSet) {
 	flagSet.String(utils.INCLUDE_RELATION_FILE, "", "A file containing a list of fully-qualified tables to be included in the backup")
 	flagSet.Bool(utils.INCREMENTAL, false, "Only back up data for AO tables that have been modified since the last backup")
 	flagSet.Int(utils.JOBS, 1, "The number of parallel connections to use when backing up data")
+	flagSet.StringArray(utils.LABEL, []string{}, "A label in the format key=value to attach to the backup, which can be used to select the backup to restore. --label can be specified multiple times.")
 	flagSet.Bool(utils.LEAF_PARTITION_DATA, false, "For partition tables, create one data file per leaf partition instead of one data file for the whole table")
+	flagSet.Bool(utils.METADATA_DIRECTORY, false, "Write metadata as a directory containing one file per object instead of a single metadata file")
 	flagSet.Bool(utils.METADATA_ONLY, false, "Only back up metadata, do not back up data")
+	flagSet.String(utils.METRICS_FILE, "", "The absolute path of a file to which to write Prometheus metrics about the backup")
 	flagSet.Bool(utils.NO_COMPRESSION, false, "Disable compression of data files")
+	flagSet.String(utils.NOTIFICATION_CONFIG, "", "The configuration file listing webhooks to notify when the backup completes")
 	flagSet.String(utils.PLUGIN_CONFIG, "", "The configuration file to use for a plugin")
 	flagSet.Bool("version", false, "Print version number and exit")
 	flagSet.Bool(utils.QUIET, false, "Suppress non-warning, non-error log messages")
+	flagSet.StringSlice(utils.RETENTION, []string{}, "Retention rules in the format period=count, e.g. daily=7,weekly=4,monthly=12, after which older backups of the same database are deleted. Valid periods are daily, weekly, monthly, and yearly.")
+	flagSet.Bool(utils.RETENTION_DRY_RUN, false, "Report which backups the retention policy would delete without deleting them")
 	flagSet.Bool(utils.SINGLE_DATA_FILE, false, "Back up all data to a single file instead of one per table")
 	flagSet.Bool(utils.VERBOSE, false, "Print verbose log messages")
+	flagSet.Bool(utils.WITH_CHECKSUMS, false, "Compute a checksum of the contents of each table so that gprestore can verify the restored data")
 	flagSet.Bool(utils.WITH_STATS, false, "Back up query plan statistics")
 }
 
@@ -69,6 +77,11 @@ func DoInit(cmd *cobra.Command) {
 }
 
 func DoFlagValidation(cmd *cobra.Command) {
+	if configFile := MustGetFlagString(utils.CONFIG); configFile != "" {
+		err := utils.ApplyConfigFile(cmd.Flags(), configFile)
+		gplog.FatalOnError(err)
+	}
+	utils.CheckMandatoryFlags(cmd.Flags(), utils.DBNAME)
 	ValidateFlagCombinations(cmd.Flags())
 	ValidateFlagValues()
 }
@@ -119,6 +132,10 @@ func DoSetup() {
 		pluginConfig.CopyPluginConfigToAllHosts(globalCluster, pluginConfigFlag)
 		pluginConfig.SetupPluginForBackup(globalCluster, globalFPInfo)
 	}
+
+	backupReport.Status = backup_history.BackupStatusInProgress
+	err = backup_history.WriteBackupHistory(globalFPInfo.GetBackupHistoryFilePath(), &backupReport.BackupConfig)
+	gplog.FatalOnError(err)
 }
 
 func DoBackup() {
@@ -136,6 +153,7 @@ func DoBackup() {
 			pluginConfig.MustRestoreFile(targetBackupFPInfo.GetConfigFilePath())
 			pluginConfig.MustRestoreFile(targetBackupFPInfo.GetTOCFilePath())
 		}
+		ValidateIncrementalChain(targetBackupFPInfo)
 	}
 
 	gplog.Info("Gathering table state information")
@@ -185,11 +203,21 @@ func DoBackup() {
 		backupStatistics(metadataTables)
 	}
 
+	metadataFile.Close()
+	if MustGetFlagBool(utils.METADATA_DIRECTORY) {
+		backupMetadataDirectory(metadataFilename)
+	}
 	globalTOC.WriteToFileAndMakeReadOnly(globalFPInfo.GetTOCFilePath())
 	for connNum := 0; connNum < connectionPool.NumConns; connNum++ {
 		connectionPool.MustCommit(connNum)
 	}
-	metadataFile.Close()
+	if !backupReport.MetadataOnly {
+		tableOids := make([]uint32, 0)
+		for _, entry := range globalTOC.DataEntries {
+			tableOids = append(tableOids, entry.Oid)
+		}
+		tableDataReports.SetTableSizes(utils.GetTableSizes(connectionPool, tableOids))
+	}
 	if MustGetFlagString(utils.PLUGIN_CONFIG) != "" {
 		pluginConfig.MustBackupFile(metadataFilename)
 		pluginConfig.MustBackupFile(globalFPInfo.GetTOCFilePath())
@@ -198,8 +226,11 @@ func DoBackup() {
 		}
 	}
 
+	backupReport.Status = backup_history.BackupStatusSucceed
+	backupReport.EndTime = operating.System.Now().Format("20060102150405")
 	err := backup_history.WriteBackupHistory(globalFPInfo.GetBackupHistoryFilePath(), &backupReport.BackupConfig)
 	gplog.FatalOnError(err)
+	EnforceRetentionPolicy(globalFPInfo.GetBackupHistoryFilePath())
 }
 
 func backupGlobal(metadataFile *utils.FileWithByteCount) {
@@ -285,7 +316,7 @@ func backupPredata(metadataFile *utils.FileWithByteCount, tables []Table, tableO
 	BackupCreateSequences(metadataFile, sequences, relationMetadata)
 	constraints, conMetadata := RetrieveConstraints()
 
-	BackupDependentObjects(metadataFile, tables, protocols, metadataMap, constraints, sortables, funcInfoMap, tableOnly)
+	BackupDependentObjects(metadataFile, tables, protocols, metadataMap, constraints, sortables, sequences, funcInfoMap, tableOnly)
 
 	PrintAlterSequenceStatements(metadataFile, globalTOC, sequences, sequenceOwnerColumns)
 
@@ -368,6 +399,15 @@ func backupStatistics(tables []Table) {
 	}
 }
 
+func backupMetadataDirectory(metadataFilename string) {
+	metadataDirectory := globalFPInfo.GetMetadataDirectoryPath()
+	gplog.Info("Writing metadata in directory format to %s", metadataDirectory)
+	globalTOC.SplitMetadataFileIntoDirectory(metadataFilename, metadataDirectory, "global", "predata", "postdata")
+	if MustGetFlagBool(utils.WITH_STATS) {
+		globalTOC.SplitMetadataFileIntoDirectory(globalFPInfo.GetStatisticsFilePath(), metadataDirectory, "statistics")
+	}
+}
+
 func DoTeardown() {
 	defer func() {
 		DoCleanup()
@@ -403,6 +443,9 @@ func DoTeardown() {
 		fmt.Println(errStr)
 	}
 	errMsg := utils.ParseErrorMessage(errStr)
+	if utils.GetExitStatus(errMsg) == "failure" {
+		RecordBackupFailure(errMsg)
+	}
 
 	/*
 	 * Only create a report file if we fail after the cluster is initialized
@@ -414,6 +457,7 @@ func DoTeardown() {
 			return
 		}
 		reportFilename := globalFPInfo.GetBackupReportFilePath()
+		jsonReportFilename := globalFPInfo.GetBackupJSONReportFilePath()
 		configFilename := globalFPInfo.GetConfigFilePath()
 
 		time.Sleep(time.Second) // We sleep for 1 second to ensure multiple backups do not start within the same second.
@@ -422,7 +466,19 @@ func DoTeardown() {
 			backupReport.ConstructBackupParamsString()
 			backup_history.WriteConfigFile(&backupReport.BackupConfig, configFilename)
 			backupReport.WriteBackupReportFile(reportFilename, globalFPInfo.Timestamp, objectCounts, errMsg)
-			utils.EmailReport(globalCluster, globalFPInfo.Timestamp, reportFilename, "gpbackup")
+			jsonReport := backupReport.ConstructBackupJSONReport(globalFPInfo.Timestamp, objectCounts, tableDataReports.GetTables(), errMsg)
+			utils.WriteJSONReportFile(jsonReportFilename, jsonReport)
+			if metricsFilename := MustGetFlagString(utils.METRICS_FILE); metricsFilename != "" {
+				writeBackupMetricsFile(metricsFilename, jsonReport)
+			}
+			if emailConfig := MustGetFlagString(utils.EMAIL_CONFIG); emailConfig != "" {
+				utils.SendEmailReport(emailConfig, "gpbackup", globalFPInfo.Timestamp, jsonReport.Status, reportFilename, jsonReportFilename)
+			} else {
+				utils.EmailReport(globalCluster, globalFPInfo.Timestamp, reportFilename, "gpbackup")
+			}
+			if notificationConfig := MustGetFlagString(utils.NOTIFICATION_CONFIG); notificationConfig != "" {
+				utils.SendWebhookNotifications(notificationConfig, "gpbackup", globalFPInfo.Timestamp, jsonReport.Status, jsonReport)
+			}
 			if pluginConfig != nil {
 				err := pluginConfig.BackupFile(configFilename)
 				if err != nil {
@@ -434,6 +490,11 @@ func DoTeardown() {
 					gplog.Error(fmt.Sprintf("%v", err))
 					return
 				}
+				err = pluginConfig.BackupFile(jsonReportFilename)
+				if err != nil {
+					gplog.Error(fmt.Sprintf("%v", err))
+					return
+				}
 			}
 		}
 		if pluginConfig != nil {
@@ -442,6 +503,14 @@ func DoTeardown() {
 	}
 }
 
+func writeBackupMetricsFile(metricsFilename string, jsonReport utils.BackupJSONReport) {
+	segmentBytes := make(map[int]int64, 0)
+	if !backupReport.MetadataOnly && pluginConfig == nil {
+		segmentBytes = utils.GetSegmentDataFileSizes(globalCluster, globalFPInfo)
+	}
+	utils.WriteMetricsFile(metricsFilename, utils.ConstructBackupMetrics(metricsFilename, jsonReport, segmentBytes))
+}
+
 func DoCleanup() {
 	defer func() {
 		if err := recover(); err != nil {
@@ -452,6 +521,9 @@ func DoCleanup() {
 	}()
 
 	gplog.Verbose("Beginning cleanup")
+	if wasTerminated {
+		RecordBackupFailure("Backup was terminated by a signal")
+	}
 	if globalFPInfo.Timestamp != "" {
 		if MustGetFlagBool(utils.SINGLE_DATA_FILE) {
 			utils.CleanUpSegmentHelperProcesses(globalCluster, globalFPInfo, "backup")
@@ -471,6 +543,24 @@ func DoCleanup() {
 	}
 }
 
+/*
+ * A backup that fails or is terminated after its history entry is written is
+ * marked as failed, so that it is never used as the base of an incremental
+ * backup or selected for restore.
+ */
+func RecordBackupFailure(errMsg string) {
+	if backupReport == nil || backupReport.Status != backup_history.BackupStatusInProgress {
+		return
+	}
+	backupReport.Status = backup_history.BackupStatusFailed
+	backupReport.Error = errMsg
+	backupReport.EndTime = operating.System.Now().Format("20060102150405")
+	err := backup_history.WriteBackupHistory(globalFPInfo.GetBackupHistoryFilePath(), &backupReport.BackupConfig)
+	if err != nil {
+		gplog.Warn("Unable to record failure of backup %s in backup history file: %v", globalFPInfo.Timestamp, err)
+	}
+}
+
 func GetVersion() string {
 	return version
 }
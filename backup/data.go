@@ -12,7 +12,9 @@ import (
 
 	"github.com/greenplum-db/gp-common-go-libs/dbconn"
 	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gp-common-go-libs/operating"
 	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
 	"gopkg.in/cheggaaa/pb.v1"
 )
 
@@ -42,7 +44,7 @@ func AddTableDataEntriesToTOC(tables []Table, rowsCopiedMaps []map[uint32]int64)
 				}
 			}
 			attributes := ConstructTableAttributesList(table.ColumnDefs)
-			globalTOC.AddMasterDataEntry(table.Schema, table.Name, table.Oid, attributes, rowsCopied, table.PartitionLevelInfo.RootName)
+			globalTOC.AddMasterDataEntry(table.Schema, table.Name, table.Oid, attributes, rowsCopied, table.PartitionLevelInfo.RootName, tableChecksums[table.Oid])
 		}
 	}
 }
@@ -103,11 +105,22 @@ func BackupSingleTableData(table Table, rowsCopiedMap map[uint32]int64, counters
 		} else {
 			destinationToWrite = globalFPInfo.GetTableBackupFilePathForCopyCommand(table.Oid, utils.GetPipeThroughProgram().Extension, false)
 		}
+		startTime := operating.System.Now()
 		rowsCopied, err := CopyTableOut(connectionPool, table, destinationToWrite, whichConn)
+		tableDataReports.AddTable(table.Schema, table.Name, rowsCopied, startTime, operating.System.Now(), err)
 		if err != nil {
 			return err
 		}
 		rowsCopiedMap[table.Oid] = rowsCopied
+		if MustGetFlagBool(utils.WITH_CHECKSUMS) {
+			checksum, err := utils.GetTableChecksum(connectionPool, table.FQN(), whichConn)
+			if err != nil {
+				return errors.Wrap(err, fmt.Sprintf("Error computing checksum of table %s", table.FQN()))
+			}
+			tableChecksumsMutex.Lock()
+			tableChecksums[table.Oid] = checksum
+			tableChecksumsMutex.Unlock()
+		}
 		counters.ProgressBar.Increment()
 	}
 	return nil
@@ -124,6 +137,7 @@ func BackupDataForAllTables(tables []Table) []map[uint32]int64 {
 	counters.ProgressBar = utils.NewProgressBar(int(counters.TotalRegTables), "Tables backed up: ", utils.PB_INFO)
 	counters.ProgressBar.Start()
 	rowsCopiedMaps := make([]map[uint32]int64, connectionPool.NumConns)
+	tableChecksums = make(map[uint32]string, 0)
 	/*
 	 * We break when an interrupt is received and rely on
 	 * TerminateHangingCopySessions to kill any COPY statements
@@ -6,6 +6,7 @@ import (
 	"github.com/greenplum-db/gpbackup/backup"
 	"github.com/greenplum-db/gpbackup/backup_history"
 	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
 
 	"fmt"
 
@@ -55,6 +56,14 @@ var _ bool = Describe("backup/data tests", func() {
 			expectedDataEntries := []utils.MasterDataEntry{{Schema: "public", Name: "table", Oid: 1, AttributeString: "(a)"}}
 			Expect(toc.DataEntries).To(Equal(expectedDataEntries))
 		})
+		It("adds the checksum of a table to its entry in the TOC", func() {
+			backup.SetTableChecksums(map[uint32]string{1: "10:123456789"})
+			defer backup.SetTableChecksums(nil)
+			tables := []backup.Table{table}
+			backup.AddTableDataEntriesToTOC(tables, rowsCopiedMaps)
+			expectedDataEntries := []utils.MasterDataEntry{{Schema: "public", Name: "table", Oid: 1, AttributeString: "(a)", Checksum: "10:123456789"}}
+			Expect(toc.DataEntries).To(Equal(expectedDataEntries))
+		})
 		It("does not add an entry for an external table to the TOC", func() {
 			table.IsExternal = true
 			tables := []backup.Table{table}
@@ -171,6 +180,49 @@ var _ bool = Describe("backup/data tests", func() {
 			Expect(rowsCopiedMap[0]).To(Equal(int64(10)))
 			Expect(counters.NumRegTables).To(Equal(int64(1)))
 		})
+		It("computes the checksum of a table after backing it up", func() {
+			cmdFlags.Set(utils.WITH_CHECKSUMS, "true")
+			backup.SetTableChecksums(map[uint32]string{})
+			defer backup.SetTableChecksums(nil)
+			toc := &utils.TOC{}
+			backup.SetTOC(toc)
+
+			mock.ExpectExec("COPY (.*)").WillReturnResult(sqlmock.NewResult(0, 10))
+			mock.ExpectQuery(regexp.QuoteMeta("FROM public.testtable gpbackup_row")).WillReturnRows(sqlmock.NewRows([]string{"string"}).AddRow("10:123456789"))
+			err := backup.BackupSingleTableData(testTable, rowsCopiedMap, &counters, 0)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			backup.AddTableDataEntriesToTOC([]backup.Table{testTable}, []map[uint32]int64{rowsCopiedMap})
+			Expect(toc.DataEntries[0].Checksum).To(Equal("10:123456789"))
+		})
+		It("returns an error if the checksum of a table cannot be computed", func() {
+			cmdFlags.Set(utils.WITH_CHECKSUMS, "true")
+
+			mock.ExpectExec("COPY (.*)").WillReturnResult(sqlmock.NewResult(0, 10))
+			mock.ExpectQuery(regexp.QuoteMeta("FROM public.testtable gpbackup_row")).WillReturnError(errors.New("permission denied"))
+			err := backup.BackupSingleTableData(testTable, rowsCopiedMap, &counters, 0)
+
+			Expect(err).To(MatchError("Error computing checksum of table public.testtable: permission denied"))
+		})
+		It("records the result of a failed table backup for the JSON report", func() {
+			testTable.Name = "failedtable"
+			mock.ExpectExec("COPY (.*)").WillReturnError(errors.New("permission denied"))
+			err := backup.BackupSingleTableData(testTable, rowsCopiedMap, &counters, 0)
+
+			Expect(err).Should(HaveOccurred())
+			Expect(rowsCopiedMap).To(BeEmpty())
+			var tableReport *utils.TableDataReport
+			tableReports := backup.GetTableDataReports()
+			for i := range tableReports {
+				if tableReports[i].Name == "failedtable" {
+					tableReport = &tableReports[i]
+				}
+			}
+			Expect(tableReport).ToNot(BeNil())
+			Expect(tableReport.Schema).To(Equal("public"))
+			Expect(tableReport.Rows).To(Equal(int64(0)))
+			Expect(tableReport.Error).To(Equal("permission denied"))
+		})
 		It("backs up a single external table", func() {
 			cmdFlags.Set(utils.LEAF_PARTITION_DATA, "false")
 			testTable.IsExternal = true
@@ -20,7 +20,7 @@ import (
  *   - Protocols
  */
 func AddProtocolDependenciesForGPDB4(depMap DependencyMap, tables []Table, protocols []ExternalProtocol) {
-	protocolMap := make(map[string]UniqueID, len(protocols))
+	protocolMap := make(map[string]utils.UniqueID, len(protocols))
 	for _, p := range protocols {
 		protocolMap[p.Name] = p.GetUniqueID()
 	}
@@ -31,7 +31,7 @@ func AddProtocolDependenciesForGPDB4(depMap DependencyMap, tables []Table, proto
 			if protocolEntry, ok := protocolMap[protocolName]; ok {
 				tableEntry := table.GetUniqueID()
 				if _, ok := depMap[tableEntry]; !ok {
-					depMap[tableEntry] = make(map[UniqueID]bool, 0)
+					depMap[tableEntry] = make(map[utils.UniqueID]bool, 0)
 				}
 				depMap[tableEntry][protocolEntry] = true
 			}
@@ -108,17 +108,17 @@ func ConstructDependentObjectMetadataMap(functions MetadataMap, types MetadataMa
 
 type Sortable interface {
 	FQN() string
-	GetUniqueID() UniqueID
+	GetUniqueID() utils.UniqueID
 }
 
 func TopologicalSort(slice []Sortable, dependencies DependencyMap) []Sortable {
-	inDegrees := make(map[UniqueID]int, 0)
-	dependencyIndexes := make(map[UniqueID]int, 0)
-	isDependentOn := make(map[UniqueID][]UniqueID, 0)
+	inDegrees := make(map[utils.UniqueID]int, 0)
+	dependencyIndexes := make(map[utils.UniqueID]int, 0)
+	isDependentOn := make(map[utils.UniqueID][]utils.UniqueID, 0)
 	queue := make([]Sortable, 0)
 	sorted := make([]Sortable, 0)
-	notVisited := make(map[UniqueID]bool, 0)
-	nameForUniqueID := make(map[UniqueID]string, 0)
+	notVisited := make(map[utils.UniqueID]bool, 0)
+	nameForUniqueID := make(map[utils.UniqueID]string, 0)
 	for i, item := range slice {
 		uniqueID := item.GetUniqueID()
 		nameForUniqueID[uniqueID] = item.FQN()
@@ -162,15 +162,10 @@ func TopologicalSort(slice []Sortable, dependencies DependencyMap) []Sortable {
 	return sorted
 }
 
-type DependencyMap map[UniqueID]map[UniqueID]bool
-
-type UniqueID struct {
-	ClassID uint32
-	Oid     uint32
-}
+type DependencyMap map[utils.UniqueID]map[utils.UniqueID]bool
 
 // This function only returns dependencies that are referenced in the backup set
-func GetDependencies(connectionPool *dbconn.DBConn, backupSet map[UniqueID]bool) DependencyMap {
+func GetDependencies(connectionPool *dbconn.DBConn, backupSet map[utils.UniqueID]bool) DependencyMap {
 	query := fmt.Sprintf(`SELECT
 	coalesce(id1.refclassid, d.classid) AS classid,
 	coalesce(id1.refobjid, d.objid) AS objid,
@@ -207,11 +202,11 @@ AND typelem != 0`)
 
 	dependencyMap := make(DependencyMap, 0)
 	for _, dep := range pgDependDeps {
-		object := UniqueID{
+		object := utils.UniqueID{
 			ClassID: dep.ClassID,
 			Oid:     dep.ObjID,
 		}
-		referenceObject := UniqueID{
+		referenceObject := utils.UniqueID{
 			ClassID: dep.RefClassID,
 			Oid:     dep.RefObjID,
 		}
@@ -224,7 +219,7 @@ AND typelem != 0`)
 		}
 
 		if _, ok := dependencyMap[object]; !ok {
-			dependencyMap[object] = make(map[UniqueID]bool, 0)
+			dependencyMap[object] = make(map[utils.UniqueID]bool, 0)
 		}
 
 		dependencyMap[object][referenceObject] = true
@@ -235,6 +230,57 @@ AND typelem != 0`)
 	return dependencyMap
 }
 
+/*
+ * Column defaults and table constraints depend on the functions, sequences,
+ * and tables that they use, but are not part of the table's own dependencies,
+ * so they are not used to sort objects.  They are recorded in the TOC so that
+ * a table can be restored along with the objects it needs.
+ */
+func AddColumnDefaultAndConstraintDependencies(connectionPool *dbconn.DBConn, depMap DependencyMap, backupSet map[utils.UniqueID]bool) {
+	query := `SELECT
+	'pg_class'::regclass::oid AS classid,
+	a.adrelid AS objid,
+	d.refclassid,
+	d.refobjid
+FROM pg_depend d
+JOIN pg_attrdef a ON d.objid = a.oid
+WHERE d.classid = 'pg_attrdef'::regclass::oid
+AND d.deptype = 'n'
+UNION
+SELECT
+	'pg_class'::regclass::oid AS classid,
+	c.conrelid AS objid,
+	d.refclassid,
+	d.refobjid
+FROM pg_depend d
+JOIN pg_constraint c ON d.objid = c.oid
+WHERE d.classid = 'pg_constraint'::regclass::oid
+AND c.conrelid != 0
+AND d.deptype = 'n'`
+
+	tableDeps := make([]struct {
+		ClassID    uint32
+		ObjID      uint32
+		RefClassID uint32
+		RefObjID   uint32
+	}, 0)
+
+	err := connectionPool.Select(&tableDeps, query)
+	gplog.FatalOnError(err)
+
+	for _, dep := range tableDeps {
+		table := utils.UniqueID{ClassID: dep.ClassID, Oid: dep.ObjID}
+		referenceObject := utils.UniqueID{ClassID: dep.RefClassID, Oid: dep.RefObjID}
+		if table == referenceObject || !backupSet[table] || !backupSet[referenceObject] {
+			continue
+		}
+		if _, ok := depMap[table]; !ok {
+			depMap[table] = make(map[utils.UniqueID]bool, 0)
+		}
+		depMap[table][referenceObject] = true
+	}
+}
+
 func breakCircularDependencies(depMap DependencyMap) {
 	for entry, deps := range depMap {
 		for dep := range deps {
@@ -251,12 +297,13 @@ func breakCircularDependencies(depMap DependencyMap) {
 	}
 }
 
-func PrintDependentObjectStatements(metadataFile *utils.FileWithByteCount, toc *utils.TOC, objects []Sortable, metadataMap MetadataMap, constraints []Constraint, funcInfoMap map[uint32]FunctionInfo) {
+func PrintDependentObjectStatements(metadataFile *utils.FileWithByteCount, toc *utils.TOC, objects []Sortable, metadataMap MetadataMap, constraints []Constraint, funcInfoMap map[uint32]FunctionInfo, dependencies DependencyMap) {
 	conMap := make(map[string][]Constraint)
 	for _, constraint := range constraints {
 		conMap[constraint.OwningObject] = append(conMap[constraint.OwningObject], constraint)
 	}
 	for _, object := range objects {
+		numEntries := len(toc.PredataEntries)
 		objMetadata := metadataMap[object.GetUniqueID()]
 		switch obj := object.(type) {
 		case BaseType:
@@ -298,5 +345,10 @@ func PrintDependentObjectStatements(metadataFile *utils.FileWithByteCount, toc *
 		case UserMapping:
 			PrintCreateUserMappingStatement(metadataFile, toc, obj)
 		}
+		objectDeps := make([]utils.UniqueID, 0, len(dependencies[object.GetUniqueID()]))
+		for dep := range dependencies[object.GetUniqueID()] {
+			objectDeps = append(objectDeps, dep)
+		}
+		toc.SetPredataDependencies(numEntries, object.GetUniqueID(), objectDeps)
 	}
 }
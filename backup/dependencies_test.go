@@ -4,6 +4,7 @@ import (
 	"github.com/greenplum-db/gp-common-go-libs/testhelper"
 	"github.com/greenplum-db/gpbackup/backup"
 	"github.com/greenplum-db/gpbackup/testutils"
+	"github.com/greenplum-db/gpbackup/utils"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -14,14 +15,14 @@ var _ = Describe("backup/dependencies tests", func() {
 		relation1 backup.Relation
 		relation2 backup.Relation
 		relation3 backup.Relation
-		depMap    map[backup.UniqueID]map[backup.UniqueID]bool
+		depMap    map[utils.UniqueID]map[utils.UniqueID]bool
 	)
 
 	BeforeEach(func() {
 		relation1 = backup.Relation{Schema: "public", Name: "relation1", Oid: 1}
 		relation2 = backup.Relation{Schema: "public", Name: "relation2", Oid: 2}
 		relation3 = backup.Relation{Schema: "public", Name: "relation3", Oid: 3}
-		depMap = make(map[backup.UniqueID]map[backup.UniqueID]bool, 0)
+		depMap = make(map[utils.UniqueID]map[utils.UniqueID]bool, 0)
 		toc, backupfile = testutils.InitializeTestTOC(buffer, "predata")
 	})
 	Describe("TopologicalSort", func() {
@@ -35,7 +36,7 @@ var _ = Describe("backup/dependencies tests", func() {
 			Expect(relations[2].FQN()).To(Equal("public.relation3"))
 		})
 		It("sorts the slice correctly if there is an object dependent on one other object", func() {
-			depMap[backup.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: 1}] = map[backup.UniqueID]bool{{ClassID: backup.PG_CLASS_OID, Oid: 3}: true}
+			depMap[utils.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: 1}] = map[utils.UniqueID]bool{{ClassID: backup.PG_CLASS_OID, Oid: 3}: true}
 			relations := []backup.Sortable{relation1, relation2, relation3}
 
 			relations = backup.TopologicalSort(relations, depMap)
@@ -45,8 +46,8 @@ var _ = Describe("backup/dependencies tests", func() {
 			Expect(relations[2].FQN()).To(Equal("public.relation1"))
 		})
 		It("sorts the slice correctly if there are two objects dependent on one other object", func() {
-			depMap[backup.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: 1}] = map[backup.UniqueID]bool{{ClassID: backup.PG_CLASS_OID, Oid: 2}: true}
-			depMap[backup.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: 3}] = map[backup.UniqueID]bool{{ClassID: backup.PG_CLASS_OID, Oid: 2}: true}
+			depMap[utils.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: 1}] = map[utils.UniqueID]bool{{ClassID: backup.PG_CLASS_OID, Oid: 2}: true}
+			depMap[utils.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: 3}] = map[utils.UniqueID]bool{{ClassID: backup.PG_CLASS_OID, Oid: 2}: true}
 			relations := []backup.Sortable{relation1, relation2, relation3}
 
 			relations = backup.TopologicalSort(relations, depMap)
@@ -56,7 +57,7 @@ var _ = Describe("backup/dependencies tests", func() {
 			Expect(relations[2].FQN()).To(Equal("public.relation3"))
 		})
 		It("sorts the slice correctly if there is one object dependent on two other objects", func() {
-			depMap[backup.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: 2}] = map[backup.UniqueID]bool{{ClassID: backup.PG_CLASS_OID, Oid: 1}: true, {ClassID: backup.PG_CLASS_OID, Oid: 1}: true}
+			depMap[utils.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: 2}] = map[utils.UniqueID]bool{{ClassID: backup.PG_CLASS_OID, Oid: 1}: true, {ClassID: backup.PG_CLASS_OID, Oid: 1}: true}
 			relations := []backup.Sortable{relation1, relation2, relation3}
 
 			relations = backup.TopologicalSort(relations, depMap)
@@ -66,9 +67,9 @@ var _ = Describe("backup/dependencies tests", func() {
 			Expect(relations[2].FQN()).To(Equal("public.relation2"))
 		})
 		It("aborts if dependency loop (this shouldn't be possible)", func() {
-			depMap[backup.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: 1}] = map[backup.UniqueID]bool{{ClassID: backup.PG_CLASS_OID, Oid: 3}: true}
-			depMap[backup.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: 2}] = map[backup.UniqueID]bool{{ClassID: backup.PG_CLASS_OID, Oid: 1}: true}
-			depMap[backup.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: 3}] = map[backup.UniqueID]bool{{ClassID: backup.PG_CLASS_OID, Oid: 2}: true}
+			depMap[utils.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: 1}] = map[utils.UniqueID]bool{{ClassID: backup.PG_CLASS_OID, Oid: 3}: true}
+			depMap[utils.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: 2}] = map[utils.UniqueID]bool{{ClassID: backup.PG_CLASS_OID, Oid: 1}: true}
+			depMap[utils.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: 3}] = map[utils.UniqueID]bool{{ClassID: backup.PG_CLASS_OID, Oid: 2}: true}
 
 			sortable := []backup.Sortable{relation1, relation2, relation3}
 			defer func() {
@@ -86,8 +87,8 @@ var _ = Describe("backup/dependencies tests", func() {
 			sortable = backup.TopologicalSort(sortable, depMap)
 		})
 		It("aborts if dependencies are not met", func() {
-			depMap[backup.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: 1}] = map[backup.UniqueID]bool{{ClassID: backup.PG_CLASS_OID, Oid: 2}: true}
-			depMap[backup.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: 1}] = map[backup.UniqueID]bool{{ClassID: backup.PG_CLASS_OID, Oid: 4}: true}
+			depMap[utils.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: 1}] = map[utils.UniqueID]bool{{ClassID: backup.PG_CLASS_OID, Oid: 2}: true}
+			depMap[utils.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: 1}] = map[utils.UniqueID]bool{{ClassID: backup.PG_CLASS_OID, Oid: 4}: true}
 			sortable := []backup.Sortable{relation1, relation2}
 
 			defer testhelper.ShouldPanicWithMessage("Dependency resolution failed; see log file gbytes.Buffer for details. This is a bug, please report.")
@@ -96,24 +97,24 @@ var _ = Describe("backup/dependencies tests", func() {
 	})
 	Describe("ConstructDependentObjectMetadataMap", func() {
 		It("composes metadata maps for functions, types, and tables into one map", func() {
-			funcMap := backup.MetadataMap{backup.UniqueID{Oid: 1}: backup.ObjectMetadata{Comment: "function"}}
-			typeMap := backup.MetadataMap{backup.UniqueID{Oid: 2}: backup.ObjectMetadata{Comment: "type"}}
-			tableMap := backup.MetadataMap{backup.UniqueID{Oid: 3}: backup.ObjectMetadata{Comment: "relation"}}
-			protoMap := backup.MetadataMap{backup.UniqueID{Oid: 4}: backup.ObjectMetadata{Comment: "protocol"}}
-			tsParserMap := backup.MetadataMap{backup.UniqueID{Oid: 5}: backup.ObjectMetadata{Comment: "text search parser"}}
-			tsConfigMap := backup.MetadataMap{backup.UniqueID{Oid: 6}: backup.ObjectMetadata{Comment: "text search config"}}
-			tsTemplateMap := backup.MetadataMap{backup.UniqueID{Oid: 7}: backup.ObjectMetadata{Comment: "text search template"}}
-			tsDictionaryMap := backup.MetadataMap{backup.UniqueID{Oid: 8}: backup.ObjectMetadata{Comment: "text search dictionary"}}
+			funcMap := backup.MetadataMap{utils.UniqueID{Oid: 1}: backup.ObjectMetadata{Comment: "function"}}
+			typeMap := backup.MetadataMap{utils.UniqueID{Oid: 2}: backup.ObjectMetadata{Comment: "type"}}
+			tableMap := backup.MetadataMap{utils.UniqueID{Oid: 3}: backup.ObjectMetadata{Comment: "relation"}}
+			protoMap := backup.MetadataMap{utils.UniqueID{Oid: 4}: backup.ObjectMetadata{Comment: "protocol"}}
+			tsParserMap := backup.MetadataMap{utils.UniqueID{Oid: 5}: backup.ObjectMetadata{Comment: "text search parser"}}
+			tsConfigMap := backup.MetadataMap{utils.UniqueID{Oid: 6}: backup.ObjectMetadata{Comment: "text search config"}}
+			tsTemplateMap := backup.MetadataMap{utils.UniqueID{Oid: 7}: backup.ObjectMetadata{Comment: "text search template"}}
+			tsDictionaryMap := backup.MetadataMap{utils.UniqueID{Oid: 8}: backup.ObjectMetadata{Comment: "text search dictionary"}}
 			result := backup.ConstructDependentObjectMetadataMap(funcMap, typeMap, tableMap, protoMap, tsParserMap, tsConfigMap, tsTemplateMap, tsDictionaryMap)
 			expected := backup.MetadataMap{
-				backup.UniqueID{Oid: 1}: backup.ObjectMetadata{Comment: "function"},
-				backup.UniqueID{Oid: 2}: backup.ObjectMetadata{Comment: "type"},
-				backup.UniqueID{Oid: 3}: backup.ObjectMetadata{Comment: "relation"},
-				backup.UniqueID{Oid: 4}: backup.ObjectMetadata{Comment: "protocol"},
-				backup.UniqueID{Oid: 5}: backup.ObjectMetadata{Comment: "text search parser"},
-				backup.UniqueID{Oid: 6}: backup.ObjectMetadata{Comment: "text search config"},
-				backup.UniqueID{Oid: 7}: backup.ObjectMetadata{Comment: "text search template"},
-				backup.UniqueID{Oid: 8}: backup.ObjectMetadata{Comment: "text search dictionary"},
+				utils.UniqueID{Oid: 1}: backup.ObjectMetadata{Comment: "function"},
+				utils.UniqueID{Oid: 2}: backup.ObjectMetadata{Comment: "type"},
+				utils.UniqueID{Oid: 3}: backup.ObjectMetadata{Comment: "relation"},
+				utils.UniqueID{Oid: 4}: backup.ObjectMetadata{Comment: "protocol"},
+				utils.UniqueID{Oid: 5}: backup.ObjectMetadata{Comment: "text search parser"},
+				utils.UniqueID{Oid: 6}: backup.ObjectMetadata{Comment: "text search config"},
+				utils.UniqueID{Oid: 7}: backup.ObjectMetadata{Comment: "text search template"},
+				utils.UniqueID{Oid: 8}: backup.ObjectMetadata{Comment: "text search dictionary"},
 			}
 			Expect(result).To(Equal(expected))
 		})
@@ -143,20 +144,20 @@ var _ = Describe("backup/dependencies tests", func() {
 				backup.RangeType{Oid: 7, Schema: "public", Name: "rangetype1"},
 			}
 			metadataMap = backup.MetadataMap{
-				backup.UniqueID{ClassID: backup.PG_PROC_OID, Oid: 1}:        backup.ObjectMetadata{Comment: "function"},
-				backup.UniqueID{ClassID: backup.PG_TYPE_OID, Oid: 2}:        backup.ObjectMetadata{Comment: "base type"},
-				backup.UniqueID{ClassID: backup.PG_TYPE_OID, Oid: 3}:        backup.ObjectMetadata{Comment: "composite type"},
-				backup.UniqueID{ClassID: backup.PG_TYPE_OID, Oid: 4}:        backup.ObjectMetadata{Comment: "domain"},
-				backup.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: 5}:       backup.ObjectMetadata{Comment: "relation"},
-				backup.UniqueID{ClassID: backup.PG_EXTPROTOCOL_OID, Oid: 6}: backup.ObjectMetadata{Comment: "protocol"},
-				backup.UniqueID{ClassID: backup.PG_TYPE_OID, Oid: 7}:        backup.ObjectMetadata{Comment: "range type"},
+				utils.UniqueID{ClassID: backup.PG_PROC_OID, Oid: 1}:        backup.ObjectMetadata{Comment: "function"},
+				utils.UniqueID{ClassID: backup.PG_TYPE_OID, Oid: 2}:        backup.ObjectMetadata{Comment: "base type"},
+				utils.UniqueID{ClassID: backup.PG_TYPE_OID, Oid: 3}:        backup.ObjectMetadata{Comment: "composite type"},
+				utils.UniqueID{ClassID: backup.PG_TYPE_OID, Oid: 4}:        backup.ObjectMetadata{Comment: "domain"},
+				utils.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: 5}:       backup.ObjectMetadata{Comment: "relation"},
+				utils.UniqueID{ClassID: backup.PG_EXTPROTOCOL_OID, Oid: 6}: backup.ObjectMetadata{Comment: "protocol"},
+				utils.UniqueID{ClassID: backup.PG_TYPE_OID, Oid: 7}:        backup.ObjectMetadata{Comment: "range type"},
 			}
 		})
 		It("prints create statements for dependent types, functions, protocols, and tables (domain has a constraint)", func() {
 			constraints := []backup.Constraint{
 				{Name: "check_constraint", ConDef: "CHECK (VALUE > 2)", OwningObject: "public.domain"},
 			}
-			backup.PrintDependentObjectStatements(backupfile, toc, objects, metadataMap, constraints, funcInfoMap)
+			backup.PrintDependentObjectStatements(backupfile, toc, objects, metadataMap, constraints, funcInfoMap, backup.DependencyMap{})
 			testhelper.ExpectRegexp(buffer, `
 CREATE FUNCTION public.function(integer, integer) RETURNS integer AS
 $_$SELECT $1 + $2$_$
@@ -203,7 +204,7 @@ COMMENT ON PROTOCOL ext_protocol IS 'protocol';
 		})
 		It("prints create statements for dependent types, functions, protocols, and tables (no domain constraint)", func() {
 			constraints := []backup.Constraint{}
-			backup.PrintDependentObjectStatements(backupfile, toc, objects, metadataMap, constraints, funcInfoMap)
+			backup.PrintDependentObjectStatements(backupfile, toc, objects, metadataMap, constraints, funcInfoMap, backup.DependencyMap{})
 			testhelper.ExpectRegexp(buffer, `
 CREATE FUNCTION public.function(integer, integer) RETURNS integer AS
 $_$SELECT $1 + $2$_$
@@ -247,5 +248,27 @@ CREATE TRUSTED PROTOCOL ext_protocol (readfunc = public.read_from_s3, writefunc
 COMMENT ON PROTOCOL ext_protocol IS 'protocol';
 `)
 		})
+		It("records each object and the objects it depends on in the TOC entries for that object", func() {
+			functionID := utils.UniqueID{ClassID: backup.PG_PROC_OID, Oid: 1}
+			baseTypeID := utils.UniqueID{ClassID: backup.PG_TYPE_OID, Oid: 2}
+			tableID := utils.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: 5}
+			dependencies := backup.DependencyMap{
+				tableID:    {functionID: true, baseTypeID: true},
+				baseTypeID: {functionID: true},
+			}
+			backup.PrintDependentObjectStatements(backupfile, toc, []backup.Sortable{objects[0], objects[4]}, metadataMap, []backup.Constraint{}, funcInfoMap, dependencies)
+
+			Expect(toc.PredataEntries).To(HaveLen(4))
+			for _, entry := range toc.PredataEntries[:2] {
+				Expect(entry.ObjectType).To(Equal("FUNCTION"))
+				Expect(entry.ID).To(Equal(functionID))
+				Expect(entry.Dependencies).To(BeEmpty())
+			}
+			for _, entry := range toc.PredataEntries[2:] {
+				Expect(entry.ObjectType).To(Equal("TABLE"))
+				Expect(entry.ID).To(Equal(tableID))
+				Expect(entry.Dependencies).To(Equal([]utils.UniqueID{baseTypeID, functionID}))
+			}
+		})
 	})
 })
@@ -32,6 +32,13 @@ var (
 	wasTerminated  bool
 	backupLockFile lockfile.Lockfile
 
+	// Per-table results of the data backup, for the JSON report
+	tableDataReports utils.TableDataReportList
+
+	// Content checksums of the backed up tables by table oid, with --with-checksums
+	tableChecksums      map[uint32]string
+	tableChecksumsMutex sync.Mutex
+
 	/*
 	 * Used for synchronizing DoCleanup.  In DoInit() we increment the group
 	 * and then wait for at least one DoCleanup to finish, either in DoTeardown
@@ -77,6 +84,14 @@ func GetReport() *utils.Report {
 	return backupReport
 }
 
+func GetTableDataReports() []utils.TableDataReport {
+	return tableDataReports.GetTables()
+}
+
+func SetTableChecksums(checksums map[uint32]string) {
+	tableChecksums = checksums
+}
+
 func SetTOC(toc *utils.TOC) {
 	globalTOC = toc
 }
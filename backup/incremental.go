@@ -3,6 +3,7 @@ package backup
 import (
 	"github.com/greenplum-db/gp-common-go-libs/gplog"
 	"github.com/greenplum-db/gp-common-go-libs/iohelper"
+	"github.com/greenplum-db/gpbackup/backup_filepath"
 	"github.com/greenplum-db/gpbackup/backup_history"
 	"github.com/greenplum-db/gpbackup/utils"
 	"github.com/pkg/errors"
@@ -57,6 +58,9 @@ func GetLatestMatchingBackupTimestamp() string {
 
 func GetLatestMatchingBackupConfig(history *backup_history.History, currentBackupConfig *backup_history.BackupConfig) *backup_history.BackupConfig {
 	for _, backupConfig := range history.BackupConfigs {
+		if backupConfig.Deleted || !backupConfig.IsSuccessful() {
+			continue
+		}
 		if MatchesIncrementalFlags(&backupConfig, currentBackupConfig) {
 			return &backupConfig
 		}
@@ -79,6 +83,32 @@ func MatchesIncrementalFlags(backupConfig *backup_history.BackupConfig, currentB
 		utils.NewIncludeSet(backupConfig.ExcludeSchemas).Equals(utils.NewIncludeSet(MustGetFlagStringSlice(utils.EXCLUDE_SCHEMA)))
 }
 
+/*
+ * An incremental backup can only be restored if every backup in the chain it
+ * is based on can be, so the chain is checked before building on it.
+ */
+func ValidateIncrementalChain(targetFPInfo backup_filepath.FilePathInfo) {
+	fpInfoList := []backup_filepath.FilePathInfo{targetFPInfo}
+	if iohelper.FileExistsAndIsReadable(targetFPInfo.GetConfigFilePath()) {
+		restorePlan := backup_history.ReadConfigFile(targetFPInfo.GetConfigFilePath()).RestorePlan
+		if len(restorePlan) > 0 {
+			fpInfoList = make([]backup_filepath.FilePathInfo, 0)
+			for _, entry := range restorePlan {
+				fpInfoList = append(fpInfoList, backup_filepath.NewFilePathInfo(globalCluster, targetFPInfo.UserSpecifiedBackupDir,
+					entry.Timestamp, targetFPInfo.UserSpecifiedSegPrefix))
+			}
+		}
+	}
+	problems := utils.ValidateBackupChain(globalCluster, fpInfoList, pluginConfig, true)
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			gplog.Error(problem)
+		}
+		gplog.Fatal(errors.Errorf("Incremental backup chain of backup %s is incomplete", targetFPInfo.Timestamp),
+			"Cannot base an incremental backup on it.  Please take a full backup.")
+	}
+}
+
 func PopulateRestorePlan(changedTables []Table,
 	restorePlan []backup_history.RestorePlanEntry, allTables []Table) []backup_history.RestorePlanEntry {
 	currBackupRestorePlanEntry := backup_history.RestorePlanEntry{
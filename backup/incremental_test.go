@@ -90,6 +90,19 @@ var _ = Describe("backup/incremental tests", func() {
 
 			structmatcher.ExpectStructsToMatch(history.BackupConfigs[1], latestBackupHistoryEntry)
 		})
+		It("Should skip backups that did not succeed or have been deleted", func() {
+			historyWithFailures := backup_history.History{BackupConfigs: []backup_history.BackupConfig{
+				{DatabaseName: "test1", Timestamp: "timestamp4", Status: backup_history.BackupStatusInProgress},
+				{DatabaseName: "test1", Timestamp: "timestamp3", Status: backup_history.BackupStatusFailed},
+				{DatabaseName: "test1", Timestamp: "timestamp2", Deleted: true},
+				{DatabaseName: "test1", Timestamp: "timestamp1", Status: backup_history.BackupStatusSucceed},
+			}}
+			currentBackupConfig := backup_history.BackupConfig{DatabaseName: "test1"}
+
+			latestBackupHistoryEntry := backup.GetLatestMatchingBackupConfig(&historyWithFailures, &currentBackupConfig)
+
+			structmatcher.ExpectStructsToMatch(historyWithFailures.BackupConfigs[3], latestBackupHistoryEntry)
+		})
 		It("should return nil with no matching Dbname", func() {
 			currentBackupConfig := backup_history.BackupConfig{DatabaseName: "test3"}
 
@@ -50,7 +50,7 @@ type ACL struct {
 	ConnectWithGrant    bool
 }
 
-type MetadataMap map[UniqueID]ObjectMetadata
+type MetadataMap map[utils.UniqueID]ObjectMetadata
 
 func PrintStatements(metadataFile *utils.FileWithByteCount, toc *utils.TOC, obj utils.TOCObject, statements []string) {
 	for _, statement := range statements {
@@ -92,7 +92,7 @@ func ConstructMetadataMap(results []MetadataQueryStruct) MetadataMap {
 	}
 	var metadata ObjectMetadata
 	quotedRoleNames := GetQuotedRoleNames(connectionPool)
-	currentUniqueID := UniqueID{}
+	currentUniqueID := utils.UniqueID{}
 	// Collect all entries for the same object into one ObjectMetadata
 	for _, result := range results {
 		privilegesStr := ""
@@ -102,7 +102,7 @@ func ConstructMetadataMap(results []MetadataQueryStruct) MetadataMap {
 			privilegesStr = result.Privileges.String
 		}
 		if result.UniqueID != currentUniqueID {
-			if (currentUniqueID != UniqueID{}) {
+			if (currentUniqueID != utils.UniqueID{}) {
 				metadata.Privileges = sortACLs(metadata.Privileges)
 				metadataMap[currentUniqueID] = metadata
 			}
@@ -7,6 +7,7 @@ import (
 	"github.com/greenplum-db/gp-common-go-libs/testhelper"
 	"github.com/greenplum-db/gpbackup/backup"
 	"github.com/greenplum-db/gpbackup/testutils"
+	"github.com/greenplum-db/gpbackup/utils"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -264,11 +265,11 @@ ALTER DEFAULT PRIVILEGES FOR ROLE testrole GRANT USAGE ON TABLES TO somerole WIT
 		})
 	})
 	Describe("ConstructMetadataMap", func() {
-		object1A := backup.MetadataQueryStruct{UniqueID: backup.UniqueID{Oid: 1}, Privileges: sql.NullString{String: "gpadmin=r/gpadmin", Valid: true}, Kind: "", Owner: "testrole", Comment: ""}
-		object1B := backup.MetadataQueryStruct{UniqueID: backup.UniqueID{Oid: 1}, Privileges: sql.NullString{String: "testrole=r/testrole", Valid: true}, Kind: "", Owner: "testrole", Comment: ""}
-		object2 := backup.MetadataQueryStruct{UniqueID: backup.UniqueID{Oid: 2}, Privileges: sql.NullString{String: "testrole=r/testrole", Valid: true}, Kind: "", Owner: "testrole", Comment: "this is a comment", SecurityLabelProvider: "some_provider", SecurityLabel: "some_label"}
-		objectDefaultKind := backup.MetadataQueryStruct{UniqueID: backup.UniqueID{Oid: 3}, Privileges: sql.NullString{String: "", Valid: false}, Kind: "Default", Owner: "testrole", Comment: ""}
-		objectEmptyKind := backup.MetadataQueryStruct{UniqueID: backup.UniqueID{Oid: 4}, Privileges: sql.NullString{String: "", Valid: false}, Kind: "Empty", Owner: "testrole", Comment: ""}
+		object1A := backup.MetadataQueryStruct{UniqueID: utils.UniqueID{Oid: 1}, Privileges: sql.NullString{String: "gpadmin=r/gpadmin", Valid: true}, Kind: "", Owner: "testrole", Comment: ""}
+		object1B := backup.MetadataQueryStruct{UniqueID: utils.UniqueID{Oid: 1}, Privileges: sql.NullString{String: "testrole=r/testrole", Valid: true}, Kind: "", Owner: "testrole", Comment: ""}
+		object2 := backup.MetadataQueryStruct{UniqueID: utils.UniqueID{Oid: 2}, Privileges: sql.NullString{String: "testrole=r/testrole", Valid: true}, Kind: "", Owner: "testrole", Comment: "this is a comment", SecurityLabelProvider: "some_provider", SecurityLabel: "some_label"}
+		objectDefaultKind := backup.MetadataQueryStruct{UniqueID: utils.UniqueID{Oid: 3}, Privileges: sql.NullString{String: "", Valid: false}, Kind: "Default", Owner: "testrole", Comment: ""}
+		objectEmptyKind := backup.MetadataQueryStruct{UniqueID: utils.UniqueID{Oid: 4}, Privileges: sql.NullString{String: "", Valid: false}, Kind: "Empty", Owner: "testrole", Comment: ""}
 		var metadataList []backup.MetadataQueryStruct
 		BeforeEach(func() {
 			rolnames := sqlmock.NewRows([]string{"rolename", "quotedrolename"}).
@@ -287,7 +288,7 @@ ALTER DEFAULT PRIVILEGES FOR ROLE testrole GRANT USAGE ON TABLES TO somerole WIT
 			metadataMap := backup.ConstructMetadataMap(metadataList)
 			expectedObjectMetadata := backup.ObjectMetadata{Privileges: []backup.ACL{{Grantee: "testrole", Select: true}}, Owner: "testrole", Comment: "this is a comment", SecurityLabelProvider: "some_provider", SecurityLabel: "some_label"}
 			Expect(metadataMap).To(HaveLen(1))
-			Expect(metadataMap[backup.UniqueID{Oid: 2}]).To(Equal(expectedObjectMetadata))
+			Expect(metadataMap[utils.UniqueID{Oid: 2}]).To(Equal(expectedObjectMetadata))
 
 		})
 		It("One object with two ACL entries", func() {
@@ -295,7 +296,7 @@ ALTER DEFAULT PRIVILEGES FOR ROLE testrole GRANT USAGE ON TABLES TO somerole WIT
 			metadataMap := backup.ConstructMetadataMap(metadataList)
 			expectedObjectMetadata := backup.ObjectMetadata{Privileges: []backup.ACL{{Grantee: "gpadmin", Select: true}, {Grantee: "testrole", Select: true}}, Owner: "testrole"}
 			Expect(metadataMap).To(HaveLen(1))
-			Expect(metadataMap[backup.UniqueID{Oid: 1}]).To(Equal(expectedObjectMetadata))
+			Expect(metadataMap[utils.UniqueID{Oid: 1}]).To(Equal(expectedObjectMetadata))
 		})
 		It("Multiple objects", func() {
 			metadataList = []backup.MetadataQueryStruct{object1A, object1B, object2}
@@ -303,22 +304,22 @@ ALTER DEFAULT PRIVILEGES FOR ROLE testrole GRANT USAGE ON TABLES TO somerole WIT
 			expectedObjectMetadataOne := backup.ObjectMetadata{Privileges: []backup.ACL{{Grantee: "gpadmin", Select: true}, {Grantee: "testrole", Select: true}}, Owner: "testrole"}
 			expectedObjectMetadataTwo := backup.ObjectMetadata{Privileges: []backup.ACL{{Grantee: "testrole", Select: true}}, Owner: "testrole", Comment: "this is a comment", SecurityLabelProvider: "some_provider", SecurityLabel: "some_label"}
 			Expect(metadataMap).To(HaveLen(2))
-			Expect(metadataMap[backup.UniqueID{Oid: 1}]).To(Equal(expectedObjectMetadataOne))
-			Expect(metadataMap[backup.UniqueID{Oid: 2}]).To(Equal(expectedObjectMetadataTwo))
+			Expect(metadataMap[utils.UniqueID{Oid: 1}]).To(Equal(expectedObjectMetadataOne))
+			Expect(metadataMap[utils.UniqueID{Oid: 2}]).To(Equal(expectedObjectMetadataTwo))
 		})
 		It("Default Kind", func() {
 			metadataList = []backup.MetadataQueryStruct{objectDefaultKind}
 			metadataMap := backup.ConstructMetadataMap(metadataList)
 			expectedObjectMetadata := backup.ObjectMetadata{Privileges: []backup.ACL{}, Owner: "testrole"}
 			Expect(metadataMap).To(HaveLen(1))
-			Expect(metadataMap[backup.UniqueID{Oid: 3}]).To(Equal(expectedObjectMetadata))
+			Expect(metadataMap[utils.UniqueID{Oid: 3}]).To(Equal(expectedObjectMetadata))
 		})
 		It("'Empty' Kind", func() {
 			metadataList = []backup.MetadataQueryStruct{objectEmptyKind}
 			metadataMap := backup.ConstructMetadataMap(metadataList)
 			expectedObjectMetadata := backup.ObjectMetadata{Privileges: []backup.ACL{{Grantee: "GRANTEE"}}, Owner: "testrole"}
 			Expect(metadataMap).To(HaveLen(1))
-			Expect(metadataMap[backup.UniqueID{Oid: 4}]).To(Equal(expectedObjectMetadata))
+			Expect(metadataMap[utils.UniqueID{Oid: 4}]).To(Equal(expectedObjectMetadata))
 		})
 	})
 	Describe("ParseACL", func() {
@@ -280,6 +280,7 @@ func PrintCreateSequenceStatements(metadataFile *utils.FileWithByteCount, toc *u
 	maxVal := int64(math.MaxInt64)
 	minVal := int64(math.MinInt64)
 	for _, sequence := range sequences {
+		numEntries := len(toc.PredataEntries)
 		start := metadataFile.ByteCount
 		metadataFile.MustPrintln("\n\nCREATE SEQUENCE", sequence.FQN())
 		if connectionPool.Version.AtLeast("6") {
@@ -310,6 +311,7 @@ func PrintCreateSequenceStatements(metadataFile *utils.FileWithByteCount, toc *u
 		section, entry := sequence.GetMetadataEntry()
 		toc.AddMetadataEntry(section, entry, start, metadataFile.ByteCount)
 		PrintObjectMetadata(metadataFile, toc, sequenceMetadata[sequence.Relation.GetUniqueID()], sequence, "")
+		toc.SetPredataDependencies(numEntries, sequence.Relation.GetUniqueID(), nil)
 	}
 }
 
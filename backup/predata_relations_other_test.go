@@ -36,6 +36,7 @@ var _ = Describe("backup/predata_relations tests", func() {
 			sequences := []backup.Sequence{seqDefault}
 			backup.PrintCreateSequenceStatements(backupfile, toc, sequences, emptySequenceMetadataMap)
 			testutils.ExpectEntry(toc.PredataEntries, 0, "public", "", "seq_name", "SEQUENCE")
+			Expect(toc.PredataEntries[0].ID).To(Equal(utils.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: 1}))
 			testutils.AssertBufferContents(toc.PredataEntries, buffer, `CREATE SEQUENCE public.seq_name
 	INCREMENT BY 1
 	NO MAXVALUE
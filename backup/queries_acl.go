@@ -106,7 +106,7 @@ func InitializeMetadataParams(connectionPool *dbconn.DBConn) {
 }
 
 type MetadataQueryStruct struct {
-	UniqueID
+	utils.UniqueID
 	Privileges            sql.NullString
 	Kind                  string
 	Owner                 string
@@ -215,7 +215,7 @@ SELECT
 `, params.CatalogTable, params.OidField, params.CatalogTable, descTable, params.OidField, commentTable, subidStr, schemaStr)
 
 	results := make([]struct {
-		UniqueID
+		utils.UniqueID
 		Comment string
 	}, 0)
 	err := connectionPool.Select(&results, query)
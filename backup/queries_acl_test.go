@@ -6,6 +6,7 @@ import (
 
 	"github.com/greenplum-db/gp-common-go-libs/structmatcher"
 	"github.com/greenplum-db/gpbackup/backup"
+	"github.com/greenplum-db/gpbackup/utils"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -103,8 +104,8 @@ ORDER BY o.oid;`)).WillReturnRows(emptyRows)
 				{Grantee: "testrole", Insert: true},
 			}, Owner: "testrole"}
 			expectedTwo := backup.ObjectMetadata{Privileges: []backup.ACL{}, Owner: "testrole", Comment: "This is a metadata comment."}
-			resultOne := resultMetadataMap[backup.UniqueID{Oid: 1}]
-			resultTwo := resultMetadataMap[backup.UniqueID{Oid: 2}]
+			resultOne := resultMetadataMap[utils.UniqueID{Oid: 1}]
+			resultTwo := resultMetadataMap[utils.UniqueID{Oid: 2}]
 			Expect(resultMetadataMap).To(HaveLen(2))
 			structmatcher.ExpectStructsToMatch(&expectedOne, &resultOne)
 			structmatcher.ExpectStructsToMatch(&expectedTwo, &resultTwo)
@@ -153,8 +154,8 @@ FROM table o JOIN pg_shdescription d ON (d.objoid = oid AND d.classoid = 'table'
 
 			expectedOne := backup.ObjectMetadata{Privileges: []backup.ACL{}, Comment: "This is a metadata comment."}
 			expectedTwo := backup.ObjectMetadata{Privileges: []backup.ACL{}, Comment: "This is also a metadata comment."}
-			resultOne := resultMetadataMap[backup.UniqueID{Oid: 1}]
-			resultTwo := resultMetadataMap[backup.UniqueID{Oid: 2}]
+			resultOne := resultMetadataMap[utils.UniqueID{Oid: 1}]
+			resultTwo := resultMetadataMap[utils.UniqueID{Oid: 2}]
 			Expect(resultMetadataMap).To(HaveLen(2))
 			structmatcher.ExpectStructsToMatch(&expectedOne, &resultOne)
 			structmatcher.ExpectStructsToMatch(&expectedTwo, &resultTwo)
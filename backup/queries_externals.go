@@ -122,8 +122,8 @@ func (p ExternalProtocol) GetMetadataEntry() (string, utils.MetadataEntry) {
 		}
 }
 
-func (p ExternalProtocol) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_EXTPROTOCOL_OID, Oid: p.Oid}
+func (p ExternalProtocol) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_EXTPROTOCOL_OID, Oid: p.Oid}
 }
 
 func (p ExternalProtocol) FQN() string {
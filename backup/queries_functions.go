@@ -51,8 +51,8 @@ func (f Function) GetMetadataEntry() (string, utils.MetadataEntry) {
 		}
 }
 
-func (f Function) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_PROC_OID, Oid: f.Oid}
+func (f Function) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_PROC_OID, Oid: f.Oid}
 }
 
 func (f Function) FQN() string {
@@ -385,8 +385,8 @@ func (a Aggregate) GetMetadataEntry() (string, utils.MetadataEntry) {
 		}
 }
 
-func (a Aggregate) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_AGGREGATE_OID, Oid: a.Oid}
+func (a Aggregate) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_AGGREGATE_OID, Oid: a.Oid}
 }
 
 func (a Aggregate) FQN() string {
@@ -595,8 +595,8 @@ func (c Cast) GetMetadataEntry() (string, utils.MetadataEntry) {
 		}
 }
 
-func (c Cast) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_CAST_OID, Oid: c.Oid}
+func (c Cast) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_CAST_OID, Oid: c.Oid}
 }
 
 func (c Cast) FQN() string {
@@ -674,8 +674,8 @@ func (e Extension) GetMetadataEntry() (string, utils.MetadataEntry) {
 		}
 }
 
-func (e Extension) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_EXTENSION_OID, Oid: e.Oid}
+func (e Extension) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_EXTENSION_OID, Oid: e.Oid}
 }
 
 func (e Extension) FQN() string {
@@ -721,8 +721,8 @@ func (pl ProceduralLanguage) GetMetadataEntry() (string, utils.MetadataEntry) {
 		}
 }
 
-func (pl ProceduralLanguage) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_LANGUAGE_OID, Oid: pl.Oid}
+func (pl ProceduralLanguage) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_LANGUAGE_OID, Oid: pl.Oid}
 }
 
 func (pl ProceduralLanguage) FQN() string {
@@ -792,8 +792,8 @@ func (c Conversion) GetMetadataEntry() (string, utils.MetadataEntry) {
 		}
 }
 
-func (c Conversion) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_CONVERSION_OID, Oid: c.Oid}
+func (c Conversion) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_CONVERSION_OID, Oid: c.Oid}
 }
 
 func (c Conversion) FQN() string {
@@ -844,8 +844,8 @@ func (fdw ForeignDataWrapper) GetMetadataEntry() (string, utils.MetadataEntry) {
 		}
 }
 
-func (fdw ForeignDataWrapper) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_FOREIGN_DATA_WRAPPER_OID, Oid: fdw.Oid}
+func (fdw ForeignDataWrapper) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_FOREIGN_DATA_WRAPPER_OID, Oid: fdw.Oid}
 }
 
 func (fdw ForeignDataWrapper) FQN() string {
@@ -894,8 +894,8 @@ func (fs ForeignServer) GetMetadataEntry() (string, utils.MetadataEntry) {
 		}
 }
 
-func (fs ForeignServer) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_FOREIGN_SERVER_OID, Oid: fs.Oid}
+func (fs ForeignServer) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_FOREIGN_SERVER_OID, Oid: fs.Oid}
 }
 
 func (fs ForeignServer) FQN() string {
@@ -944,8 +944,8 @@ func (um UserMapping) GetMetadataEntry() (string, utils.MetadataEntry) {
 		}
 }
 
-func (um UserMapping) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_USER_MAPPING_OID, Oid: um.Oid}
+func (um UserMapping) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_USER_MAPPING_OID, Oid: um.Oid}
 }
 
 func (um UserMapping) FQN() string {
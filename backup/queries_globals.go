@@ -58,8 +58,8 @@ func (db Database) GetMetadataEntry() (string, utils.MetadataEntry) {
 		}
 }
 
-func (db Database) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_DATABASE_OID, Oid: db.Oid}
+func (db Database) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_DATABASE_OID, Oid: db.Oid}
 }
 
 func (db Database) FQN() string {
@@ -152,8 +152,8 @@ func (rq ResourceQueue) GetMetadataEntry() (string, utils.MetadataEntry) {
 		}
 }
 
-func (rq ResourceQueue) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_RESQUEUE_OID, Oid: rq.Oid}
+func (rq ResourceQueue) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_RESQUEUE_OID, Oid: rq.Oid}
 }
 
 func (rq ResourceQueue) FQN() string {
@@ -214,8 +214,8 @@ func (rg ResourceGroup) GetMetadataEntry() (string, utils.MetadataEntry) {
 		}
 }
 
-func (rg ResourceGroup) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_RESGROUP_OID, Oid: rg.Oid}
+func (rg ResourceGroup) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_RESGROUP_OID, Oid: rg.Oid}
 }
 
 func (rg ResourceGroup) FQN() string {
@@ -297,8 +297,8 @@ func (r Role) GetMetadataEntry() (string, utils.MetadataEntry) {
 		}
 }
 
-func (r Role) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_AUTHID_OID, Oid: r.Oid}
+func (r Role) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_AUTHID_OID, Oid: r.Oid}
 }
 
 func (r Role) FQN() string {
@@ -509,8 +509,8 @@ func (t Tablespace) GetMetadataEntry() (string, utils.MetadataEntry) {
 		}
 }
 
-func (t Tablespace) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_TABLESPACE_OID, Oid: t.Oid}
+func (t Tablespace) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_TABLESPACE_OID, Oid: t.Oid}
 }
 
 func (t Tablespace) FQN() string {
@@ -40,8 +40,8 @@ func (o Operator) GetMetadataEntry() (string, utils.MetadataEntry) {
 		}
 }
 
-func (o Operator) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_OPERATOR_OID, Oid: o.Oid}
+func (o Operator) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_OPERATOR_OID, Oid: o.Oid}
 }
 
 func (o Operator) FQN() string {
@@ -128,8 +128,8 @@ func (opf OperatorFamily) GetMetadataEntry() (string, utils.MetadataEntry) {
 		}
 }
 
-func (opf OperatorFamily) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_OPFAMILY_OID, Oid: opf.Oid}
+func (opf OperatorFamily) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_OPFAMILY_OID, Oid: opf.Oid}
 }
 
 func (opf OperatorFamily) FQN() string {
@@ -179,8 +179,8 @@ func (opc OperatorClass) GetMetadataEntry() (string, utils.MetadataEntry) {
 		}
 }
 
-func (opc OperatorClass) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_OPCLASS_OID, Oid: opc.Oid}
+func (opc OperatorClass) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_OPCLASS_OID, Oid: opc.Oid}
 }
 
 func (opc OperatorClass) FQN() string {
@@ -69,8 +69,8 @@ func (i IndexDefinition) GetMetadataEntry() (string, utils.MetadataEntry) {
 		}
 }
 
-func (i IndexDefinition) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_INDEX_OID, Oid: i.Oid}
+func (i IndexDefinition) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_INDEX_OID, Oid: i.Oid}
 }
 
 func (i IndexDefinition) FQN() string {
@@ -184,8 +184,8 @@ func (r RuleDefinition) GetMetadataEntry() (string, utils.MetadataEntry) {
 		}
 }
 
-func (r RuleDefinition) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_REWRITE_OID, Oid: r.Oid}
+func (r RuleDefinition) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_REWRITE_OID, Oid: r.Oid}
 }
 
 func (r RuleDefinition) FQN() string {
@@ -237,8 +237,8 @@ func (t TriggerDefinition) GetMetadataEntry() (string, utils.MetadataEntry) {
 		}
 }
 
-func (t TriggerDefinition) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_TRIGGER_OID, Oid: t.Oid}
+func (t TriggerDefinition) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_TRIGGER_OID, Oid: t.Oid}
 }
 
 func (t TriggerDefinition) FQN() string {
@@ -295,8 +295,8 @@ func (et EventTrigger) GetMetadataEntry() (string, utils.MetadataEntry) {
 		}
 }
 
-func (et EventTrigger) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_EVENT_TRIGGER, Oid: et.Oid}
+func (et EventTrigger) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_EVENT_TRIGGER, Oid: et.Oid}
 }
 
 func (et EventTrigger) FQN() string {
@@ -63,8 +63,8 @@ func (r Relation) FQN() string {
 	return utils.MakeFQN(r.Schema, r.Name)
 }
 
-func (r Relation) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_CLASS_OID, Oid: r.Oid}
+func (r Relation) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_CLASS_OID, Oid: r.Oid}
 }
 
 /*
@@ -295,8 +295,8 @@ func (v View) GetMetadataEntry() (string, utils.MetadataEntry) {
 		}
 }
 
-func (v View) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_CLASS_OID, Oid: v.Oid}
+func (v View) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_CLASS_OID, Oid: v.Oid}
 }
 
 func (v View) FQN() string {
@@ -43,8 +43,8 @@ func (s Schema) GetMetadataEntry() (string, utils.MetadataEntry) {
 		}
 }
 
-func (s Schema) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_NAMESPACE_OID, Oid: s.Oid}
+func (s Schema) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_NAMESPACE_OID, Oid: s.Oid}
 }
 
 func (s Schema) FQN() string {
@@ -97,8 +97,8 @@ func (c Constraint) GetMetadataEntry() (string, utils.MetadataEntry) {
 		}
 }
 
-func (c Constraint) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_CONSTRAINT_OID, Oid: c.Oid}
+func (c Constraint) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_CONSTRAINT_OID, Oid: c.Oid}
 }
 
 func (c Constraint) FQN() string {
@@ -39,8 +39,8 @@ func (tsp TextSearchParser) GetMetadataEntry() (string, utils.MetadataEntry) {
 		}
 }
 
-func (tsp TextSearchParser) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_TS_PARSER_OID, Oid: tsp.Oid}
+func (tsp TextSearchParser) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_TS_PARSER_OID, Oid: tsp.Oid}
 }
 
 func (tsp TextSearchParser) FQN() string {
@@ -90,8 +90,8 @@ func (tst TextSearchTemplate) GetMetadataEntry() (string, utils.MetadataEntry) {
 		}
 }
 
-func (tst TextSearchTemplate) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_TS_TEMPLATE_OID, Oid: tst.Oid}
+func (tst TextSearchTemplate) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_TS_TEMPLATE_OID, Oid: tst.Oid}
 }
 
 func (tst TextSearchTemplate) FQN() string {
@@ -138,8 +138,8 @@ func (tsd TextSearchDictionary) GetMetadataEntry() (string, utils.MetadataEntry)
 		}
 }
 
-func (tsd TextSearchDictionary) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_TS_DICT_OID, Oid: tsd.Oid}
+func (tsd TextSearchDictionary) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_TS_DICT_OID, Oid: tsd.Oid}
 }
 
 func (tsd TextSearchDictionary) FQN() string {
@@ -188,8 +188,8 @@ func (tsc TextSearchConfiguration) GetMetadataEntry() (string, utils.MetadataEnt
 		}
 }
 
-func (tsc TextSearchConfiguration) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_TS_CONFIG_OID, Oid: tsc.Oid}
+func (tsc TextSearchConfiguration) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_TS_CONFIG_OID, Oid: tsc.Oid}
 }
 
 func (tsc TextSearchConfiguration) FQN() string {
@@ -53,8 +53,8 @@ func (t BaseType) GetMetadataEntry() (string, utils.MetadataEntry) {
 	return GetTypeMetadataEntry(t.Schema, t.Name)
 }
 
-func (t BaseType) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_TYPE_OID, Oid: t.Oid}
+func (t BaseType) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_TYPE_OID, Oid: t.Oid}
 }
 
 func (t BaseType) FQN() string {
@@ -209,8 +209,8 @@ func (t CompositeType) GetMetadataEntry() (string, utils.MetadataEntry) {
 	return GetTypeMetadataEntry(t.Schema, t.Name)
 }
 
-func (t CompositeType) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_TYPE_OID, Oid: t.Oid}
+func (t CompositeType) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_TYPE_OID, Oid: t.Oid}
 }
 
 func (t CompositeType) FQN() string {
@@ -325,8 +325,8 @@ func (t Domain) GetMetadataEntry() (string, utils.MetadataEntry) {
 		}
 }
 
-func (t Domain) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_TYPE_OID, Oid: t.Oid}
+func (t Domain) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_TYPE_OID, Oid: t.Oid}
 }
 
 func (t Domain) FQN() string {
@@ -391,8 +391,8 @@ func (t EnumType) GetMetadataEntry() (string, utils.MetadataEntry) {
 	return GetTypeMetadataEntry(t.Schema, t.Name)
 }
 
-func (t EnumType) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_TYPE_OID, Oid: t.Oid}
+func (t EnumType) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_TYPE_OID, Oid: t.Oid}
 }
 
 func (t EnumType) FQN() string {
@@ -441,8 +441,8 @@ func (t RangeType) GetMetadataEntry() (string, utils.MetadataEntry) {
 	return GetTypeMetadataEntry(t.Schema, t.Name)
 }
 
-func (t RangeType) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_TYPE_OID, Oid: t.Oid}
+func (t RangeType) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_TYPE_OID, Oid: t.Oid}
 }
 
 func (t RangeType) FQN() string {
@@ -500,8 +500,8 @@ func (t ShellType) GetMetadataEntry() (string, utils.MetadataEntry) {
 	return GetTypeMetadataEntry(t.Schema, t.Name)
 }
 
-func (t ShellType) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_TYPE_OID, Oid: t.Oid}
+func (t ShellType) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_TYPE_OID, Oid: t.Oid}
 }
 
 func (t ShellType) FQN() string {
@@ -547,8 +547,8 @@ func (c Collation) GetMetadataEntry() (string, utils.MetadataEntry) {
 		}
 }
 
-func (c Collation) GetUniqueID() UniqueID {
-	return UniqueID{ClassID: PG_COLLATION_OID, Oid: c.Oid}
+func (c Collation) GetUniqueID() utils.UniqueID {
+	return utils.UniqueID{ClassID: PG_COLLATION_OID, Oid: c.Oid}
 }
 
 func (c Collation) FQN() string {
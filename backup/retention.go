@@ -0,0 +1,105 @@
+package backup
+
+/*
+ * This file contains functions related to deleting the backups that have
+ * expired under the retention policy given with --retention.
+ */
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gpbackup/backup_filepath"
+	"github.com/greenplum-db/gpbackup/backup_history"
+	"github.com/greenplum-db/gpbackup/utils"
+)
+
+/*
+ * The current backup has already succeeded by the time retention is enforced,
+ * so failures to delete expired backups are logged as warnings and listed in
+ * the report rather than failing the backup.
+ */
+func EnforceRetentionPolicy(historyFilename string) {
+	policy, _ := backup_history.ParseRetentionPolicy(MustGetFlagStringSlice(utils.RETENTION))
+	if len(policy) == 0 {
+		return
+	}
+	history, err := backup_history.NewHistory(historyFilename)
+	if err != nil {
+		gplog.Warn("Unable to read backup history file %s to enforce retention policy: %v", historyFilename, err)
+		return
+	}
+	expired := history.GetExpiredBackups(&backupReport.BackupConfig, policy)
+	dryRun := MustGetFlagBool(utils.RETENTION_DRY_RUN)
+	if dryRun {
+		if len(expired) > 0 {
+			gplog.Info("Retention policy dry run: the following backups would be deleted: %s", strings.Join(expired, ", "))
+		} else {
+			gplog.Info("Retention policy dry run: no backups would be deleted")
+		}
+		backupReport.RetentionSummary = ConstructRetentionSummary(policy, expired, nil, true)
+		return
+	}
+
+	deleted := make([]string, 0)
+	failed := make([]string, 0)
+	for _, timestamp := range expired {
+		gplog.Info("Deleting backup %s, which has expired under the retention policy", timestamp)
+		err = DeleteExpiredBackup(timestamp)
+		if err != nil {
+			gplog.Warn("Unable to delete expired backup %s: %v", timestamp, err)
+			failed = append(failed, timestamp)
+			continue
+		}
+		deleted = append(deleted, timestamp)
+	}
+	if len(deleted) > 0 {
+		err = backup_history.UpdateHistoryFile(historyFilename, func(history *backup_history.History) {
+			markBackupsDeleted(history, deleted)
+		})
+		if err != nil {
+			gplog.Warn("Unable to mark expired backups as deleted in backup history file %s: %v", historyFilename, err)
+		}
+	}
+	backupReport.RetentionSummary = ConstructRetentionSummary(policy, deleted, failed, false)
+}
+
+func markBackupsDeleted(history *backup_history.History, timestamps []string) {
+	for i := range history.BackupConfigs {
+		for _, timestamp := range timestamps {
+			if history.BackupConfigs[i].Timestamp == timestamp {
+				history.BackupConfigs[i].Deleted = true
+			}
+		}
+	}
+}
+
+/*
+ * Expired backups are in the same backup set as the current backup, so they
+ * have the same backup directory and plugin and can be deleted the same way.
+ */
+func DeleteExpiredBackup(timestamp string) error {
+	if pluginConfig != nil {
+		return pluginConfig.DeleteBackup(globalCluster, timestamp)
+	}
+	fpInfo := backup_filepath.NewFilePathInfo(globalCluster, globalFPInfo.UserSpecifiedBackupDir, timestamp, globalFPInfo.UserSpecifiedSegPrefix)
+	return utils.DeleteBackupDirectories(globalCluster, fpInfo)
+}
+
+func ConstructRetentionSummary(policy backup_history.RetentionPolicy, deleted []string, failed []string, dryRun bool) string {
+	deletedStr := "None"
+	if len(deleted) > 0 {
+		deletedStr = strings.Join(deleted, ", ")
+	}
+	summary := fmt.Sprintf("Retention Policy: %s", policy)
+	if dryRun {
+		summary += fmt.Sprintf("\nExpired Backups (Dry Run, Not Deleted): %s", deletedStr)
+	} else {
+		summary += fmt.Sprintf("\nExpired Backups Deleted: %s", deletedStr)
+	}
+	if len(failed) > 0 {
+		summary += fmt.Sprintf("\nExpired Backups Not Deleted Due To Errors: %s", strings.Join(failed, ", "))
+	}
+	return summary
+}
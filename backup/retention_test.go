@@ -0,0 +1,78 @@
+package backup_test
+
+import (
+	"github.com/greenplum-db/gp-common-go-libs/cluster"
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/greenplum-db/gpbackup/backup"
+	"github.com/greenplum-db/gpbackup/backup_filepath"
+	"github.com/greenplum-db/gpbackup/backup_history"
+	"github.com/greenplum-db/gpbackup/utils"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+var _ = Describe("backup/retention tests", func() {
+	Describe("ConstructRetentionSummary", func() {
+		policy := backup_history.RetentionPolicy{{Period: "daily", Count: 7}, {Period: "weekly", Count: 4}}
+		It("lists the deleted backups", func() {
+			summary := backup.ConstructRetentionSummary(policy, []string{"20170102010101", "20170101010101"}, []string{}, false)
+
+			Expect(summary).To(Equal(`Retention Policy: daily=7, weekly=4
+Expired Backups Deleted: 20170102010101, 20170101010101`))
+		})
+		It("lists the backups that could not be deleted", func() {
+			summary := backup.ConstructRetentionSummary(policy, []string{}, []string{"20170101010101"}, false)
+
+			Expect(summary).To(Equal(`Retention Policy: daily=7, weekly=4
+Expired Backups Deleted: None
+Expired Backups Not Deleted Due To Errors: 20170101010101`))
+		})
+		It("notes a dry run", func() {
+			summary := backup.ConstructRetentionSummary(policy, []string{"20170101010101"}, nil, true)
+
+			Expect(summary).To(Equal(`Retention Policy: daily=7, weekly=4
+Expired Backups (Dry Run, Not Deleted): 20170101010101`))
+		})
+	})
+	Describe("DeleteExpiredBackup", func() {
+		var testExecutor *testhelper.TestExecutor
+		BeforeEach(func() {
+			testExecutor = &testhelper.TestExecutor{ClusterOutput: &cluster.RemoteOutput{}}
+			testCluster := cluster.NewCluster([]cluster.SegConfig{
+				{ContentID: -1, Hostname: "localhost", DataDir: "/data/gpseg-1"},
+				{ContentID: 0, Hostname: "remotehost1", DataDir: "/data/gpseg0"},
+			})
+			testCluster.Executor = testExecutor
+			backup.SetCluster(testCluster)
+			backup.SetFPInfo(backup_filepath.NewFilePathInfo(testCluster, "", "20170103010101", "gpseg"))
+			backup.SetPluginConfig(nil)
+		})
+		AfterEach(func() {
+			backup.SetPluginConfig(nil)
+		})
+		It("removes the backup directory on the master and every segment", func() {
+			err := backup.DeleteExpiredBackup("20170101010101")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(testExecutor.ClusterCommands[0][-1]).To(ContainElement("rm -rf /data/gpseg-1/backups/20170101/20170101010101"))
+			Expect(testExecutor.ClusterCommands[0][0]).To(ContainElement("rm -rf /data/gpseg0/backups/20170101/20170101010101"))
+		})
+		It("returns an error if a backup directory cannot be removed", func() {
+			testExecutor.ClusterOutput = &cluster.RemoteOutput{NumErrors: 1, Errors: map[int]error{0: errors.New("exit status 1")}}
+
+			err := backup.DeleteExpiredBackup("20170101010101")
+
+			Expect(err).To(MatchError("Unable to delete backup directories on 1 segments"))
+		})
+		It("deletes the backup through the plugin", func() {
+			backup.SetPluginConfig(&utils.PluginConfig{ExecutablePath: "/usr/local/bin/s3_plugin", ConfigPath: "/tmp/s3_config.yaml"})
+
+			err := backup.DeleteExpiredBackup("20170101010101")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(testExecutor.LocalCommands).To(Equal([]string{"/usr/local/bin/s3_plugin delete_backup /tmp/s3_config.yaml 20170101010101"}))
+			Expect(testExecutor.NumExecutions).To(Equal(1))
+		})
+	})
+})
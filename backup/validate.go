@@ -105,14 +105,19 @@ func ValidateFlagCombinations(flags *pflag.FlagSet) {
 	utils.CheckExclusiveFlags(flags, utils.EXCLUDE_RELATION, utils.EXCLUDE_RELATION_FILE, utils.LEAF_PARTITION_DATA)
 	utils.CheckExclusiveFlags(flags, utils.JOBS, utils.METADATA_ONLY, utils.SINGLE_DATA_FILE)
 	utils.CheckExclusiveFlags(flags, utils.METADATA_ONLY, utils.LEAF_PARTITION_DATA)
+	utils.CheckExclusiveFlags(flags, utils.METADATA_ONLY, utils.WITH_CHECKSUMS)
 	utils.CheckExclusiveFlags(flags, utils.NO_COMPRESSION, utils.COMPRESSION_LEVEL)
 	utils.CheckExclusiveFlags(flags, utils.PLUGIN_CONFIG, utils.BACKUP_DIR)
+	utils.CheckExclusiveFlags(flags, utils.PLUGIN_CONFIG, utils.METADATA_DIRECTORY)
 	if MustGetFlagString(utils.FROM_TIMESTAMP) != "" && !MustGetFlagBool(utils.INCREMENTAL) {
 		gplog.Fatal(errors.Errorf("--from-timestamp must be specified with --incremental"), "")
 	}
 	if MustGetFlagBool(utils.INCREMENTAL) && !MustGetFlagBool(utils.LEAF_PARTITION_DATA) {
 		gplog.Fatal(errors.Errorf("--leaf-partition-data must be specified with --incremental"), "")
 	}
+	if MustGetFlagBool(utils.RETENTION_DRY_RUN) && len(MustGetFlagStringSlice(utils.RETENTION)) == 0 {
+		gplog.Fatal(errors.Errorf("--retention-dry-run must be specified with --retention"), "")
+	}
 }
 
 func ValidateFlagValues() {
@@ -120,6 +125,20 @@ func ValidateFlagValues() {
 	gplog.FatalOnError(err)
 	err = utils.ValidateFullPath(MustGetFlagString(utils.PLUGIN_CONFIG))
 	gplog.FatalOnError(err)
+	err = utils.ValidateFullPath(MustGetFlagString(utils.METRICS_FILE))
+	gplog.FatalOnError(err)
+	if MustGetFlagString(utils.NOTIFICATION_CONFIG) != "" {
+		_, err = utils.ReadNotificationConfig(MustGetFlagString(utils.NOTIFICATION_CONFIG))
+		gplog.FatalOnError(err)
+	}
+	if MustGetFlagString(utils.EMAIL_CONFIG) != "" {
+		_, err = utils.ReadEmailConfig(MustGetFlagString(utils.EMAIL_CONFIG))
+		gplog.FatalOnError(err)
+	}
+	_, err = utils.ParseLabels(MustGetFlagStringArray(utils.LABEL))
+	gplog.FatalOnError(err)
+	_, err = backup_history.ParseRetentionPolicy(MustGetFlagStringSlice(utils.RETENTION))
+	gplog.FatalOnError(err)
 	ValidateCompressionLevel(MustGetFlagInt(utils.COMPRESSION_LEVEL))
 	if MustGetFlagString(utils.FROM_TIMESTAMP) != "" && !backup_filepath.IsValidTimestamp(MustGetFlagString(utils.FROM_TIMESTAMP)) {
 		gplog.Fatal(errors.Errorf("Timestamp %s is invalid.  Timestamps must be in the format YYYYMMDDHHMMSS.",
@@ -42,6 +42,9 @@ func InitializeConnectionPool() {
 	connectionPool = dbconn.NewDBConnFromEnvironment(MustGetFlagString(utils.DBNAME))
 	connectionPool.MustConnect(MustGetFlagInt(utils.JOBS))
 	utils.ValidateGPDBVersionCompatibility(connectionPool)
+	if MustGetFlagBool(utils.WITH_CHECKSUMS) && connectionPool.Version.Before("5") {
+		gplog.Fatal(errors.Errorf("--with-checksums requires GPDB 5 or later"), "")
+	}
 	InitializeMetadataParams(connectionPool)
 	for connNum := 0; connNum < connectionPool.NumConns; connNum++ {
 		connectionPool.MustExec("SET application_name TO 'gpbackup'", connNum)
@@ -63,9 +66,16 @@ func SetSessionGUCs(connNum int) {
 		connectionPool.MustExec("SET INTERVALSTYLE = POSTGRES", connNum)
 		connectionPool.MustExec("SET lock_timeout = 0", connNum)
 	}
+	if MustGetFlagBool(utils.WITH_CHECKSUMS) {
+		for _, setting := range utils.GetChecksumSessionSettings(connectionPool) {
+			connectionPool.MustExec(setting, connNum)
+		}
+	}
 }
 
 func NewBackupConfig(dbName string, dbVersion string, backupVersion string, plugin string, timestamp string, opts options.Options) *backup_history.BackupConfig {
+	labels, err := utils.ParseLabels(MustGetFlagStringArray(utils.LABEL))
+	gplog.FatalOnError(err)
 	backupConfig := backup_history.BackupConfig{
 		BackupDir:             MustGetFlagString(utils.BACKUP_DIR),
 		BackupVersion:         backupVersion,
@@ -82,11 +92,14 @@ func NewBackupConfig(dbName string, dbVersion string, backupVersion string, plug
 		IncludeSchemas:        MustGetFlagStringSlice(utils.INCLUDE_SCHEMA),
 		IncludeTableFiltered:  len(MustGetFlagStringArray(utils.INCLUDE_RELATION)) > 0,
 		Incremental:           MustGetFlagBool(utils.INCREMENTAL),
+		Labels:                labels,
 		LeafPartitionData:     MustGetFlagBool(utils.LEAF_PARTITION_DATA),
+		MetadataDirectory:     MustGetFlagBool(utils.METADATA_DIRECTORY),
 		MetadataOnly:          MustGetFlagBool(utils.METADATA_ONLY),
 		Plugin:                plugin,
 		SingleDataFile:        MustGetFlagBool(utils.SINGLE_DATA_FILE),
 		Timestamp:             timestamp,
+		WithChecksums:         MustGetFlagBool(utils.WITH_CHECKSUMS),
 		WithStatistics:        MustGetFlagBool(utils.WITH_STATS),
 	}
 
@@ -101,6 +114,7 @@ func InitializeBackupReport(opts options.Options) {
 	}
 	config := NewBackupConfig(escapedDBName, connectionPool.Version.VersionString, version,
 		plugin, globalFPInfo.Timestamp, opts)
+	config.SegmentCount = len(globalCluster.ContentIDs) - 1
 
 	isFilteredBackup := config.IncludeTableFiltered || config.IncludeSchemaFiltered ||
 		config.ExcludeTableFiltered || config.ExcludeSchemaFiltered
@@ -115,6 +129,9 @@ func InitializeBackupReport(opts options.Options) {
 		DatabaseSize: dbSize,
 		BackupConfig: *config,
 	}
+	if MustGetFlagString(utils.CONFIG) != "" {
+		backupReport.EffectiveFlags = utils.GetEffectiveFlags(cmdFlags)
+	}
 	backupReport.ConstructBackupParamsString()
 }
 
@@ -476,8 +493,8 @@ func BackupCreateSequences(metadataFile *utils.FileWithByteCount, sequences []Se
 	PrintCreateSequenceStatements(metadataFile, globalTOC, sequences, relationMetadata)
 }
 
-func createBackupSet(objSlice []Sortable) (backupSet map[UniqueID]bool) {
-	backupSet = make(map[UniqueID]bool, 0)
+func createBackupSet(objSlice []Sortable) (backupSet map[utils.UniqueID]bool) {
+	backupSet = make(map[utils.UniqueID]bool, 0)
 	for _, obj := range objSlice {
 		backupSet[obj.GetUniqueID()] = true
 	}
@@ -512,8 +529,8 @@ func addToMetadataMap(newMetadata MetadataMap, metadataMap MetadataMap) {
 // This function is fairly unwieldy, but there's not really a good way to break it down
 func BackupDependentObjects(metadataFile *utils.FileWithByteCount, tables []Table,
 	protocols []ExternalProtocol, filteredMetadata MetadataMap,
-	constraints []Constraint, sortables []Sortable, funcInfoMap map[uint32]FunctionInfo,
-	tableOnly bool) {
+	constraints []Constraint, sortables []Sortable, sequences []Sequence,
+	funcInfoMap map[uint32]FunctionInfo, tableOnly bool) {
 
 	gplog.Verbose("Writing CREATE statements for dependent objects to metadata file")
 
@@ -524,7 +541,11 @@ func BackupDependentObjects(metadataFile *utils.FileWithByteCount, tables []Tabl
 	}
 	sortedSlice := TopologicalSort(sortables, relevantDeps)
 
-	PrintDependentObjectStatements(metadataFile, globalTOC, sortedSlice, filteredMetadata, constraints, funcInfoMap)
+	for _, sequence := range sequences {
+		backupSet[sequence.GetUniqueID()] = true
+	}
+	AddColumnDefaultAndConstraintDependencies(connectionPool, relevantDeps, backupSet)
+	PrintDependentObjectStatements(metadataFile, globalTOC, sortedSlice, filteredMetadata, constraints, funcInfoMap, relevantDeps)
 	extPartInfo, partInfoMap := GetExternalPartitionInfo(connectionPool)
 	if len(extPartInfo) > 0 {
 		gplog.Verbose("Writing EXCHANGE PARTITION statements to metadata file")
@@ -97,11 +97,13 @@ func (backupFPInfo *FilePathInfo) GetTableBackupFilePathForCopyCommand(tableOid
 }
 
 var metadataFilenameMap = map[string]string{
-	"config":            "config.yaml",
-	"metadata":          "metadata.sql",
-	"statistics":        "statistics.sql",
-	"table of contents": "toc.yaml",
-	"report":            "report",
+	"config":             "config.yaml",
+	"metadata":           "metadata.sql",
+	"metadata directory": "metadata",
+	"statistics":         "statistics.sql",
+	"table of contents":  "toc.yaml",
+	"report":             "report",
+	"json report":        "report.json",
 }
 
 func (backupFPInfo *FilePathInfo) GetBackupFilePath(filetype string) string {
@@ -117,6 +119,10 @@ func (backupFPInfo *FilePathInfo) GetMetadataFilePath() string {
 	return backupFPInfo.GetBackupFilePath("metadata")
 }
 
+func (backupFPInfo *FilePathInfo) GetMetadataDirectoryPath() string {
+	return backupFPInfo.GetBackupFilePath("metadata directory")
+}
+
 func (backupFPInfo *FilePathInfo) GetStatisticsFilePath() string {
 	return backupFPInfo.GetBackupFilePath("statistics")
 }
@@ -129,10 +135,22 @@ func (backupFPInfo *FilePathInfo) GetBackupReportFilePath() string {
 	return backupFPInfo.GetBackupFilePath("report")
 }
 
+func (backupFPInfo *FilePathInfo) GetBackupJSONReportFilePath() string {
+	return backupFPInfo.GetBackupFilePath("json report")
+}
+
 func (backupFPInfo *FilePathInfo) GetRestoreReportFilePath(restoreTimestamp string) string {
 	return path.Join(backupFPInfo.GetDirForContent(-1), fmt.Sprintf("gprestore_%s_%s_report", backupFPInfo.Timestamp, restoreTimestamp))
 }
 
+func (backupFPInfo *FilePathInfo) GetRestoreJSONReportFilePath(restoreTimestamp string) string {
+	return backupFPInfo.GetRestoreReportFilePath(restoreTimestamp) + ".json"
+}
+
+func (backupFPInfo *FilePathInfo) GetRestoreErrorsFilePath(restoreTimestamp string) string {
+	return path.Join(backupFPInfo.GetDirForContent(-1), fmt.Sprintf("gprestore_%s_%s_errors.sql", backupFPInfo.Timestamp, restoreTimestamp))
+}
+
 func (backupFPInfo *FilePathInfo) GetConfigFilePath() string {
 	return backupFPInfo.GetBackupFilePath("config")
 }
@@ -92,6 +92,32 @@ var _ = Describe("filepath tests", func() {
 			Expect(fpInfo.GetBackupReportFilePath()).To(Equal("/foo/bar/gpseg-1/backups/20170101/20170101010101/gpbackup_20170101010101_report"))
 		})
 	})
+	Describe("GetJSONReportFilePath", func() {
+		It("returns backup JSON report file path", func() {
+			fpInfo := backup_filepath.NewFilePathInfo(c, "", "20170101010101", "gpseg")
+			Expect(fpInfo.GetBackupJSONReportFilePath()).To(Equal("/data/gpseg-1/backups/20170101/20170101010101/gpbackup_20170101010101_report.json"))
+		})
+		It("returns restore JSON report file path", func() {
+			fpInfo := backup_filepath.NewFilePathInfo(c, "", "20170101010101", "gpseg")
+			Expect(fpInfo.GetRestoreJSONReportFilePath("20170102010101")).To(Equal("/data/gpseg-1/backups/20170101/20170101010101/gprestore_20170101010101_20170102010101_report.json"))
+		})
+	})
+	Describe("GetRestoreErrorsFilePath", func() {
+		It("returns restore errors file path", func() {
+			fpInfo := backup_filepath.NewFilePathInfo(c, "", "20170101010101", "gpseg")
+			Expect(fpInfo.GetRestoreErrorsFilePath("20170102010101")).To(Equal("/data/gpseg-1/backups/20170101/20170101010101/gprestore_20170101010101_20170102010101_errors.sql"))
+		})
+	})
+	Describe("GetMetadataDirectoryPath", func() {
+		It("returns metadata directory path", func() {
+			fpInfo := backup_filepath.NewFilePathInfo(c, "", "20170101010101", "gpseg")
+			Expect(fpInfo.GetMetadataDirectoryPath()).To(Equal("/data/gpseg-1/backups/20170101/20170101010101/gpbackup_20170101010101_metadata"))
+		})
+		It("returns metadata directory path based on user specified path", func() {
+			fpInfo := backup_filepath.NewFilePathInfo(c, "/foo/bar", "20170101010101", "gpseg")
+			Expect(fpInfo.GetMetadataDirectoryPath()).To(Equal("/foo/bar/gpseg-1/backups/20170101/20170101010101/gpbackup_20170101010101_metadata"))
+		})
+	})
 	Describe("GetTableBackupFilePath", func() {
 		It("returns table file path", func() {
 			fpInfo := backup_filepath.NewFilePathInfo(c, "", "20170101010101", "gpseg")
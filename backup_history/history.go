@@ -3,6 +3,8 @@ package backup_history
 //TODO: change package name to conform to Go standards
 
 import (
+	"os"
+	"path/filepath"
 	"sort"
 	"time"
 
@@ -10,6 +12,7 @@ import (
 	"github.com/greenplum-db/gp-common-go-libs/iohelper"
 	"github.com/greenplum-db/gp-common-go-libs/operating"
 	"github.com/nightlyone/lockfile"
+	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
 )
 
@@ -26,6 +29,8 @@ type BackupConfig struct {
 	DatabaseVersion       string
 	DataOnly              bool
 	Deleted               bool
+	EndTime               string `yaml:",omitempty"`
+	Error                 string `yaml:",omitempty"`
 	ExcludeRelations      []string
 	ExcludeSchemaFiltered bool
 	ExcludeSchemas        []string
@@ -35,15 +40,26 @@ type BackupConfig struct {
 	IncludeSchemas        []string
 	IncludeTableFiltered  bool
 	Incremental           bool
+	Labels                map[string]string `yaml:",omitempty"`
 	LeafPartitionData     bool
+	MetadataDirectory     bool
 	MetadataOnly          bool
 	Plugin                string
 	RestorePlan           []RestorePlanEntry
+	SegmentCount          int `yaml:",omitempty"`
 	SingleDataFile        bool
+	Status                string `yaml:",omitempty"`
 	Timestamp             string
+	WithChecksums         bool
 	WithStatistics        bool
 }
 
+const (
+	BackupStatusInProgress = "in_progress"
+	BackupStatusSucceed    = "success"
+	BackupStatusFailed     = "failed"
+)
+
 func ReadConfigFile(filename string) *BackupConfig {
 	config := &BackupConfig{}
 	contents, err := operating.System.ReadFile(filename)
@@ -62,7 +78,35 @@ func WriteConfigFile(config *BackupConfig, configFilename string) {
 	gplog.FatalOnError(err)
 }
 
+// Backups recorded before statuses were added only reached the history if they succeeded
+func (config *BackupConfig) IsSuccessful() bool {
+	return config.Status == "" || config.Status == BackupStatusSucceed
+}
+
+// Returns true if the backup has all of the given labels with the given values
+func (config *BackupConfig) HasLabels(labels map[string]string) bool {
+	for key, value := range labels {
+		if configValue, ok := config.Labels[key]; !ok || configValue != value {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+ * The schema version is increased whenever a change to the history file
+ * format would prevent an older gpbackup from reading it correctly.  History
+ * files written before the version was recorded have version 0.
+ */
+const HistorySchemaVersion = 1
+
+const (
+	historyLockFilename = "/tmp/gpbackup_history.yaml.lck"
+	historyLockTimeout  = 5 * time.Minute
+)
+
 type History struct {
+	SchemaVersion int
 	BackupConfigs []BackupConfig
 }
 
@@ -74,7 +118,11 @@ func NewHistory(filename string) (*History, error) {
 	}
 	err = yaml.Unmarshal(contents, history)
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrapf(err, "Unable to parse backup history file %s.  The previous version of the file, if any, is %s", filename, getHistoryBackupFilename(filename))
+	}
+	if history.SchemaVersion > HistorySchemaVersion {
+		return nil, errors.Errorf("Backup history file %s has schema version %d, but this version of gpbackup only supports schema version %d or earlier",
+			filename, history.SchemaVersion, HistorySchemaVersion)
 	}
 
 	return history, nil
@@ -87,16 +135,60 @@ func (history *History) AddBackupConfig(backupConfig *BackupConfig) {
 	})
 }
 
+/*
+ * Returns the most recent backup in the history for which matches returns
+ * true, or nil if there is no such backup.  Deleted backups and backups that
+ * did not succeed are skipped.
+ */
+func (history *History) FindLatestBackup(matches func(config *BackupConfig) bool) *BackupConfig {
+	var latest *BackupConfig
+	for i := range history.BackupConfigs {
+		config := &history.BackupConfigs[i]
+		if config.Deleted || !config.IsSuccessful() || !matches(config) {
+			continue
+		}
+		if latest == nil || config.Timestamp > latest.Timestamp {
+			latest = config
+		}
+	}
+	return latest
+}
+
+/*
+ * A backup is written to the history when it starts and again when it ends,
+ * so an existing entry for the same timestamp is replaced.
+ */
 func WriteBackupHistory(historyFilePath string, currentBackupConfig *BackupConfig) error {
-	lock := lockHistoryFile()
+	return UpdateHistoryFile(historyFilePath, func(history *History) {
+		if len(history.BackupConfigs) == 0 {
+			gplog.Verbose("No existing backups found. Creating new backup history file.")
+		}
+		for i := range history.BackupConfigs {
+			if history.BackupConfigs[i].Timestamp == currentBackupConfig.Timestamp {
+				history.BackupConfigs = append(history.BackupConfigs[:i], history.BackupConfigs[i+1:]...)
+				break
+			}
+		}
+		history.AddBackupConfig(currentBackupConfig)
+	})
+}
+
+/*
+ * The history file is read, changed, and written back while holding the
+ * history lock, so that concurrent gpbackup and gpbackup_manager runs never
+ * overwrite each other's changes to it.
+ */
+func UpdateHistoryFile(historyFilePath string, update func(history *History)) error {
+	lock, err := lockHistoryFile()
+	if err != nil {
+		return err
+	}
 	defer func() {
 		_ = lock.Unlock()
 	}()
 
 	var history *History
-
 	if iohelper.FileExistsAndIsReadable(historyFilePath) {
-		var err error
 		history, err = NewHistory(historyFilePath)
 		if err != nil {
 			return err
@@ -104,63 +196,99 @@ func WriteBackupHistory(historyFilePath string, currentBackupConfig *BackupConfi
 	} else {
 		history = &History{BackupConfigs: make([]BackupConfig, 0)}
 	}
-	if len(history.BackupConfigs) == 0 {
-		gplog.Verbose("No existing backups found. Creating new backup history file.")
-	}
-	history.AddBackupConfig(currentBackupConfig)
+	update(history)
 	return history.WriteToFileAndMakeReadOnly(historyFilePath)
 }
 
-func (history *History) RewriteHistoryFile(historyFilePath string) error {
-	lock := lockHistoryFile()
-	defer func() {
-		_ = lock.Unlock()
-	}()
-
-	err := history.WriteToFileAndMakeReadOnly(historyFilePath)
-	return err
-}
-
-func lockHistoryFile() lockfile.Lockfile {
-	lock, err := lockfile.New("/tmp/gpbackup_history.yaml.lck")
-	gplog.FatalOnError(err)
-	err = lock.TryLock()
-	for err != nil {
-		time.Sleep(50 * time.Millisecond)
+/*
+ * A lock file left behind by a process that no longer exists is reclaimed by
+ * TryLock, which checks the PID recorded in the file.  A lock file whose PID
+ * has been reused by another process is never reclaimed automatically, as it
+ * cannot be removed safely while other processes may be checking it, so it
+ * must be removed by hand once the wait for it times out.
+ */
+func lockHistoryFile() (lockfile.Lockfile, error) {
+	lock, err := lockfile.New(historyLockFilename)
+	if err != nil {
+		return lock, err
+	}
+	start := time.Now()
+	for {
 		err = lock.TryLock()
+		if err == nil {
+			return lock, nil
+		}
+		if time.Since(start) > historyLockTimeout {
+			return lock, errors.Errorf("Timed out waiting for backup history lock file %s.  If no other gpbackup or gpbackup_manager process is running, remove the file and try again.", historyLockFilename)
+		}
+		time.Sleep(50 * time.Millisecond)
 	}
-	return lock
 }
 
+func getHistoryBackupFilename(filename string) string {
+	return filename + ".bak"
+}
+
+/*
+ * The new contents are written to a temporary file that is then renamed over
+ * the history file, so a crash during the write leaves the previous history
+ * file intact.  The previous history file is also kept as a backup copy.
+ */
 func (history *History) WriteToFileAndMakeReadOnly(filename string) error {
-	_, err := operating.System.Stat(filename)
-	fileExists := (err == nil)
-	if fileExists {
-		err = operating.System.Chmod(filename, 0644)
-		if err != nil {
-			return err
-		}
+	history.SchemaVersion = HistorySchemaVersion
+	historyFileContents, err := yaml.Marshal(history)
+	if err != nil {
+		return err
 	}
-	var historyFileContents []byte
-	historyFileContents, err = yaml.Marshal(history)
+	tempFilename := filename + ".tmp"
+	err = writeFileAndSync(tempFilename, historyFileContents)
 	if err != nil {
 		return err
 	}
-	historyFile := iohelper.MustOpenFileForWriting(filename)
-	defer func() {
-		err = historyFile.Close()
+	if _, err = operating.System.Stat(filename); err == nil {
+		backupFilename := getHistoryBackupFilename(filename)
+		_ = operating.System.Remove(backupFilename)
+		err = os.Link(filename, backupFilename)
 		if err != nil {
-			gplog.Warn("cannot close history file: %v", err)
+			gplog.Warn("Unable to save a backup copy of history file %s: %v", filename, err)
 		}
-	}()
-	_, err = historyFile.Write(historyFileContents)
+	}
+	err = os.Rename(tempFilename, filename)
+	if err != nil {
+		return err
+	}
+	syncDirectory(filepath.Dir(filename))
+	return nil
+}
+
+func writeFileAndSync(filename string, contents []byte) error {
+	_ = operating.System.Remove(filename)
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
 		return err
 	}
-	err = operating.System.Chmod(filename, 0444)
+	_, err = file.Write(contents)
+	if err == nil {
+		err = file.Sync()
+	}
+	closeErr := file.Close()
 	if err != nil {
 		return err
 	}
+	if closeErr != nil {
+		return closeErr
+	}
+	return operating.System.Chmod(filename, 0444)
+}
 
-	return nil
+// A rename is only durable once the directory containing the file is synced
+func syncDirectory(dirname string) {
+	dir, err := os.Open(dirname)
+	if err == nil {
+		err = dir.Sync()
+		_ = dir.Close()
+	}
+	if err != nil {
+		gplog.Verbose("Unable to sync directory %s: %v", dirname, err)
+	}
 }
@@ -2,8 +2,10 @@ package backup_history_test
 
 import (
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"os/exec"
 
 	"github.com/greenplum-db/gp-common-go-libs/iohelper"
 	"github.com/greenplum-db/gp-common-go-libs/operating"
@@ -87,6 +89,23 @@ var _ bool = Describe("backup/history tests", func() {
 			Expect(err).ToNot(HaveOccurred())
 			structmatcher.ExpectStructsToMatch(&historyWithEntries, resultHistory)
 		})
+		It("records the schema version and keeps the previous file as a backup copy", func() {
+			err := ioutil.WriteFile(historyFilePath, []byte("backupconfigs: []\n"), 0444)
+			Expect(err).ToNot(HaveOccurred())
+			defer os.Remove(historyFilePath + ".bak")
+
+			err = historyWithEntries.WriteToFileAndMakeReadOnly(historyFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			contents, err := ioutil.ReadFile(historyFilePath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(contents)).To(HavePrefix("schemaversion: 1\n"))
+			backupContents, err := ioutil.ReadFile(historyFilePath + ".bak")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(backupContents)).To(Equal("backupconfigs: []\n"))
+			_, err = os.Stat(historyFilePath + ".tmp")
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
 		It("writes file when file exists and is readonly ", func() {
 			err := ioutil.WriteFile(historyFilePath, []byte{}, 0444)
 			Expect(err).ToNot(HaveOccurred())
@@ -137,6 +156,12 @@ var _ bool = Describe("backup/history tests", func() {
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("not yaml"))
 			})
+			It("gpbackup_history.yaml has a newer schema version", func() {
+				operating.System.ReadFile = func(string) ([]byte, error) { return []byte("schemaversion: 99\n"), nil }
+
+				_, err := backup_history.NewHistory("/tempfile")
+				Expect(err).To(MatchError("Backup history file /tempfile has schema version 99, but this version of gpbackup only supports schema version 1 or earlier"))
+			})
 			It("NewHistory returns an empty History", func() {
 				backup.SetFPInfo(backup_filepath.FilePathInfo{UserSpecifiedBackupDir: "/tmp", UserSpecifiedSegPrefix: "/test-prefix"})
 				backup.SetReport(&utils.Report{})
@@ -181,6 +206,7 @@ var _ bool = Describe("backup/history tests", func() {
 			resultHistory, err := backup_history.NewHistory(historyFilePath)
 			Expect(err).ToNot(HaveOccurred())
 			expectedHistory := backup_history.History{
+				SchemaVersion: backup_history.HistorySchemaVersion,
 				BackupConfigs: []backup_history.BackupConfig{testConfig3, testConfig2, testConfig1},
 			}
 			structmatcher.ExpectStructsToMatch(&expectedHistory, resultHistory)
@@ -192,9 +218,72 @@ var _ bool = Describe("backup/history tests", func() {
 
 			resultHistory, err := backup_history.NewHistory(historyFilePath)
 			Expect(err).ToNot(HaveOccurred())
-			expectedHistory := backup_history.History{BackupConfigs: []backup_history.BackupConfig{testConfig3}}
+			expectedHistory := backup_history.History{SchemaVersion: backup_history.HistorySchemaVersion, BackupConfigs: []backup_history.BackupConfig{testConfig3}}
 			structmatcher.ExpectStructsToMatch(&expectedHistory, resultHistory)
 			Expect(testLogfile).To(gbytes.Say("No existing backups found. Creating new backup history file."))
 		})
+		It("replaces an existing entry with the same timestamp", func() {
+			inProgressConfig := testConfig3
+			inProgressConfig.Status = backup_history.BackupStatusInProgress
+			err := backup_history.WriteBackupHistory(historyFilePath, &inProgressConfig)
+			Expect(err).ToNot(HaveOccurred())
+			failedConfig := testConfig3
+			failedConfig.Status = backup_history.BackupStatusFailed
+			failedConfig.Error = "ERROR: relation does not exist"
+
+			err = backup_history.WriteBackupHistory(historyFilePath, &failedConfig)
+			Expect(err).ToNot(HaveOccurred())
+
+			resultHistory, err := backup_history.NewHistory(historyFilePath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resultHistory.BackupConfigs).To(HaveLen(1))
+			structmatcher.ExpectStructsToMatch(&failedConfig, &resultHistory.BackupConfigs[0])
+		})
+		It("reclaims a lock file left behind by a process that no longer exists", func() {
+			lockFilename := "/tmp/gpbackup_history.yaml.lck"
+			deadProcess := exec.Command("true")
+			err := deadProcess.Run()
+			Expect(err).ToNot(HaveOccurred())
+			err = ioutil.WriteFile(lockFilename, []byte(fmt.Sprintf("%d\n", deadProcess.Process.Pid)), 0644)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = backup_history.WriteBackupHistory(historyFilePath, &testConfig3)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = os.Stat(lockFilename)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+	Describe("FindLatestBackup", func() {
+		history := backup_history.History{BackupConfigs: []backup_history.BackupConfig{
+			{DatabaseName: "testdb", Timestamp: "20170101010101", Labels: map[string]string{"release": "pre-5.1"}},
+			{DatabaseName: "testdb", Timestamp: "20170103010101", Labels: map[string]string{"release": "pre-5.1", "owner": "dba"}, Deleted: true},
+			{DatabaseName: "testdb", Timestamp: "20170102010101", Labels: map[string]string{"release": "pre-5.1", "owner": "dba"}},
+			{DatabaseName: "testdb", Timestamp: "20170104010101"},
+			{DatabaseName: "testdb", Timestamp: "20170105010101", Status: backup_history.BackupStatusFailed},
+			{DatabaseName: "testdb", Timestamp: "20170106010101", Status: backup_history.BackupStatusInProgress},
+		}}
+		hasReleaseLabel := func(config *backup_history.BackupConfig) bool {
+			return config.HasLabels(map[string]string{"release": "pre-5.1"})
+		}
+		It("returns the most recent matching backup that is not deleted", func() {
+			config := history.FindLatestBackup(hasReleaseLabel)
+
+			Expect(config.Timestamp).To(Equal("20170102010101"))
+		})
+		It("returns nil if no backup matches", func() {
+			config := history.FindLatestBackup(func(config *backup_history.BackupConfig) bool {
+				return config.HasLabels(map[string]string{"release": "pre-6.0"})
+			})
+
+			Expect(config).To(BeNil())
+		})
+		It("matches every successful backup if no labels are given", func() {
+			config := history.FindLatestBackup(func(config *backup_history.BackupConfig) bool {
+				return config.HasLabels(nil)
+			})
+
+			Expect(config.Timestamp).To(Equal("20170104010101"))
+		})
 	})
 })
@@ -0,0 +1,189 @@
+package backup_history
+
+/*
+ * This file contains structs and functions related to deciding which backups
+ * in the backup history have expired under a retention policy.
+ */
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var retentionPeriods = []string{"daily", "weekly", "monthly", "yearly"}
+
+/*
+ * A rule such as weekly=4 keeps the most recent full backup from each of the
+ * 4 most recent weeks in which a full backup was taken.
+ */
+type RetentionRule struct {
+	Period string
+	Count  int
+}
+
+type RetentionPolicy []RetentionRule
+
+/*
+ * Rules are given in the format period=count, e.g. daily=7, and each period
+ * may be given only once.
+ */
+func ParseRetentionPolicy(ruleStrs []string) (RetentionPolicy, error) {
+	policy := make(RetentionPolicy, 0)
+	seenPeriods := make(map[string]bool, 0)
+	for _, ruleStr := range ruleStrs {
+		periodCount := strings.SplitN(ruleStr, "=", 2)
+		if len(periodCount) != 2 {
+			return nil, errors.Errorf("Retention rule %s is invalid.  Retention rules must be in the format period=count.", ruleStr)
+		}
+		period := strings.TrimSpace(periodCount[0])
+		if !isRetentionPeriod(period) {
+			return nil, errors.Errorf("Retention period %s is invalid.  Valid periods are %s.", period, strings.Join(retentionPeriods, ", "))
+		}
+		count, err := strconv.Atoi(strings.TrimSpace(periodCount[1]))
+		if err != nil || count < 1 {
+			return nil, errors.Errorf("Retention count %s is invalid.  Retention counts must be positive integers.", periodCount[1])
+		}
+		if seenPeriods[period] {
+			return nil, errors.Errorf("Retention period %s is specified more than once", period)
+		}
+		seenPeriods[period] = true
+		policy = append(policy, RetentionRule{Period: period, Count: count})
+	}
+	return policy, nil
+}
+
+func isRetentionPeriod(period string) bool {
+	for _, validPeriod := range retentionPeriods {
+		if period == validPeriod {
+			return true
+		}
+	}
+	return false
+}
+
+func (policy RetentionPolicy) String() string {
+	ruleStrs := make([]string, 0)
+	for _, rule := range policy {
+		ruleStrs = append(ruleStrs, fmt.Sprintf("%s=%d", rule.Period, rule.Count))
+	}
+	return strings.Join(ruleStrs, ", ")
+}
+
+// Returns a key that is the same for all timestamps in the same period
+func getPeriodKey(timestamp string, period string) string {
+	switch period {
+	case "daily":
+		return timestamp[:8]
+	case "weekly":
+		backupTime, _ := time.Parse("20060102150405", timestamp)
+		year, week := backupTime.ISOWeek()
+		return fmt.Sprintf("%d-%02d", year, week)
+	case "monthly":
+		return timestamp[:6]
+	}
+	return timestamp[:4]
+}
+
+func sameStrings(strs1 []string, strs2 []string) bool {
+	if len(strs1) != len(strs2) {
+		return false
+	}
+	sorted1 := append([]string{}, strs1...)
+	sorted2 := append([]string{}, strs2...)
+	sort.Strings(sorted1)
+	sort.Strings(sorted2)
+	for i := range sorted1 {
+		if sorted1[i] != sorted2[i] {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+ * Retention only applies to backups of the same database to the same location
+ * with the same sections and filters as the current backup, so that a policy
+ * given for one series of backups never removes backups from another.
+ */
+func (config *BackupConfig) InSameBackupSet(other *BackupConfig) bool {
+	return config.DatabaseName == other.DatabaseName &&
+		config.BackupDir == other.BackupDir &&
+		config.Plugin == other.Plugin &&
+		config.DataOnly == other.DataOnly &&
+		config.MetadataOnly == other.MetadataOnly &&
+		sameStrings(config.IncludeSchemas, other.IncludeSchemas) &&
+		sameStrings(config.IncludeRelations, other.IncludeRelations) &&
+		sameStrings(config.ExcludeSchemas, other.ExcludeSchemas) &&
+		sameStrings(config.ExcludeRelations, other.ExcludeRelations)
+}
+
+// The first entry in an incremental backup's restore plan is its full backup
+func (config *BackupConfig) GetBaseTimestamp() string {
+	if !config.Incremental || len(config.RestorePlan) == 0 {
+		return config.Timestamp
+	}
+	return config.RestorePlan[0].Timestamp
+}
+
+/*
+ * Returns the timestamps of the successful backups in the same backup set as
+ * the current backup that the policy does not keep, most recent first.  Each
+ * rule keeps the latest full backup in each of its most recent periods, and
+ * incremental backups are kept or expired along with the full backup on which
+ * they are based, so that incremental chains are never broken.  The chain
+ * containing the current backup is always kept.
+ */
+func (history *History) GetExpiredBackups(current *BackupConfig, policy RetentionPolicy) []string {
+	if len(policy) == 0 {
+		return []string{}
+	}
+	fullBackups := make([]*BackupConfig, 0)
+	incrementalBackups := make([]*BackupConfig, 0)
+	for i := range history.BackupConfigs {
+		config := &history.BackupConfigs[i]
+		if config.Deleted || !config.IsSuccessful() || !config.InSameBackupSet(current) {
+			continue
+		}
+		if config.Incremental {
+			incrementalBackups = append(incrementalBackups, config)
+		} else {
+			fullBackups = append(fullBackups, config)
+		}
+	}
+	sort.Slice(fullBackups, func(i, j int) bool {
+		return fullBackups[i].Timestamp > fullBackups[j].Timestamp
+	})
+
+	keptFullBackups := map[string]bool{current.GetBaseTimestamp(): true}
+	for _, rule := range policy {
+		keptPeriods := make(map[string]bool, 0)
+		for _, config := range fullBackups {
+			periodKey := getPeriodKey(config.Timestamp, rule.Period)
+			if !keptPeriods[periodKey] && len(keptPeriods) < rule.Count {
+				keptPeriods[periodKey] = true
+				keptFullBackups[config.Timestamp] = true
+			}
+		}
+	}
+
+	expiredFullBackups := make(map[string]bool, 0)
+	expired := make([]string, 0)
+	for _, config := range fullBackups {
+		if !keptFullBackups[config.Timestamp] {
+			expiredFullBackups[config.Timestamp] = true
+			expired = append(expired, config.Timestamp)
+		}
+	}
+	for _, config := range incrementalBackups {
+		if expiredFullBackups[config.GetBaseTimestamp()] {
+			expired = append(expired, config.Timestamp)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(expired)))
+	return expired
+}
@@ -0,0 +1,106 @@
+package backup_history_test
+
+import (
+	"github.com/greenplum-db/gpbackup/backup_history"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("backup_history/retention tests", func() {
+	Describe("ParseRetentionPolicy", func() {
+		It("parses rules in the order given", func() {
+			policy, err := backup_history.ParseRetentionPolicy([]string{"daily=7", "weekly=4", "monthly=12"})
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(policy).To(Equal(backup_history.RetentionPolicy{{Period: "daily", Count: 7}, {Period: "weekly", Count: 4}, {Period: "monthly", Count: 12}}))
+			Expect(policy.String()).To(Equal("daily=7, weekly=4, monthly=12"))
+		})
+		It("returns an empty policy if no rules are given", func() {
+			policy, err := backup_history.ParseRetentionPolicy([]string{})
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(policy).To(BeEmpty())
+		})
+		It("returns an error for a malformed rule", func() {
+			_, err := backup_history.ParseRetentionPolicy([]string{"daily"})
+
+			Expect(err).To(MatchError("Retention rule daily is invalid.  Retention rules must be in the format period=count."))
+		})
+		It("returns an error for an unknown period", func() {
+			_, err := backup_history.ParseRetentionPolicy([]string{"hourly=24"})
+
+			Expect(err).To(MatchError("Retention period hourly is invalid.  Valid periods are daily, weekly, monthly, yearly."))
+		})
+		It("returns an error for a count that is not positive", func() {
+			_, err := backup_history.ParseRetentionPolicy([]string{"daily=0"})
+
+			Expect(err).To(MatchError("Retention count 0 is invalid.  Retention counts must be positive integers."))
+		})
+		It("returns an error if a period is given more than once", func() {
+			_, err := backup_history.ParseRetentionPolicy([]string{"daily=7", "daily=3"})
+
+			Expect(err).To(MatchError("Retention period daily is specified more than once"))
+		})
+	})
+	Describe("GetExpiredBackups", func() {
+		fullBackup := func(timestamp string) backup_history.BackupConfig {
+			return backup_history.BackupConfig{DatabaseName: "testdb", Timestamp: timestamp}
+		}
+		incrementalBackup := func(timestamp string, restorePlan ...string) backup_history.BackupConfig {
+			config := backup_history.BackupConfig{DatabaseName: "testdb", Timestamp: timestamp, Incremental: true}
+			for _, planTimestamp := range restorePlan {
+				config.RestorePlan = append(config.RestorePlan, backup_history.RestorePlanEntry{Timestamp: planTimestamp})
+			}
+			return config
+		}
+		var history *backup_history.History
+		BeforeEach(func() {
+			history = &backup_history.History{BackupConfigs: []backup_history.BackupConfig{
+				fullBackup("20170110010101"),
+				fullBackup("20170109020202"),
+				fullBackup("20170109010101"),
+				incrementalBackup("20170108020202", "20170108010101"),
+				fullBackup("20170108010101"),
+				fullBackup("20170102010101"),
+				fullBackup("20161231010101"),
+			}}
+		})
+		It("keeps the latest full backup of each day for daily rules", func() {
+			expired := history.GetExpiredBackups(&history.BackupConfigs[0], backup_history.RetentionPolicy{{Period: "daily", Count: 2}})
+
+			Expect(expired).To(Equal([]string{"20170109010101", "20170108020202", "20170108010101", "20170102010101", "20161231010101"}))
+		})
+		It("keeps backups required by any rule", func() {
+			expired := history.GetExpiredBackups(&history.BackupConfigs[0], backup_history.RetentionPolicy{{Period: "daily", Count: 1}, {Period: "weekly", Count: 2}, {Period: "yearly", Count: 2}})
+
+			Expect(expired).To(Equal([]string{"20170109020202", "20170109010101", "20170102010101"}))
+		})
+		It("keeps incremental backups along with their full backup", func() {
+			expired := history.GetExpiredBackups(&history.BackupConfigs[0], backup_history.RetentionPolicy{{Period: "daily", Count: 3}})
+
+			Expect(expired).To(Equal([]string{"20170109010101", "20170102010101", "20161231010101"}))
+		})
+		It("always keeps the incremental chain of the current backup", func() {
+			current := incrementalBackup("20170111010101", "20161231010101")
+			history.BackupConfigs = append(history.BackupConfigs, current)
+
+			expired := history.GetExpiredBackups(&current, backup_history.RetentionPolicy{{Period: "daily", Count: 1}})
+
+			Expect(expired).ToNot(ContainElement("20161231010101"))
+			Expect(expired).ToNot(ContainElement("20170111010101"))
+			Expect(expired).To(ContainElement("20170109020202"))
+		})
+		It("ignores deleted backups and backups in other backup sets", func() {
+			history.BackupConfigs[5].Deleted = true
+			history.BackupConfigs[6].DatabaseName = "otherdb"
+			history.BackupConfigs[4].MetadataOnly = true
+
+			expired := history.GetExpiredBackups(&history.BackupConfigs[0], backup_history.RetentionPolicy{{Period: "monthly", Count: 1}})
+
+			Expect(expired).To(Equal([]string{"20170109020202", "20170109010101"}))
+		})
+		It("returns no backups for an empty policy", func() {
+			Expect(history.GetExpiredBackups(&history.BackupConfigs[0], backup_history.RetentionPolicy{})).To(BeEmpty())
+		})
+	})
+})
@@ -882,6 +882,17 @@ var _ = Describe("backup end to end integration tests", func() {
 			assertDataRestored(restoreConn, schema2TupleCounts)
 		})
 
+		It("runs gpbackup and gprestore with metadata-directory backup flag", func() {
+			backupdir := filepath.Join(custom_backup_dir, "metadata_directory") // Must be unique
+			timestamp := gpbackup(gpbackupPath, backupHelperPath, "--metadata-directory", "--with-stats", "--backup-dir", backupdir)
+			gprestore(gprestorePath, restoreHelperPath, timestamp, "--redirect-db", "restoredb", "--backup-dir", backupdir, "--with-stats")
+
+			assertRelationsCreated(restoreConn, TOTAL_RELATIONS)
+			assertDataRestored(restoreConn, publicSchemaTupleCounts)
+			assertDataRestored(restoreConn, schema2TupleCounts)
+
+			os.RemoveAll(backupdir)
+		})
 		It("runs gpbackup and gprestore with the data-only restore flag", func() {
 			testutils.ExecuteSQLFile(restoreConn, "test_tables_ddl.sql")
 			timestamp := gpbackup(gpbackupPath, backupHelperPath)
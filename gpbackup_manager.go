@@ -0,0 +1,25 @@
+//go:build gpbackup_manager
+// +build gpbackup_manager
+
+package main
+
+import (
+	"os"
+
+	. "github.com/greenplum-db/gpbackup/manager"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/spf13/cobra"
+)
+
+func main() {
+	var rootCmd = &cobra.Command{
+		Use:     "gpbackup_manager",
+		Short:   "gpbackup_manager is a utility for inspecting and managing existing gpbackup backups",
+		Version: GetVersion(),
+	}
+	rootCmd.SetArgs(utils.HandleSingleDashes(os.Args[1:]))
+	DoInit(rootCmd)
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(2)
+	}
+}
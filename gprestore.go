@@ -19,6 +19,10 @@ func main() {
 		Run: func(cmd *cobra.Command, args []string) {
 			defer DoTeardown()
 			DoValidation(cmd)
+			if MustGetFlagString(utils.PRINT_SQL) != "" {
+				DoPrintSQL()
+				return
+			}
 			DoSetup()
 			DoRestore()
 		}}
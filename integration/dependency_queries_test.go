@@ -4,6 +4,7 @@ import (
 	"github.com/greenplum-db/gp-common-go-libs/testhelper"
 	"github.com/greenplum-db/gpbackup/backup"
 	"github.com/greenplum-db/gpbackup/testutils"
+	"github.com/greenplum-db/gpbackup/utils"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -18,9 +19,9 @@ var _ = Describe("backup integration tests", func() {
 
 			oidFoo := testutils.OidFromObjectName(connectionPool, "public", "foo", backup.TYPE_RELATION)
 			oidBar := testutils.OidFromObjectName(connectionPool, "public", "bar", backup.TYPE_RELATION)
-			fooEntry := backup.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: oidFoo}
-			barEntry := backup.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: oidBar}
-			backupSet := map[backup.UniqueID]bool{fooEntry: true, barEntry: true}
+			fooEntry := utils.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: oidFoo}
+			barEntry := utils.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: oidBar}
+			backupSet := map[utils.UniqueID]bool{fooEntry: true, barEntry: true}
 
 			deps := backup.GetDependencies(connectionPool, backupSet)
 
@@ -48,10 +49,10 @@ var _ = Describe("backup integration tests", func() {
 			protocolOid := testutils.OidFromObjectName(connectionPool, "", "s3", backup.TYPE_PROTOCOL)
 			functionOid := testutils.OidFromObjectName(connectionPool, "public", "read_from_s3", backup.TYPE_FUNCTION)
 
-			tableEntry := backup.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: tableOid}
-			protocolEntry := backup.UniqueID{ClassID: backup.PG_EXTPROTOCOL_OID, Oid: protocolOid}
-			functionEntry := backup.UniqueID{ClassID: backup.PG_PROC_OID, Oid: functionOid}
-			backupSet := map[backup.UniqueID]bool{tableEntry: true, protocolEntry: true, functionEntry: true}
+			tableEntry := utils.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: tableOid}
+			protocolEntry := utils.UniqueID{ClassID: backup.PG_EXTPROTOCOL_OID, Oid: protocolOid}
+			functionEntry := utils.UniqueID{ClassID: backup.PG_PROC_OID, Oid: functionOid}
+			backupSet := map[utils.UniqueID]bool{tableEntry: true, protocolEntry: true, functionEntry: true}
 
 			deps := backup.GetDependencies(connectionPool, backupSet)
 			if connectionPool.Version.Is("4") {
@@ -79,10 +80,10 @@ var _ = Describe("backup integration tests", func() {
 			parent2Oid := testutils.OidFromObjectName(connectionPool, "public", "parent2", backup.TYPE_RELATION)
 			childOid := testutils.OidFromObjectName(connectionPool, "public", "child", backup.TYPE_RELATION)
 
-			parent1Entry := backup.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: parent1Oid}
-			parent2Entry := backup.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: parent2Oid}
-			childEntry := backup.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: childOid}
-			backupSet := map[backup.UniqueID]bool{parent1Entry: true, parent2Entry: true, childEntry: true}
+			parent1Entry := utils.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: parent1Oid}
+			parent2Entry := utils.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: parent2Oid}
+			childEntry := utils.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: childOid}
+			backupSet := map[utils.UniqueID]bool{parent1Entry: true, parent2Entry: true, childEntry: true}
 
 			deps := backup.GetDependencies(connectionPool, backupSet)
 
@@ -105,7 +106,7 @@ object-relational database management system');`)
 			parserID := testutils.UniqueIDFromObjectName(connectionPool, "public", "testparser", backup.TYPE_TSPARSER)
 			configID := testutils.UniqueIDFromObjectName(connectionPool, "public", "testconfig", backup.TYPE_TSCONFIGURATION)
 			viewID := testutils.UniqueIDFromObjectName(connectionPool, "public", "ts_config_view", backup.TYPE_RELATION)
-			backupSet := map[backup.UniqueID]bool{parserID: true, configID: true, viewID: true}
+			backupSet := map[utils.UniqueID]bool{parserID: true, configID: true, viewID: true}
 
 			deps := backup.GetDependencies(connectionPool, backupSet)
 			Expect(deps).To(HaveLen(2))
@@ -115,11 +116,11 @@ object-relational database management system');`)
 			Expect(deps[viewID]).To(HaveKey(configID))
 		})
 		Describe("function dependencies", func() {
-			var compositeEntry backup.UniqueID
+			var compositeEntry utils.UniqueID
 			BeforeEach(func() {
 				testhelper.AssertQueryRuns(connectionPool, "CREATE TYPE public.composite_ints AS (one integer, two integer)")
 				compositeOid := testutils.OidFromObjectName(connectionPool, "public", "composite_ints", backup.TYPE_TYPE)
-				compositeEntry = backup.UniqueID{ClassID: backup.PG_TYPE_OID, Oid: compositeOid}
+				compositeEntry = utils.UniqueID{ClassID: backup.PG_TYPE_OID, Oid: compositeOid}
 			})
 			AfterEach(func() {
 				testhelper.AssertQueryRuns(connectionPool, "DROP TYPE public.composite_ints CASCADE")
@@ -129,8 +130,8 @@ object-relational database management system');`)
 				defer testhelper.AssertQueryRuns(connectionPool, "DROP FUNCTION public.add(public.composite_ints)")
 
 				functionOid := testutils.OidFromObjectName(connectionPool, "public", "add", backup.TYPE_FUNCTION)
-				funcEntry := backup.UniqueID{ClassID: backup.PG_PROC_OID, Oid: functionOid}
-				backupSet := map[backup.UniqueID]bool{funcEntry: true, compositeEntry: true}
+				funcEntry := utils.UniqueID{ClassID: backup.PG_PROC_OID, Oid: functionOid}
+				backupSet := map[utils.UniqueID]bool{funcEntry: true, compositeEntry: true}
 
 				functionDeps := backup.GetDependencies(connectionPool, backupSet)
 
@@ -143,8 +144,8 @@ object-relational database management system');`)
 				defer testhelper.AssertQueryRuns(connectionPool, "DROP FUNCTION public.compose(integer, integer)")
 
 				functionOid := testutils.OidFromObjectName(connectionPool, "public", "compose", backup.TYPE_FUNCTION)
-				funcEntry := backup.UniqueID{ClassID: backup.PG_PROC_OID, Oid: functionOid}
-				backupSet := map[backup.UniqueID]bool{funcEntry: true, compositeEntry: true}
+				funcEntry := utils.UniqueID{ClassID: backup.PG_PROC_OID, Oid: functionOid}
+				backupSet := map[utils.UniqueID]bool{funcEntry: true, compositeEntry: true}
 
 				functionDeps := backup.GetDependencies(connectionPool, backupSet)
 
@@ -162,10 +163,10 @@ object-relational database management system');`)
 				defer testhelper.AssertQueryRuns(connectionPool, "DROP FUNCTION public.compose(public.base_type[], public.composite_ints)")
 
 				functionOid := testutils.OidFromObjectName(connectionPool, "public", "compose", backup.TYPE_FUNCTION)
-				funcEntry := backup.UniqueID{ClassID: backup.PG_PROC_OID, Oid: functionOid}
+				funcEntry := utils.UniqueID{ClassID: backup.PG_PROC_OID, Oid: functionOid}
 				baseOid := testutils.OidFromObjectName(connectionPool, "public", "base_type", backup.TYPE_TYPE)
-				baseEntry := backup.UniqueID{ClassID: backup.PG_TYPE_OID, Oid: baseOid}
-				backupSet := map[backup.UniqueID]bool{funcEntry: true, compositeEntry: true, baseEntry: true}
+				baseEntry := utils.UniqueID{ClassID: backup.PG_TYPE_OID, Oid: baseOid}
+				backupSet := map[utils.UniqueID]bool{funcEntry: true, compositeEntry: true, baseEntry: true}
 
 				functionDeps := backup.GetDependencies(connectionPool, backupSet)
 
@@ -178,7 +179,7 @@ object-relational database management system');`)
 		Describe("type dependencies", func() {
 			var (
 				baseOid   uint32
-				baseEntry backup.UniqueID
+				baseEntry utils.UniqueID
 			)
 			BeforeEach(func() {
 				testhelper.AssertQueryRuns(connectionPool, "CREATE TYPE public.base_type")
@@ -187,7 +188,7 @@ object-relational database management system');`)
 				testhelper.AssertQueryRuns(connectionPool, "CREATE TYPE public.base_type(INPUT=public.base_fn_in, OUTPUT=public.base_fn_out)")
 
 				baseOid = testutils.OidFromObjectName(connectionPool, "public", "base_type", backup.TYPE_TYPE)
-				baseEntry = backup.UniqueID{ClassID: backup.PG_TYPE_OID, Oid: baseOid}
+				baseEntry = utils.UniqueID{ClassID: backup.PG_TYPE_OID, Oid: baseOid}
 			})
 			AfterEach(func() {
 				testhelper.AssertQueryRuns(connectionPool, "DROP TYPE public.base_type CASCADE")
@@ -201,9 +202,9 @@ object-relational database management system');`)
 				domainOid := testutils.OidFromObjectName(connectionPool, "public", "parent_domain", backup.TYPE_TYPE)
 				domain2Oid := testutils.OidFromObjectName(connectionPool, "public", "domain_type", backup.TYPE_TYPE)
 
-				domainEntry := backup.UniqueID{ClassID: backup.PG_TYPE_OID, Oid: domainOid}
-				domain2Entry := backup.UniqueID{ClassID: backup.PG_TYPE_OID, Oid: domain2Oid}
-				backupSet := map[backup.UniqueID]bool{domainEntry: true, domain2Entry: true}
+				domainEntry := utils.UniqueID{ClassID: backup.PG_TYPE_OID, Oid: domainOid}
+				domain2Entry := utils.UniqueID{ClassID: backup.PG_TYPE_OID, Oid: domain2Oid}
+				backupSet := map[utils.UniqueID]bool{domainEntry: true, domain2Entry: true}
 
 				deps := backup.GetDependencies(connectionPool, backupSet)
 
@@ -216,9 +217,9 @@ object-relational database management system');`)
 				baseInOid := testutils.OidFromObjectName(connectionPool, "public", "base_fn_in", backup.TYPE_FUNCTION)
 				baseOutOid := testutils.OidFromObjectName(connectionPool, "public", "base_fn_out", backup.TYPE_FUNCTION)
 
-				baseInEntry := backup.UniqueID{ClassID: backup.PG_PROC_OID, Oid: baseInOid}
-				baseOutEntry := backup.UniqueID{ClassID: backup.PG_PROC_OID, Oid: baseOutOid}
-				backupSet := map[backup.UniqueID]bool{baseEntry: true, baseInEntry: true, baseOutEntry: true}
+				baseInEntry := utils.UniqueID{ClassID: backup.PG_PROC_OID, Oid: baseInOid}
+				baseOutEntry := utils.UniqueID{ClassID: backup.PG_PROC_OID, Oid: baseOutOid}
+				backupSet := map[utils.UniqueID]bool{baseEntry: true, baseInEntry: true, baseOutEntry: true}
 
 				deps := backup.GetDependencies(connectionPool, backupSet)
 
@@ -232,8 +233,8 @@ object-relational database management system');`)
 				defer testhelper.AssertQueryRuns(connectionPool, "DROP TYPE public.comp_type")
 
 				compositeOid := testutils.OidFromObjectName(connectionPool, "public", "comp_type", backup.TYPE_TYPE)
-				compositeEntry := backup.UniqueID{ClassID: backup.PG_TYPE_OID, Oid: compositeOid}
-				backupSet := map[backup.UniqueID]bool{baseEntry: true, compositeEntry: true}
+				compositeEntry := utils.UniqueID{ClassID: backup.PG_TYPE_OID, Oid: compositeOid}
+				backupSet := map[utils.UniqueID]bool{baseEntry: true, compositeEntry: true}
 
 				deps := backup.GetDependencies(connectionPool, backupSet)
 
@@ -252,10 +253,10 @@ object-relational database management system');`)
 				defer testhelper.AssertQueryRuns(connectionPool, "DROP TYPE public.comp_type")
 
 				base2Oid := testutils.OidFromObjectName(connectionPool, "public", "base_type2", backup.TYPE_TYPE)
-				base2Entry := backup.UniqueID{ClassID: backup.PG_TYPE_OID, Oid: base2Oid}
+				base2Entry := utils.UniqueID{ClassID: backup.PG_TYPE_OID, Oid: base2Oid}
 				compositeOid := testutils.OidFromObjectName(connectionPool, "public", "comp_type", backup.TYPE_TYPE)
-				compositeEntry := backup.UniqueID{ClassID: backup.PG_TYPE_OID, Oid: compositeOid}
-				backupSet := map[backup.UniqueID]bool{baseEntry: true, base2Entry: true, compositeEntry: true}
+				compositeEntry := utils.UniqueID{ClassID: backup.PG_TYPE_OID, Oid: compositeOid}
+				backupSet := map[utils.UniqueID]bool{baseEntry: true, base2Entry: true, compositeEntry: true}
 
 				deps := backup.GetDependencies(connectionPool, backupSet)
 
@@ -269,8 +270,8 @@ object-relational database management system');`)
 				defer testhelper.AssertQueryRuns(connectionPool, "DROP TYPE public.comp_type")
 
 				compositeOid := testutils.OidFromObjectName(connectionPool, "public", "comp_type", backup.TYPE_TYPE)
-				compositeEntry := backup.UniqueID{ClassID: backup.PG_TYPE_OID, Oid: compositeOid}
-				backupSet := map[backup.UniqueID]bool{baseEntry: true, compositeEntry: true}
+				compositeEntry := utils.UniqueID{ClassID: backup.PG_TYPE_OID, Oid: compositeOid}
+				backupSet := map[utils.UniqueID]bool{baseEntry: true, compositeEntry: true}
 
 				deps := backup.GetDependencies(connectionPool, backupSet)
 
@@ -287,9 +288,9 @@ object-relational database management system');`)
 				tableOid := testutils.OidFromObjectName(connectionPool, "public", "my_table", backup.TYPE_RELATION)
 				typeOid := testutils.OidFromObjectName(connectionPool, "public", "my_type", backup.TYPE_TYPE)
 
-				tableEntry := backup.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: tableOid}
-				typeEntry := backup.UniqueID{ClassID: backup.PG_TYPE_OID, Oid: typeOid}
-				backupSet := map[backup.UniqueID]bool{tableEntry: true, typeEntry: true}
+				tableEntry := utils.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: tableOid}
+				typeEntry := utils.UniqueID{ClassID: backup.PG_TYPE_OID, Oid: typeOid}
+				backupSet := map[utils.UniqueID]bool{tableEntry: true, typeEntry: true}
 
 				deps := backup.GetDependencies(connectionPool, backupSet)
 
@@ -299,4 +300,45 @@ object-relational database management system');`)
 			})
 		})
 	})
+	Describe("AddColumnDefaultAndConstraintDependencies", func() {
+		It("adds dependencies of a table on the functions and sequences used in its column defaults and constraints", func() {
+			testhelper.AssertQueryRuns(connectionPool, "CREATE FUNCTION public.is_positive(integer) RETURNS boolean AS 'SELECT $1 > 0' LANGUAGE SQL IMMUTABLE")
+			defer testhelper.AssertQueryRuns(connectionPool, "DROP FUNCTION public.is_positive(integer)")
+			testhelper.AssertQueryRuns(connectionPool, "CREATE SEQUENCE public.id_seq")
+			defer testhelper.AssertQueryRuns(connectionPool, "DROP SEQUENCE public.id_seq")
+			testhelper.AssertQueryRuns(connectionPool, "CREATE TABLE public.foo(i int DEFAULT nextval('public.id_seq') CHECK (public.is_positive(i)))")
+			defer testhelper.AssertQueryRuns(connectionPool, "DROP TABLE public.foo")
+
+			tableOid := testutils.OidFromObjectName(connectionPool, "public", "foo", backup.TYPE_RELATION)
+			sequenceOid := testutils.OidFromObjectName(connectionPool, "public", "id_seq", backup.TYPE_RELATION)
+			functionOid := testutils.OidFromObjectName(connectionPool, "public", "is_positive", backup.TYPE_FUNCTION)
+			tableEntry := utils.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: tableOid}
+			sequenceEntry := utils.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: sequenceOid}
+			functionEntry := utils.UniqueID{ClassID: backup.PG_PROC_OID, Oid: functionOid}
+			backupSet := map[utils.UniqueID]bool{tableEntry: true, sequenceEntry: true, functionEntry: true}
+
+			deps := backup.DependencyMap{}
+			backup.AddColumnDefaultAndConstraintDependencies(connectionPool, deps, backupSet)
+
+			Expect(deps).To(HaveLen(1))
+			Expect(deps[tableEntry]).To(HaveLen(2))
+			Expect(deps[tableEntry]).To(HaveKey(sequenceEntry))
+			Expect(deps[tableEntry]).To(HaveKey(functionEntry))
+		})
+		It("does not add dependencies on objects that are not in the backup", func() {
+			testhelper.AssertQueryRuns(connectionPool, "CREATE SEQUENCE public.id_seq")
+			defer testhelper.AssertQueryRuns(connectionPool, "DROP SEQUENCE public.id_seq")
+			testhelper.AssertQueryRuns(connectionPool, "CREATE TABLE public.foo(i int DEFAULT nextval('public.id_seq'))")
+			defer testhelper.AssertQueryRuns(connectionPool, "DROP TABLE public.foo")
+
+			tableOid := testutils.OidFromObjectName(connectionPool, "public", "foo", backup.TYPE_RELATION)
+			tableEntry := utils.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: tableOid}
+			backupSet := map[utils.UniqueID]bool{tableEntry: true}
+
+			deps := backup.DependencyMap{}
+			backup.AddColumnDefaultAndConstraintDependencies(connectionPool, deps, backupSet)
+
+			Expect(deps).To(BeEmpty())
+		})
+	})
 })
@@ -7,6 +7,7 @@ import (
 	"github.com/greenplum-db/gp-common-go-libs/testhelper"
 	"github.com/greenplum-db/gpbackup/backup"
 	"github.com/greenplum-db/gpbackup/testutils"
+	"github.com/greenplum-db/gpbackup/utils"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -116,7 +117,7 @@ var _ = Describe("backup integration create statement tests", func() {
 		})
 		It("creates a resource queue with all attributes", func() {
 			everythingQueue := backup.ResourceQueue{Oid: 1, Name: `"everythingQueue"`, ActiveStatements: 7, MaxCost: "32.80", CostOvercommit: true, MinCost: "22.80", Priority: "low", MemoryLimit: "2GB"}
-			emptyMetadataMap := map[backup.UniqueID]backup.ObjectMetadata{}
+			emptyMetadataMap := map[utils.UniqueID]backup.ObjectMetadata{}
 
 			backup.PrintCreateResourceQueueStatements(backupfile, toc, []backup.ResourceQueue{everythingQueue}, emptyMetadataMap)
 
@@ -140,7 +141,7 @@ var _ = Describe("backup integration create statement tests", func() {
 		})
 		It("creates a basic resource group", func() {
 			someGroup := backup.ResourceGroup{Oid: 1, Name: "some_group", CPURateLimit: 10, MemoryLimit: 20, Concurrency: 15, MemorySharedQuota: 25, MemorySpillRatio: 30, MemoryAuditor: 0, Cpuset: "-1"}
-			emptyMetadataMap := map[backup.UniqueID]backup.ObjectMetadata{}
+			emptyMetadataMap := map[utils.UniqueID]backup.ObjectMetadata{}
 
 			backup.PrintCreateResourceGroupStatements(backupfile, toc, []backup.ResourceGroup{someGroup}, emptyMetadataMap)
 
@@ -159,7 +160,7 @@ var _ = Describe("backup integration create statement tests", func() {
 		})
 		It("alters a default resource group", func() {
 			defaultGroup := backup.ResourceGroup{Oid: 1, Name: "default_group", CPURateLimit: 10, MemoryLimit: 20, Concurrency: 15, MemorySharedQuota: 25, MemorySpillRatio: 30, MemoryAuditor: 0, Cpuset: "-1"}
-			emptyMetadataMap := map[backup.UniqueID]backup.ObjectMetadata{}
+			emptyMetadataMap := map[utils.UniqueID]backup.ObjectMetadata{}
 
 			backup.PrintCreateResourceGroupStatements(backupfile, toc, []backup.ResourceGroup{defaultGroup}, emptyMetadataMap)
 
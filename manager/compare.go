@@ -0,0 +1,336 @@
+package manager
+
+/*
+ * This file contains structs and functions related to comparing the contents
+ * of two backups.
+ */
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gp-common-go-libs/iohelper"
+	"github.com/greenplum-db/gpbackup/backup_filepath"
+	"github.com/greenplum-db/gpbackup/backup_history"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	OUTPUT_FORMAT = "format"
+	OUTPUT_FILE   = "output-file"
+)
+
+var compareSections = []string{"global", "predata", "postdata"}
+
+type ObjectDifference struct {
+	Section         string
+	Schema          string
+	Name            string
+	ObjectType      string
+	ReferenceObject string
+	OldStatement    string
+	NewStatement    string
+}
+
+type RowCountDifference struct {
+	Schema      string
+	Name        string
+	OldRowCount int64
+	NewRowCount int64
+	InOld       bool
+	InNew       bool
+}
+
+type TableName struct {
+	Schema string
+	Name   string
+}
+
+type BackupComparison struct {
+	OldTimestamp    string
+	NewTimestamp    string
+	AddedObjects    []ObjectDifference
+	RemovedObjects  []ObjectDifference
+	ChangedObjects  []ObjectDifference
+	RowCountChanges []RowCountDifference
+}
+
+/*
+ * The contents of a single backup that are needed for a comparison.  Row counts
+ * are keyed by table, and for incremental backups they are taken from the
+ * backup in the restore plan that actually contains each table's data.
+ */
+type BackupContents struct {
+	Timestamp  string
+	Statements map[string]utils.StatementWithType
+	Sections   map[string]string
+	RowCounts  map[TableName]int64
+	HasData    bool
+}
+
+func NewCompareCommand() *cobra.Command {
+	compareCmd := &cobra.Command{
+		Use:   "compare <old timestamp> <new timestamp>",
+		Short: "Report objects and table row counts that differ between two backups",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			defer DoTeardown()
+			DoSetup(cmd)
+			DoCompare(args[0], args[1])
+		},
+	}
+	compareCmd.Flags().String(OUTPUT_FORMAT, "text", "The output format of the comparison, either text or json")
+	compareCmd.Flags().String(OUTPUT_FILE, "", "Write the comparison to the specified file instead of standard output")
+	return compareCmd
+}
+
+func DoCompare(oldTimestamp string, newTimestamp string) {
+	format := MustGetFlagString(OUTPUT_FORMAT)
+	if format != "text" && format != "json" {
+		gplog.Fatal(errors.Errorf("Invalid output format %s.  Valid formats are text and json.", format), "")
+	}
+
+	gplog.Verbose("Reading contents of backup %s", oldTimestamp)
+	oldContents := ReadBackupContents(GetFPInfoForTimestamp(oldTimestamp))
+	gplog.Verbose("Reading contents of backup %s", newTimestamp)
+	newContents := ReadBackupContents(GetFPInfoForTimestamp(newTimestamp))
+	comparison := CompareBackups(oldContents, newContents)
+
+	var output io.Writer = os.Stdout
+	if outputFilename := MustGetFlagString(OUTPUT_FILE); outputFilename != "" {
+		outputFile := iohelper.MustOpenFileForWriting(outputFilename)
+		defer outputFile.Close()
+		output = outputFile
+	}
+	if format == "json" {
+		err := WriteComparisonJSON(output, comparison)
+		gplog.FatalOnError(err)
+	} else {
+		WriteComparisonText(output, comparison)
+	}
+}
+
+/*
+ * Statements are keyed by section and object identity.  Identical keys can
+ * occur within one backup, so repeated occurrences are numbered in TOC order.
+ */
+func getObjectKey(section string, entry utils.StatementWithType, occurrences map[string]int) string {
+	key := fmt.Sprintf("%s|%s|%s|%s|%s", section, entry.ObjectType, entry.Schema, entry.Name, entry.ReferenceObject)
+	occurrences[key]++
+	if occurrences[key] > 1 {
+		key = fmt.Sprintf("%s|%d", key, occurrences[key])
+	}
+	return key
+}
+
+func ReadBackupContents(fpInfo backup_filepath.FilePathInfo) BackupContents {
+	config := ReadBackupConfig(fpInfo)
+	toc := ReadBackupTOC(fpInfo)
+	contents := BackupContents{
+		Timestamp:  fpInfo.Timestamp,
+		Statements: make(map[string]utils.StatementWithType, 0),
+		Sections:   make(map[string]string, 0),
+		RowCounts:  make(map[TableName]int64, 0),
+		HasData:    !config.MetadataOnly,
+	}
+
+	if !config.DataOnly {
+		var metadataFile io.ReaderAt
+		if !config.MetadataDirectory {
+			RecoverFilesUsingPlugin(fpInfo.GetMetadataFilePath())
+			file := iohelper.MustOpenFileForReading(fpInfo.GetMetadataFilePath())
+			defer file.Close()
+			metadataFile = file
+		}
+		occurrences := make(map[string]int, 0)
+		for _, section := range compareSections {
+			sectionFile := metadataFile
+			if config.MetadataDirectory {
+				sectionFile = utils.NewMetadataDirectoryReader(fpInfo.GetMetadataDirectoryPath(), toc, section)
+			}
+			statements := toc.GetSQLStatementForObjectTypes(section, sectionFile, []string{}, []string{"SESSION GUCS"}, []string{}, []string{}, []string{}, []string{})
+			for _, statement := range statements {
+				key := getObjectKey(section, statement, occurrences)
+				contents.Statements[key] = statement
+				contents.Sections[key] = section
+			}
+		}
+	}
+
+	if contents.HasData {
+		contents.RowCounts = GetRowCountsFromRestorePlan(fpInfo, config, toc)
+	}
+	return contents
+}
+
+/*
+ * An incremental backup's TOC only contains data entries for the tables backed
+ * up at that timestamp, so the row counts for the remaining tables are read
+ * from the earlier backups listed in its restore plan.
+ */
+func GetRowCountsFromRestorePlan(fpInfo backup_filepath.FilePathInfo, config *backup_history.BackupConfig, toc *utils.TOC) map[TableName]int64 {
+	rowCounts := make(map[TableName]int64, 0)
+	if config.RestorePlan == nil {
+		for _, entry := range toc.DataEntries {
+			rowCounts[TableName{Schema: entry.Schema, Name: entry.Name}] = entry.RowsCopied
+		}
+		return rowCounts
+	}
+	for _, planEntry := range config.RestorePlan {
+		planTOC := toc
+		if planEntry.Timestamp != fpInfo.Timestamp {
+			planTOC = ReadBackupTOC(GetFPInfoForTimestamp(planEntry.Timestamp))
+		}
+		planTables := utils.NewIncludeSet(planEntry.TableFQNs)
+		for _, entry := range planTOC.DataEntries {
+			if planTables.MatchesFilter(utils.MakeFQN(entry.Schema, entry.Name)) {
+				rowCounts[TableName{Schema: entry.Schema, Name: entry.Name}] = entry.RowsCopied
+			}
+		}
+	}
+	return rowCounts
+}
+
+func newObjectDifference(section string, oldStatement *utils.StatementWithType, newStatement *utils.StatementWithType) ObjectDifference {
+	difference := ObjectDifference{Section: section}
+	statement := newStatement
+	if statement == nil {
+		statement = oldStatement
+	}
+	difference.Schema = statement.Schema
+	difference.Name = statement.Name
+	difference.ObjectType = statement.ObjectType
+	difference.ReferenceObject = statement.ReferenceObject
+	if oldStatement != nil {
+		difference.OldStatement = oldStatement.Statement
+	}
+	if newStatement != nil {
+		difference.NewStatement = newStatement.Statement
+	}
+	return difference
+}
+
+func CompareBackups(oldContents BackupContents, newContents BackupContents) BackupComparison {
+	comparison := BackupComparison{
+		OldTimestamp:    oldContents.Timestamp,
+		NewTimestamp:    newContents.Timestamp,
+		AddedObjects:    make([]ObjectDifference, 0),
+		RemovedObjects:  make([]ObjectDifference, 0),
+		ChangedObjects:  make([]ObjectDifference, 0),
+		RowCountChanges: make([]RowCountDifference, 0),
+	}
+
+	for _, key := range sortedStatementKeys(newContents.Statements) {
+		newStatement := newContents.Statements[key]
+		oldStatement, inOld := oldContents.Statements[key]
+		if !inOld {
+			comparison.AddedObjects = append(comparison.AddedObjects, newObjectDifference(newContents.Sections[key], nil, &newStatement))
+		} else if oldStatement.Statement != newStatement.Statement {
+			comparison.ChangedObjects = append(comparison.ChangedObjects, newObjectDifference(newContents.Sections[key], &oldStatement, &newStatement))
+		}
+	}
+	for _, key := range sortedStatementKeys(oldContents.Statements) {
+		if _, inNew := newContents.Statements[key]; !inNew {
+			oldStatement := oldContents.Statements[key]
+			comparison.RemovedObjects = append(comparison.RemovedObjects, newObjectDifference(oldContents.Sections[key], &oldStatement, nil))
+		}
+	}
+
+	// Row counts can only be compared if both backups contain data
+	if oldContents.HasData && newContents.HasData {
+		comparison.RowCountChanges = compareRowCounts(oldContents.RowCounts, newContents.RowCounts)
+	}
+	return comparison
+}
+
+func compareRowCounts(oldRowCounts map[TableName]int64, newRowCounts map[TableName]int64) []RowCountDifference {
+	tables := make([]TableName, 0)
+	for table := range newRowCounts {
+		tables = append(tables, table)
+	}
+	for table := range oldRowCounts {
+		if _, ok := newRowCounts[table]; !ok {
+			tables = append(tables, table)
+		}
+	}
+	sort.Slice(tables, func(i int, j int) bool {
+		return utils.MakeFQN(tables[i].Schema, tables[i].Name) < utils.MakeFQN(tables[j].Schema, tables[j].Name)
+	})
+
+	differences := make([]RowCountDifference, 0)
+	for _, table := range tables {
+		oldRowCount, inOld := oldRowCounts[table]
+		newRowCount, inNew := newRowCounts[table]
+		if inOld && inNew && oldRowCount == newRowCount {
+			continue
+		}
+		differences = append(differences, RowCountDifference{Schema: table.Schema, Name: table.Name, OldRowCount: oldRowCount,
+			NewRowCount: newRowCount, InOld: inOld, InNew: inNew})
+	}
+	return differences
+}
+
+func sortedStatementKeys(statements map[string]utils.StatementWithType) []string {
+	keys := make([]string, 0, len(statements))
+	for key := range statements {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatObjectDifference(difference ObjectDifference) string {
+	name := difference.Name
+	if difference.Schema != "" {
+		name = utils.MakeFQN(difference.Schema, difference.Name)
+	}
+	objectStr := fmt.Sprintf("%s %s %s", difference.Section, difference.ObjectType, name)
+	if difference.ReferenceObject != "" {
+		objectStr += fmt.Sprintf(" (on %s)", difference.ReferenceObject)
+	}
+	return objectStr
+}
+
+func WriteComparisonText(output io.Writer, comparison BackupComparison) {
+	utils.MustPrintf(output, "Comparing backup %s to backup %s\n", comparison.OldTimestamp, comparison.NewTimestamp)
+
+	utils.MustPrintf(output, "\nObjects added: %d\n", len(comparison.AddedObjects))
+	for _, difference := range comparison.AddedObjects {
+		utils.MustPrintf(output, "  + %s\n", formatObjectDifference(difference))
+	}
+	utils.MustPrintf(output, "\nObjects removed: %d\n", len(comparison.RemovedObjects))
+	for _, difference := range comparison.RemovedObjects {
+		utils.MustPrintf(output, "  - %s\n", formatObjectDifference(difference))
+	}
+	utils.MustPrintf(output, "\nObjects changed: %d\n", len(comparison.ChangedObjects))
+	for _, difference := range comparison.ChangedObjects {
+		utils.MustPrintf(output, "  ~ %s\n", formatObjectDifference(difference))
+	}
+
+	utils.MustPrintf(output, "\nTable row count changes: %d\n", len(comparison.RowCountChanges))
+	for _, difference := range comparison.RowCountChanges {
+		fqn := utils.MakeFQN(difference.Schema, difference.Name)
+		if !difference.InOld {
+			utils.MustPrintf(output, "  %s: (not in backup) -> %d\n", fqn, difference.NewRowCount)
+		} else if !difference.InNew {
+			utils.MustPrintf(output, "  %s: %d -> (not in backup)\n", fqn, difference.OldRowCount)
+		} else {
+			utils.MustPrintf(output, "  %s: %d -> %d (%+d)\n", fqn, difference.OldRowCount, difference.NewRowCount, difference.NewRowCount-difference.OldRowCount)
+		}
+	}
+}
+
+func WriteComparisonJSON(output io.Writer, comparison BackupComparison) error {
+	contents, err := json.MarshalIndent(comparison, "", "  ")
+	if err != nil {
+		return err
+	}
+	utils.MustPrintBytes(output, append(contents, '\n'))
+	return nil
+}
@@ -0,0 +1,159 @@
+package manager_test
+
+import (
+	"encoding/json"
+
+	"github.com/greenplum-db/gp-common-go-libs/structmatcher"
+	"github.com/greenplum-db/gpbackup/backup_filepath"
+	"github.com/greenplum-db/gpbackup/backup_history"
+	"github.com/greenplum-db/gpbackup/manager"
+	"github.com/greenplum-db/gpbackup/utils"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("manager/compare tests", func() {
+	var oldContents, newContents manager.BackupContents
+	tableStatement := utils.StatementWithType{Schema: "public", Name: "foo", ObjectType: "TABLE", Statement: "CREATE TABLE public.foo (i int);"}
+	alteredTableStatement := utils.StatementWithType{Schema: "public", Name: "foo", ObjectType: "TABLE", Statement: "CREATE TABLE public.foo (i int, j text);"}
+	viewStatement := utils.StatementWithType{Schema: "public", Name: "bar", ObjectType: "VIEW", Statement: "CREATE VIEW public.bar AS SELECT 1;"}
+	indexStatement := utils.StatementWithType{Schema: "public", Name: "foo_idx", ObjectType: "INDEX", ReferenceObject: "public.foo", Statement: "CREATE INDEX foo_idx ON public.foo USING btree (i);"}
+
+	BeforeEach(func() {
+		oldContents = manager.BackupContents{
+			Timestamp: "20170101010101",
+			Statements: map[string]utils.StatementWithType{
+				"predata|TABLE|public|foo|": tableStatement,
+				"predata|VIEW|public|bar|":  viewStatement,
+			},
+			Sections: map[string]string{
+				"predata|TABLE|public|foo|": "predata",
+				"predata|VIEW|public|bar|":  "predata",
+			},
+			RowCounts: map[manager.TableName]int64{{Schema: "public", Name: "foo"}: 10, {Schema: "public", Name: "baz"}: 5},
+			HasData:   true,
+		}
+		newContents = manager.BackupContents{
+			Timestamp: "20170102010101",
+			Statements: map[string]utils.StatementWithType{
+				"predata|TABLE|public|foo|":                alteredTableStatement,
+				"postdata|INDEX|public|foo_idx|public.foo": indexStatement,
+			},
+			Sections: map[string]string{
+				"predata|TABLE|public|foo|":                "predata",
+				"postdata|INDEX|public|foo_idx|public.foo": "postdata",
+			},
+			RowCounts: map[manager.TableName]int64{{Schema: "public", Name: "foo"}: 15, {Schema: "public", Name: "qux"}: 3},
+			HasData:   true,
+		}
+	})
+	Describe("CompareBackups", func() {
+		It("reports added, removed, and changed objects", func() {
+			comparison := manager.CompareBackups(oldContents, newContents)
+
+			Expect(comparison.OldTimestamp).To(Equal("20170101010101"))
+			Expect(comparison.NewTimestamp).To(Equal("20170102010101"))
+			Expect(comparison.AddedObjects).To(HaveLen(1))
+			structmatcher.ExpectStructsToMatch(&manager.ObjectDifference{Section: "postdata", Schema: "public", Name: "foo_idx", ObjectType: "INDEX", ReferenceObject: "public.foo", NewStatement: indexStatement.Statement}, &comparison.AddedObjects[0])
+			Expect(comparison.RemovedObjects).To(HaveLen(1))
+			structmatcher.ExpectStructsToMatch(&manager.ObjectDifference{Section: "predata", Schema: "public", Name: "bar", ObjectType: "VIEW", OldStatement: viewStatement.Statement}, &comparison.RemovedObjects[0])
+			Expect(comparison.ChangedObjects).To(HaveLen(1))
+			structmatcher.ExpectStructsToMatch(&manager.ObjectDifference{Section: "predata", Schema: "public", Name: "foo", ObjectType: "TABLE", OldStatement: tableStatement.Statement, NewStatement: alteredTableStatement.Statement}, &comparison.ChangedObjects[0])
+		})
+		It("reports row count changes sorted by table", func() {
+			comparison := manager.CompareBackups(oldContents, newContents)
+
+			Expect(comparison.RowCountChanges).To(Equal([]manager.RowCountDifference{
+				{Schema: "public", Name: "baz", OldRowCount: 5, InOld: true},
+				{Schema: "public", Name: "foo", OldRowCount: 10, NewRowCount: 15, InOld: true, InNew: true},
+				{Schema: "public", Name: "qux", NewRowCount: 3, InNew: true},
+			}))
+		})
+		It("does not report tables whose row counts are unchanged", func() {
+			newContents.RowCounts = oldContents.RowCounts
+
+			comparison := manager.CompareBackups(oldContents, newContents)
+
+			Expect(comparison.RowCountChanges).To(BeEmpty())
+		})
+		It("does not report row count changes if either backup is metadata-only", func() {
+			newContents.HasData = false
+
+			comparison := manager.CompareBackups(oldContents, newContents)
+
+			Expect(comparison.RowCountChanges).To(BeEmpty())
+		})
+		It("reports no differences for identical backups", func() {
+			comparison := manager.CompareBackups(oldContents, oldContents)
+
+			Expect(comparison.AddedObjects).To(BeEmpty())
+			Expect(comparison.RemovedObjects).To(BeEmpty())
+			Expect(comparison.ChangedObjects).To(BeEmpty())
+			Expect(comparison.RowCountChanges).To(BeEmpty())
+		})
+	})
+	Describe("GetRowCountsFromRestorePlan", func() {
+		It("uses the backup's own data entries when there is no restore plan", func() {
+			toc := &utils.TOC{DataEntries: []utils.MasterDataEntry{
+				{Schema: "public", Name: "foo", RowsCopied: 10},
+				{Schema: "public", Name: "bar", RowsCopied: 0},
+			}}
+			fpInfo := backup_filepath.FilePathInfo{Timestamp: "20170101010101"}
+
+			rowCounts := manager.GetRowCountsFromRestorePlan(fpInfo, &backup_history.BackupConfig{}, toc)
+
+			Expect(rowCounts).To(Equal(map[manager.TableName]int64{{Schema: "public", Name: "foo"}: 10, {Schema: "public", Name: "bar"}: 0}))
+		})
+		It("only uses the tables listed for the backup's own timestamp in its restore plan", func() {
+			toc := &utils.TOC{DataEntries: []utils.MasterDataEntry{
+				{Schema: "public", Name: "foo", RowsCopied: 10},
+				{Schema: "public", Name: "bar", RowsCopied: 4},
+			}}
+			fpInfo := backup_filepath.FilePathInfo{Timestamp: "20170101010101"}
+			config := &backup_history.BackupConfig{RestorePlan: []backup_history.RestorePlanEntry{{Timestamp: "20170101010101", TableFQNs: []string{"public.foo"}}}}
+
+			rowCounts := manager.GetRowCountsFromRestorePlan(fpInfo, config, toc)
+
+			Expect(rowCounts).To(Equal(map[manager.TableName]int64{{Schema: "public", Name: "foo"}: 10}))
+		})
+	})
+	Describe("WriteComparisonText", func() {
+		It("writes each difference on its own line", func() {
+			buffer := gbytes.NewBuffer()
+
+			manager.WriteComparisonText(buffer, manager.CompareBackups(oldContents, newContents))
+
+			Expect(string(buffer.Contents())).To(Equal(`Comparing backup 20170101010101 to backup 20170102010101
+
+Objects added: 1
+  + postdata INDEX public.foo_idx (on public.foo)
+
+Objects removed: 1
+  - predata VIEW public.bar
+
+Objects changed: 1
+  ~ predata TABLE public.foo
+
+Table row count changes: 3
+  public.baz: 5 -> (not in backup)
+  public.foo: 10 -> 15 (+5)
+  public.qux: (not in backup) -> 3
+`))
+		})
+	})
+	Describe("WriteComparisonJSON", func() {
+		It("writes a comparison that can be read back", func() {
+			buffer := gbytes.NewBuffer()
+			comparison := manager.CompareBackups(oldContents, newContents)
+
+			err := manager.WriteComparisonJSON(buffer, comparison)
+
+			Expect(err).ToNot(HaveOccurred())
+			var result manager.BackupComparison
+			err = json.Unmarshal(buffer.Contents(), &result)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(comparison))
+		})
+	})
+})
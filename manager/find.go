@@ -0,0 +1,296 @@
+package manager
+
+/*
+ * This file contains structs and functions related to finding the backups
+ * that contain a table and restoring the table from one of them.
+ */
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gp-common-go-libs/iohelper"
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/greenplum-db/gpbackup/backup_filepath"
+	"github.com/greenplum-db/gpbackup/backup_history"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const RESTORE = "restore"
+
+/*
+ * A table found in a backup.  Incremental backups only contain the data of
+ * tables that changed, so DataTimestamp is the backup in the restore plan
+ * whose data files hold the table's data.  RowCount is -1 if that backup's
+ * table of contents could not be read.
+ */
+type TableMatch struct {
+	Timestamp     string
+	DatabaseName  string
+	BackupDir     string
+	Schema        string
+	Name          string
+	HasData       bool
+	DataTimestamp string
+	RowCount      int64
+	ObjectTypes   []string
+}
+
+func (match TableMatch) FQN() string {
+	return utils.MakeFQN(match.Schema, match.Name)
+}
+
+func NewFindTableCommand() *cobra.Command {
+	findCmd := &cobra.Command{
+		Use:   "find-table <schema.table or pattern>",
+		Short: "List the backups that contain a table, or the tables matching a shell-style pattern such as sales.order*",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			defer DoTeardown()
+			DoSetup(cmd)
+			DoFindTable(args[0])
+		},
+	}
+	findCmd.Flags().String(utils.DBNAME, "", "Only search backups of the specified database")
+	findCmd.Flags().Bool(RESTORE, false, "Restore the table with gprestore from the most recent backup that contains its data")
+	findCmd.Flags().String(utils.REDIRECT_DB, "", "Restore the table to the specified database instead of the database that was backed up")
+	return findCmd
+}
+
+func DoFindTable(pattern string) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		gplog.Fatal(errors.Errorf("Table pattern %s is invalid", pattern), "")
+	}
+	if MustGetFlagString(utils.REDIRECT_DB) != "" && !MustGetFlagBool(RESTORE) {
+		gplog.Fatal(errors.Errorf("Cannot use --redirect-db without --restore"), "")
+	}
+
+	filter := ListFilter{DatabaseName: MustGetFlagString(utils.DBNAME)}
+	matches := make([]TableMatch, 0)
+	tocReaders := make(map[string]func(timestamp string) *utils.TOC, 0)
+	for _, config := range FilterHistory(ReadHistory(), filter) {
+		if !config.IsSuccessful() || (config.Plugin != "") != (pluginConfig != nil) {
+			continue
+		}
+		if backupDir := MustGetFlagString(utils.BACKUP_DIR); backupDir != "" && config.BackupDir != backupDir {
+			continue
+		}
+		if _, ok := tocReaders[config.BackupDir]; !ok {
+			tocReaders[config.BackupDir] = readTOCForSearch(config.BackupDir)
+		}
+		backupConfig := config
+		matches = append(matches, FindTableInBackup(&backupConfig, pattern, tocReaders[config.BackupDir])...)
+	}
+	WriteTableMatches(os.Stdout, matches)
+
+	if MustGetFlagBool(RESTORE) {
+		match := ChooseMatchToRestore(pattern, matches)
+		RunRestoreForMatch(match, MustGetFlagString(utils.REDIRECT_DB))
+	}
+}
+
+/*
+ * Backups that have been deleted outside of gpbackup_manager, or whose files
+ * cannot be retrieved from plugin storage, are skipped rather than ending the
+ * search.  Tables of contents are cached because the backups in an
+ * incremental chain share the same earlier backups.
+ */
+func readTOCForSearch(backupDir string) func(timestamp string) *utils.TOC {
+	tocCache := make(map[string]*utils.TOC, 0)
+	return func(timestamp string) *utils.TOC {
+		if toc, ok := tocCache[timestamp]; ok {
+			return toc
+		}
+		tocCache[timestamp] = nil
+		fpInfo := backup_filepath.NewFilePathInfo(globalCluster, backupDir, timestamp, "")
+		if backupDir != "" {
+			matches, err := operating.System.Glob(fmt.Sprintf("%s/*-1/backups/*/%s", backupDir, timestamp))
+			if err != nil || len(matches) == 0 {
+				gplog.Verbose("Unable to find backup %s in %s", timestamp, backupDir)
+				return nil
+			}
+			fpInfo.UserSpecifiedSegPrefix = backup_filepath.ParseSegPrefix(backupDir, timestamp)
+		}
+		tocFilename := fpInfo.GetTOCFilePath()
+		if pluginConfig != nil {
+			if err := pluginConfig.RestoreFile(tocFilename); err != nil {
+				gplog.Verbose("Unable to recover table of contents of backup %s: %v", timestamp, err)
+				return nil
+			}
+		} else if !iohelper.FileExistsAndIsReadable(tocFilename) {
+			gplog.Verbose("Unable to find table of contents of backup %s", timestamp)
+			return nil
+		}
+		tocCache[timestamp] = utils.NewTOC(tocFilename)
+		return tocCache[timestamp]
+	}
+}
+
+func entryTableFQN(entry utils.MetadataEntry) string {
+	if entry.ReferenceObject != "" {
+		return entry.ReferenceObject
+	} else if entry.ObjectType == "TABLE" || entry.ObjectType == "STATISTICS" {
+		return utils.MakeFQN(entry.Schema, entry.Name)
+	}
+	return ""
+}
+
+func FindTableInBackup(config *backup_history.BackupConfig, pattern string, getTOC func(timestamp string) *utils.TOC) []TableMatch {
+	toc := getTOC(config.Timestamp)
+	if toc == nil {
+		return []TableMatch{}
+	}
+
+	dataTimestamps := make(map[string]string, 0)
+	if !config.MetadataOnly {
+		if config.RestorePlan == nil {
+			for _, entry := range toc.DataEntries {
+				dataTimestamps[utils.MakeFQN(entry.Schema, entry.Name)] = config.Timestamp
+			}
+		}
+		for _, planEntry := range config.RestorePlan {
+			for _, fqn := range planEntry.TableFQNs {
+				dataTimestamps[fqn] = planEntry.Timestamp
+			}
+		}
+	}
+
+	tables := make([]TableMatch, 0)
+	tableIndexes := make(map[string]int, 0)
+	addTable := func(schema string, name string) {
+		fqn := utils.MakeFQN(schema, name)
+		if _, ok := tableIndexes[fqn]; ok {
+			return
+		}
+		if matched, _ := path.Match(pattern, fqn); !matched {
+			return
+		}
+		tableIndexes[fqn] = len(tables)
+		tables = append(tables, TableMatch{Timestamp: config.Timestamp, DatabaseName: config.DatabaseName,
+			BackupDir: config.BackupDir, Schema: schema, Name: name, RowCount: -1, ObjectTypes: make([]string, 0)})
+	}
+	for _, entry := range toc.PredataEntries {
+		if entry.ObjectType == "TABLE" {
+			addTable(entry.Schema, entry.Name)
+		}
+	}
+	for _, entry := range toc.DataEntries {
+		addTable(entry.Schema, entry.Name)
+	}
+
+	seenObjectTypes := make(map[string]bool, 0)
+	for _, entries := range [][]utils.MetadataEntry{toc.PredataEntries, toc.PostdataEntries, toc.StatisticsEntries} {
+		for _, entry := range entries {
+			fqn := entryTableFQN(entry)
+			i, ok := tableIndexes[fqn]
+			if !ok || seenObjectTypes[fqn+"|"+entry.ObjectType] {
+				continue
+			}
+			seenObjectTypes[fqn+"|"+entry.ObjectType] = true
+			tables[i].ObjectTypes = append(tables[i].ObjectTypes, entry.ObjectType)
+		}
+	}
+	for i := range tables {
+		dataTimestamp, hasData := dataTimestamps[tables[i].FQN()]
+		if !hasData {
+			continue
+		}
+		tables[i].HasData = true
+		tables[i].DataTimestamp = dataTimestamp
+		tables[i].ObjectTypes = append(tables[i].ObjectTypes, "TABLE DATA")
+		if dataTOC := getTOC(dataTimestamp); dataTOC != nil {
+			for _, entry := range dataTOC.DataEntries {
+				if utils.MakeFQN(entry.Schema, entry.Name) == tables[i].FQN() {
+					tables[i].RowCount = entry.RowsCopied
+				}
+			}
+		}
+	}
+	return tables
+}
+
+func WriteTableMatches(output io.Writer, matches []TableMatch) {
+	if len(matches) == 0 {
+		fmt.Fprintln(output, "No backups containing a matching table were found")
+		return
+	}
+	writer := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "TIMESTAMP\tDATABASE\tTABLE\tROWS\tDATA FROM\tOBJECT TYPES")
+	for _, match := range matches {
+		rows, dataFrom := "-", "-"
+		if match.HasData {
+			rows = "unknown"
+			if match.RowCount >= 0 {
+				rows = fmt.Sprintf("%d", match.RowCount)
+			}
+			dataFrom = "this backup"
+			if match.DataTimestamp != match.Timestamp {
+				dataFrom = fmt.Sprintf("incremental link %s", match.DataTimestamp)
+			}
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\n", match.Timestamp, match.DatabaseName, match.FQN(), rows, dataFrom,
+			strings.Join(match.ObjectTypes, ","))
+	}
+	_ = writer.Flush()
+}
+
+/*
+ * Matches are ordered most recent first, so the best backup from which to
+ * restore is the most recent one containing the table's data, or the most
+ * recent one containing its definition if no backup has its data.
+ */
+func ChooseMatchToRestore(pattern string, matches []TableMatch) TableMatch {
+	if len(matches) == 0 {
+		gplog.Fatal(errors.Errorf("No backups containing a table matching %s were found", pattern), "")
+	}
+	tableFQNs := make(map[string]bool, 0)
+	for _, match := range matches {
+		tableFQNs[match.FQN()] = true
+	}
+	if len(tableFQNs) > 1 {
+		gplog.Fatal(errors.Errorf("Pattern %s matches %d tables.  Specify a single table to restore.", pattern, len(tableFQNs)), "")
+	}
+	for _, match := range matches {
+		if match.HasData {
+			return match
+		}
+	}
+	return matches[0]
+}
+
+func GetRestoreArgsForMatch(match TableMatch, redirectDB string) []string {
+	args := []string{"--timestamp", match.Timestamp, "--include-table", match.FQN()}
+	if match.BackupDir != "" {
+		args = append(args, "--backup-dir", match.BackupDir)
+	}
+	if pluginConfig != nil {
+		args = append(args, "--plugin-config", MustGetFlagString(utils.PLUGIN_CONFIG))
+	}
+	if redirectDB != "" {
+		args = append(args, "--redirect-db", redirectDB)
+	}
+	return args
+}
+
+func RunRestoreForMatch(match TableMatch, redirectDB string) {
+	gprestorePath := "gprestore"
+	if gphome := operating.System.Getenv("GPHOME"); gphome != "" {
+		gprestorePath = path.Join(gphome, "bin", "gprestore")
+	}
+	args := GetRestoreArgsForMatch(match, redirectDB)
+	gplog.Info("Restoring %s from backup %s: %s %s", match.FQN(), match.Timestamp, gprestorePath, strings.Join(args, " "))
+	restoreCmd := exec.Command(gprestorePath, args...)
+	restoreCmd.Stdin = os.Stdin
+	restoreCmd.Stdout = os.Stdout
+	restoreCmd.Stderr = os.Stderr
+	err := restoreCmd.Run()
+	gplog.FatalOnError(err, fmt.Sprintf("Unable to restore %s from backup %s", match.FQN(), match.Timestamp))
+}
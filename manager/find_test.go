@@ -0,0 +1,135 @@
+package manager_test
+
+import (
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/greenplum-db/gpbackup/backup_history"
+	"github.com/greenplum-db/gpbackup/manager"
+	"github.com/greenplum-db/gpbackup/utils"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("manager/find tests", func() {
+	var tocs map[string]*utils.TOC
+	getTOC := func(timestamp string) *utils.TOC {
+		return tocs[timestamp]
+	}
+	BeforeEach(func() {
+		tocs = map[string]*utils.TOC{
+			"20170101010101": {
+				PredataEntries: []utils.MetadataEntry{
+					{Schema: "sales", Name: "orders", ObjectType: "TABLE"},
+					{Schema: "sales", Name: "orders_pkey", ObjectType: "CONSTRAINT", ReferenceObject: "sales.orders"},
+					{Schema: "sales", Name: "customers", ObjectType: "TABLE"},
+				},
+				PostdataEntries: []utils.MetadataEntry{
+					{Schema: "sales", Name: "orders_idx", ObjectType: "INDEX", ReferenceObject: "sales.orders"},
+					{Schema: "sales", Name: "orders_idx2", ObjectType: "INDEX", ReferenceObject: "sales.orders"},
+				},
+				DataEntries: []utils.MasterDataEntry{
+					{Schema: "sales", Name: "orders", RowsCopied: 100},
+					{Schema: "sales", Name: "customers", RowsCopied: 10},
+				},
+			},
+			"20170102010101": {
+				PredataEntries: []utils.MetadataEntry{
+					{Schema: "sales", Name: "orders", ObjectType: "TABLE"},
+					{Schema: "sales", Name: "customers", ObjectType: "TABLE"},
+				},
+				DataEntries: []utils.MasterDataEntry{{Schema: "sales", Name: "customers", RowsCopied: 12}},
+			},
+		}
+	})
+	Describe("FindTableInBackup", func() {
+		It("finds a table whose data is in the backup", func() {
+			config := &backup_history.BackupConfig{DatabaseName: "testdb", Timestamp: "20170101010101",
+				RestorePlan: []backup_history.RestorePlanEntry{{Timestamp: "20170101010101", TableFQNs: []string{"sales.orders", "sales.customers"}}}}
+
+			matches := manager.FindTableInBackup(config, "sales.orders", getTOC)
+
+			Expect(matches).To(Equal([]manager.TableMatch{{Timestamp: "20170101010101", DatabaseName: "testdb", Schema: "sales", Name: "orders",
+				HasData: true, DataTimestamp: "20170101010101", RowCount: 100, ObjectTypes: []string{"TABLE", "CONSTRAINT", "INDEX", "TABLE DATA"}}}))
+		})
+		It("reads the row count of a table from the earlier backup in an incremental chain", func() {
+			config := &backup_history.BackupConfig{DatabaseName: "testdb", Timestamp: "20170102010101", Incremental: true,
+				RestorePlan: []backup_history.RestorePlanEntry{
+					{Timestamp: "20170101010101", TableFQNs: []string{"sales.orders"}},
+					{Timestamp: "20170102010101", TableFQNs: []string{"sales.customers"}},
+				}}
+
+			matches := manager.FindTableInBackup(config, "sales.*", getTOC)
+
+			Expect(matches).To(HaveLen(2))
+			Expect(matches[0].FQN()).To(Equal("sales.orders"))
+			Expect(matches[0].DataTimestamp).To(Equal("20170101010101"))
+			Expect(matches[0].RowCount).To(Equal(int64(100)))
+			Expect(matches[1].FQN()).To(Equal("sales.customers"))
+			Expect(matches[1].DataTimestamp).To(Equal("20170102010101"))
+			Expect(matches[1].RowCount).To(Equal(int64(12)))
+		})
+		It("finds tables without data in a metadata-only backup", func() {
+			config := &backup_history.BackupConfig{DatabaseName: "testdb", Timestamp: "20170101010101", MetadataOnly: true}
+
+			matches := manager.FindTableInBackup(config, "sales.cust*", getTOC)
+
+			Expect(matches).To(HaveLen(1))
+			Expect(matches[0].HasData).To(BeFalse())
+			Expect(matches[0].ObjectTypes).To(Equal([]string{"TABLE"}))
+		})
+		It("skips backups whose table of contents cannot be read", func() {
+			config := &backup_history.BackupConfig{DatabaseName: "testdb", Timestamp: "20170103010101"}
+
+			Expect(manager.FindTableInBackup(config, "sales.orders", getTOC)).To(BeEmpty())
+		})
+	})
+	Describe("WriteTableMatches", func() {
+		It("writes one row per backup containing the table", func() {
+			buffer := gbytes.NewBuffer()
+			matches := []manager.TableMatch{
+				{Timestamp: "20170103010101", DatabaseName: "testdb", Schema: "sales", Name: "orders", ObjectTypes: []string{"TABLE"}},
+				{Timestamp: "20170102010101", DatabaseName: "testdb", Schema: "sales", Name: "orders", HasData: true,
+					DataTimestamp: "20170101010101", RowCount: 100, ObjectTypes: []string{"TABLE", "TABLE DATA"}},
+				{Timestamp: "20170101010101", DatabaseName: "testdb", Schema: "sales", Name: "orders", HasData: true,
+					DataTimestamp: "20170101010101", RowCount: -1, ObjectTypes: []string{"TABLE", "TABLE DATA"}},
+			}
+
+			manager.WriteTableMatches(buffer, matches)
+
+			Expect(string(buffer.Contents())).To(Equal(`TIMESTAMP       DATABASE  TABLE         ROWS     DATA FROM                        OBJECT TYPES
+20170103010101  testdb    sales.orders  -        -                                TABLE
+20170102010101  testdb    sales.orders  100      incremental link 20170101010101  TABLE,TABLE DATA
+20170101010101  testdb    sales.orders  unknown  this backup                      TABLE,TABLE DATA
+`))
+		})
+	})
+	Describe("ChooseMatchToRestore", func() {
+		It("chooses the most recent backup containing the table's data", func() {
+			matches := []manager.TableMatch{
+				{Timestamp: "20170103010101", Schema: "sales", Name: "orders"},
+				{Timestamp: "20170102010101", Schema: "sales", Name: "orders", HasData: true},
+				{Timestamp: "20170101010101", Schema: "sales", Name: "orders", HasData: true},
+			}
+
+			Expect(manager.ChooseMatchToRestore("sales.orders", matches).Timestamp).To(Equal("20170102010101"))
+		})
+		It("panics if the pattern matches more than one table", func() {
+			matches := []manager.TableMatch{
+				{Timestamp: "20170102010101", Schema: "sales", Name: "orders"},
+				{Timestamp: "20170102010101", Schema: "sales", Name: "customers"},
+			}
+
+			defer testhelper.ShouldPanicWithMessage("Pattern sales.* matches 2 tables.  Specify a single table to restore.")
+			manager.ChooseMatchToRestore("sales.*", matches)
+		})
+	})
+	Describe("GetRestoreArgsForMatch", func() {
+		It("restores the single table from the backup directory of the backup", func() {
+			manager.SetPluginConfig(nil)
+			match := manager.TableMatch{Timestamp: "20170102010101", BackupDir: "/backups", Schema: "sales", Name: "orders"}
+
+			Expect(manager.GetRestoreArgsForMatch(match, "recovery")).To(Equal([]string{"--timestamp", "20170102010101",
+				"--include-table", "sales.orders", "--backup-dir", "/backups", "--redirect-db", "recovery"}))
+		})
+	})
+})
@@ -0,0 +1,64 @@
+package manager
+
+import (
+	"github.com/greenplum-db/gp-common-go-libs/cluster"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/spf13/pflag"
+)
+
+/*
+ * This file contains global variables and setter functions for those variables
+ * used in testing.
+ */
+
+/*
+ * Non-flag variables
+ */
+var (
+	globalCluster *cluster.Cluster
+	pluginConfig  *utils.PluginConfig
+	version       string
+)
+
+/*
+ * Command-line flags
+ */
+var cmdFlags *pflag.FlagSet
+
+/*
+ * Setter functions
+ */
+
+func SetCmdFlags(flagSet *pflag.FlagSet) {
+	cmdFlags = flagSet
+}
+
+func SetCluster(cluster *cluster.Cluster) {
+	globalCluster = cluster
+}
+
+func SetPluginConfig(config *utils.PluginConfig) {
+	pluginConfig = config
+}
+
+func SetVersion(v string) {
+	version = v
+}
+
+// Util functions to enable ease of access to global flag values
+
+func MustGetFlagString(flagName string) string {
+	return utils.MustGetFlagString(cmdFlags, flagName)
+}
+
+func MustGetFlagInt(flagName string) int {
+	return utils.MustGetFlagInt(cmdFlags, flagName)
+}
+
+func MustGetFlagBool(flagName string) bool {
+	return utils.MustGetFlagBool(cmdFlags, flagName)
+}
+
+func MustGetFlagStringSlice(flagName string) []string {
+	return utils.MustGetFlagStringSlice(cmdFlags, flagName)
+}
@@ -0,0 +1,356 @@
+package manager
+
+/*
+ * This file contains structs and functions related to listing, describing,
+ * and deleting the backups recorded in the backup history file.
+ */
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/greenplum-db/gp-common-go-libs/cluster"
+	"github.com/greenplum-db/gp-common-go-libs/dbconn"
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/greenplum-db/gpbackup/backup_filepath"
+	"github.com/greenplum-db/gpbackup/backup_history"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	AFTER           = "after"
+	BACKUP_TYPE     = "type"
+	FORCE           = "force"
+	INCLUDE_DELETED = "include-deleted"
+	PLUGIN          = "plugin"
+)
+
+/*
+ * Timestamps sort in chronological order as strings, so After and Before are
+ * compared to backup timestamps directly.  Both bounds are exclusive.
+ */
+type ListFilter struct {
+	DatabaseName   string
+	BackupType     string
+	Plugin         string
+	After          string
+	Before         string
+	IncludeDeleted bool
+}
+
+func NewListCommand() *cobra.Command {
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the backups in the backup history file",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer DoTeardown()
+			DoSetup(cmd)
+			DoList()
+		},
+	}
+	listCmd.Flags().String(utils.DBNAME, "", "Only list backups of the specified database")
+	listCmd.Flags().String(BACKUP_TYPE, "", "Only list backups of the specified type, either full or incremental")
+	listCmd.Flags().String(PLUGIN, "", "Only list backups taken with the specified plugin executable")
+	listCmd.Flags().String(AFTER, "", "Only list backups taken after the specified time, in the format YYYYMMDDHHMMSS")
+	listCmd.Flags().String(utils.BEFORE, "", "Only list backups taken before the specified time, in the format YYYYMMDDHHMMSS")
+	listCmd.Flags().Bool(INCLUDE_DELETED, false, "Also list backups that have been deleted")
+	return listCmd
+}
+
+func NewDescribeCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "describe <timestamp>",
+		Short: "Print the configuration, restore plan, and report of a backup",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			defer DoTeardown()
+			DoSetup(cmd)
+			DoDescribe(args[0])
+		},
+	}
+}
+
+func NewDeleteCommand() *cobra.Command {
+	deleteCmd := &cobra.Command{
+		Use:   "delete <timestamp>",
+		Short: "Delete the files of a backup and mark it as deleted in the backup history file",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			defer DoTeardown()
+			DoSetup(cmd)
+			DoDelete(args[0])
+		},
+	}
+	deleteCmd.Flags().Bool(FORCE, false, "Delete the backup even if later incremental backups depend on it")
+	return deleteCmd
+}
+
+func GetHistoryFilePath() string {
+	masterFPInfo := backup_filepath.NewFilePathInfo(globalCluster, "", "", "")
+	return masterFPInfo.GetBackupHistoryFilePath()
+}
+
+func ReadHistory() *backup_history.History {
+	historyFilename := GetHistoryFilePath()
+	history, err := backup_history.NewHistory(historyFilename)
+	gplog.FatalOnError(err, fmt.Sprintf("Unable to read backup history file %s", historyFilename))
+	return history
+}
+
+func FindBackupConfig(history *backup_history.History, timestamp string) *backup_history.BackupConfig {
+	for i := range history.BackupConfigs {
+		if history.BackupConfigs[i].Timestamp == timestamp {
+			return &history.BackupConfigs[i]
+		}
+	}
+	return nil
+}
+
+/*
+ * Backups taken with --backup-dir record the directory in the history file,
+ * so it does not need to be passed to gpbackup_manager again.
+ */
+func GetFPInfoForBackup(config *backup_history.BackupConfig, timestamp string) backup_filepath.FilePathInfo {
+	if config != nil && config.BackupDir != "" && MustGetFlagString(utils.BACKUP_DIR) == "" {
+		err := cmdFlags.Set(utils.BACKUP_DIR, config.BackupDir)
+		gplog.FatalOnError(err)
+	}
+	return GetFPInfoForTimestamp(timestamp)
+}
+
+func GetBackupType(config backup_history.BackupConfig) string {
+	if config.Incremental {
+		return "incremental"
+	}
+	return "full"
+}
+
+func GetBackupSections(config backup_history.BackupConfig) string {
+	if config.DataOnly {
+		return "data-only"
+	} else if config.MetadataOnly {
+		return "metadata-only"
+	}
+	return "all"
+}
+
+func getBackupStatus(config backup_history.BackupConfig) string {
+	if config.Deleted {
+		return "deleted"
+	} else if !config.IsSuccessful() {
+		return config.Status
+	}
+	return "available"
+}
+
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return "-"
+	}
+	labelStrs := make([]string, 0)
+	for key, value := range labels {
+		labelStrs = append(labelStrs, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(labelStrs)
+	return strings.Join(labelStrs, ",")
+}
+
+func (filter ListFilter) Matches(config backup_history.BackupConfig) bool {
+	return (filter.IncludeDeleted || !config.Deleted) &&
+		(filter.DatabaseName == "" || utils.UnquoteIdent(config.DatabaseName) == filter.DatabaseName) &&
+		(filter.BackupType == "" || GetBackupType(config) == filter.BackupType) &&
+		(filter.Plugin == "" || config.Plugin == filter.Plugin || path.Base(config.Plugin) == filter.Plugin) &&
+		(filter.After == "" || config.Timestamp > filter.After) &&
+		(filter.Before == "" || config.Timestamp < filter.Before)
+}
+
+// Returns the backups matching the filter, most recent first
+func FilterHistory(history *backup_history.History, filter ListFilter) []backup_history.BackupConfig {
+	configs := make([]backup_history.BackupConfig, 0)
+	for _, config := range history.BackupConfigs {
+		if filter.Matches(config) {
+			configs = append(configs, config)
+		}
+	}
+	sort.Slice(configs, func(i, j int) bool {
+		return configs[i].Timestamp > configs[j].Timestamp
+	})
+	return configs
+}
+
+func validateTimestampFlag(flagName string) {
+	timestamp := MustGetFlagString(flagName)
+	if timestamp != "" && !backup_filepath.IsValidTimestamp(timestamp) {
+		gplog.Fatal(errors.Errorf("Timestamp %s is invalid.  Timestamps must be in the format YYYYMMDDHHMMSS.", timestamp), "")
+	}
+}
+
+func DoList() {
+	backupType := MustGetFlagString(BACKUP_TYPE)
+	if backupType != "" && backupType != "full" && backupType != "incremental" {
+		gplog.Fatal(errors.Errorf("Invalid backup type %s.  Valid types are full and incremental.", backupType), "")
+	}
+	validateTimestampFlag(AFTER)
+	validateTimestampFlag(utils.BEFORE)
+	filter := ListFilter{
+		DatabaseName:   MustGetFlagString(utils.DBNAME),
+		BackupType:     backupType,
+		Plugin:         MustGetFlagString(PLUGIN),
+		After:          MustGetFlagString(AFTER),
+		Before:         MustGetFlagString(utils.BEFORE),
+		IncludeDeleted: MustGetFlagBool(INCLUDE_DELETED),
+	}
+	WriteBackupList(os.Stdout, FilterHistory(ReadHistory(), filter))
+}
+
+func WriteBackupList(output io.Writer, configs []backup_history.BackupConfig) {
+	writer := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "TIMESTAMP\tDATABASE\tTYPE\tSECTIONS\tPLUGIN\tLABELS\tSTATUS")
+	for _, config := range configs {
+		plugin := "-"
+		if config.Plugin != "" {
+			plugin = path.Base(config.Plugin)
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", config.Timestamp, config.DatabaseName, GetBackupType(config),
+			GetBackupSections(config), plugin, formatLabels(config.Labels), getBackupStatus(config))
+	}
+	_ = writer.Flush()
+}
+
+func DoDescribe(timestamp string) {
+	historyConfig := FindBackupConfig(ReadHistory(), timestamp)
+	if historyConfig != nil && historyConfig.Deleted {
+		gplog.Fatal(errors.Errorf("Backup %s has been deleted", timestamp), "")
+	}
+	fpInfo := GetFPInfoForBackup(historyConfig, timestamp)
+	config := ReadBackupConfig(fpInfo)
+
+	reportFilename := fpInfo.GetBackupReportFilePath()
+	if pluginConfig != nil {
+		err := pluginConfig.RestoreFile(reportFilename)
+		if err != nil {
+			gplog.Verbose("Unable to recover report file: %v", err)
+		}
+	}
+	reportContents, err := operating.System.ReadFile(reportFilename)
+	if err != nil {
+		gplog.Warn("Unable to read report file %s", reportFilename)
+		reportContents = nil
+	}
+	err = WriteBackupDescription(os.Stdout, config, string(reportContents))
+	gplog.FatalOnError(err)
+}
+
+func WriteBackupDescription(output io.Writer, config *backup_history.BackupConfig, reportContents string) error {
+	configWithoutPlan := *config
+	configWithoutPlan.RestorePlan = nil
+	configContents, err := yaml.Marshal(configWithoutPlan)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(output, "Backup %s\n\nConfiguration:\n%s\nRestore Plan:\n", config.Timestamp, configContents)
+	if len(config.RestorePlan) == 0 {
+		fmt.Fprintln(output, "No restore plan")
+	}
+	for _, entry := range config.RestorePlan {
+		fmt.Fprintf(output, "%s: %d tables\n", entry.Timestamp, len(entry.TableFQNs))
+		for _, fqn := range entry.TableFQNs {
+			fmt.Fprintf(output, "\t%s\n", fqn)
+		}
+	}
+	if reportContents == "" {
+		reportContents = "No report file found\n"
+	}
+	fmt.Fprintf(output, "\nReport:\n%s", reportContents)
+	return nil
+}
+
+/*
+ * An incremental backup depends on every backup in its restore plan, so a
+ * backup cannot be deleted while a later backup lists it in its restore plan.
+ */
+func GetDependentBackups(history *backup_history.History, timestamp string) []string {
+	dependents := make([]string, 0)
+	for _, config := range history.BackupConfigs {
+		if config.Deleted || config.Timestamp == timestamp {
+			continue
+		}
+		for _, entry := range config.RestorePlan {
+			if entry.Timestamp == timestamp {
+				dependents = append(dependents, config.Timestamp)
+				break
+			}
+		}
+	}
+	sort.Strings(dependents)
+	return dependents
+}
+
+func DoDelete(timestamp string) {
+	history := ReadHistory()
+	config := FindBackupConfig(history, timestamp)
+	if config == nil {
+		gplog.Fatal(errors.Errorf("Backup %s was not found in backup history file %s", timestamp, GetHistoryFilePath()), "")
+	}
+	if config.Deleted {
+		gplog.Fatal(errors.Errorf("Backup %s has already been deleted", timestamp), "")
+	}
+	if dependents := GetDependentBackups(history, timestamp); len(dependents) > 0 {
+		if !MustGetFlagBool(FORCE) {
+			gplog.Fatal(errors.Errorf("Backup %s cannot be deleted, as the following incremental backups depend on it: %s.  Use --force to delete it anyway.",
+				timestamp, strings.Join(dependents, ", ")), "")
+		}
+		gplog.Warn("Deleting backup %s, on which the following incremental backups depend: %s", timestamp, strings.Join(dependents, ", "))
+	}
+
+	if config.Plugin != "" {
+		if pluginConfig == nil {
+			gplog.Fatal(errors.Errorf("Backup %s was taken with plugin %s, so --plugin-config must be specified to delete it", timestamp, config.Plugin), "")
+		}
+		DeleteBackupUsingPlugin(timestamp)
+	} else {
+		deleteCluster := NewClusterFromDatabase()
+		segPrefix := backup_filepath.ParseSegPrefix(config.BackupDir, timestamp)
+		fpInfo := backup_filepath.NewFilePathInfo(deleteCluster, config.BackupDir, timestamp, segPrefix)
+		err := utils.DeleteBackupDirectories(deleteCluster, fpInfo)
+		gplog.FatalOnError(err)
+	}
+
+	MarkBackupDeleted(timestamp, GetHistoryFilePath())
+	gplog.Info("Backup %s successfully deleted", timestamp)
+}
+
+/*
+ * Segment data directories are only recorded in the database, so deleting
+ * a backup that is not stored with a plugin requires a database connection.
+ */
+func NewClusterFromDatabase() *cluster.Cluster {
+	connectionPool := dbconn.NewDBConnFromEnvironment("postgres")
+	connectionPool.MustConnect(1)
+	defer connectionPool.Close()
+	return cluster.NewCluster(cluster.MustGetSegmentConfiguration(connectionPool))
+}
+
+func DeleteBackupUsingPlugin(timestamp string) {
+	err := pluginConfig.DeleteBackup(globalCluster, timestamp)
+	gplog.FatalOnError(err)
+}
+
+func MarkBackupDeleted(timestamp string, historyFilename string) {
+	err := backup_history.UpdateHistoryFile(historyFilename, func(history *backup_history.History) {
+		if config := FindBackupConfig(history, timestamp); config != nil {
+			config.Deleted = true
+		}
+	})
+	gplog.FatalOnError(err, fmt.Sprintf("Unable to update backup history file %s", historyFilename))
+}
@@ -0,0 +1,175 @@
+package manager_test
+
+import (
+	"os"
+
+	"github.com/greenplum-db/gp-common-go-libs/cluster"
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/greenplum-db/gpbackup/backup_history"
+	"github.com/greenplum-db/gpbackup/manager"
+	"github.com/greenplum-db/gpbackup/utils"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/pkg/errors"
+)
+
+var _ = Describe("manager/history tests", func() {
+	var history *backup_history.History
+	BeforeEach(func() {
+		history = &backup_history.History{BackupConfigs: []backup_history.BackupConfig{
+			{DatabaseName: "testdb", Timestamp: "20170101010101", LeafPartitionData: true,
+				RestorePlan: []backup_history.RestorePlanEntry{{Timestamp: "20170101010101", TableFQNs: []string{"public.foo"}}}},
+			{DatabaseName: "testdb", Timestamp: "20170102010101", Incremental: true, LeafPartitionData: true,
+				RestorePlan: []backup_history.RestorePlanEntry{{Timestamp: "20170101010101", TableFQNs: []string{"public.foo"}}, {Timestamp: "20170102010101", TableFQNs: []string{"public.bar"}}}},
+			{DatabaseName: `"Test DB"`, Timestamp: "20170103010101", Plugin: "/usr/local/bin/s3_plugin", MetadataOnly: true, Labels: map[string]string{"release": "pre-5.1"}},
+			{DatabaseName: "testdb", Timestamp: "20170104010101", Deleted: true},
+		}}
+	})
+	Describe("FilterHistory", func() {
+		It("returns backups that have not been deleted, most recent first", func() {
+			configs := manager.FilterHistory(history, manager.ListFilter{})
+
+			Expect(configs).To(HaveLen(3))
+			Expect(configs[0].Timestamp).To(Equal("20170103010101"))
+			Expect(configs[2].Timestamp).To(Equal("20170101010101"))
+		})
+		It("includes deleted backups if requested", func() {
+			configs := manager.FilterHistory(history, manager.ListFilter{IncludeDeleted: true})
+
+			Expect(configs).To(HaveLen(4))
+			Expect(configs[0].Timestamp).To(Equal("20170104010101"))
+		})
+		It("filters by database, type, plugin, and time range", func() {
+			Expect(manager.FilterHistory(history, manager.ListFilter{DatabaseName: "Test DB"})).To(HaveLen(1))
+			Expect(manager.FilterHistory(history, manager.ListFilter{BackupType: "incremental"})).To(HaveLen(1))
+			Expect(manager.FilterHistory(history, manager.ListFilter{BackupType: "full", DatabaseName: "testdb"})).To(HaveLen(1))
+			Expect(manager.FilterHistory(history, manager.ListFilter{Plugin: "s3_plugin"})).To(HaveLen(1))
+			Expect(manager.FilterHistory(history, manager.ListFilter{Plugin: "/usr/local/bin/s3_plugin"})).To(HaveLen(1))
+
+			configs := manager.FilterHistory(history, manager.ListFilter{After: "20170101010101", Before: "20170103010101"})
+
+			Expect(configs).To(HaveLen(1))
+			Expect(configs[0].Timestamp).To(Equal("20170102010101"))
+		})
+	})
+	Describe("WriteBackupList", func() {
+		It("writes one row per backup", func() {
+			buffer := gbytes.NewBuffer()
+
+			manager.WriteBackupList(buffer, manager.FilterHistory(history, manager.ListFilter{IncludeDeleted: true}))
+
+			Expect(string(buffer.Contents())).To(Equal(`TIMESTAMP       DATABASE   TYPE         SECTIONS       PLUGIN     LABELS           STATUS
+20170104010101  testdb     full         all            -          -                deleted
+20170103010101  "Test DB"  full         metadata-only  s3_plugin  release=pre-5.1  available
+20170102010101  testdb     incremental  all            -          -                available
+20170101010101  testdb     full         all            -          -                available
+`))
+		})
+		It("shows the status of backups that did not complete successfully", func() {
+			buffer := gbytes.NewBuffer()
+			configs := []backup_history.BackupConfig{
+				{DatabaseName: "testdb", Timestamp: "20170106010101", Status: backup_history.BackupStatusInProgress},
+				{DatabaseName: "testdb", Timestamp: "20170105010101", Status: backup_history.BackupStatusFailed},
+			}
+
+			manager.WriteBackupList(buffer, configs)
+
+			Expect(string(buffer.Contents())).To(Equal(`TIMESTAMP       DATABASE  TYPE  SECTIONS  PLUGIN  LABELS  STATUS
+20170106010101  testdb    full  all       -       -       in_progress
+20170105010101  testdb    full  all       -       -       failed
+`))
+		})
+	})
+	Describe("WriteBackupDescription", func() {
+		It("writes the configuration, restore plan, and report", func() {
+			buffer := gbytes.NewBuffer()
+
+			err := manager.WriteBackupDescription(buffer, &history.BackupConfigs[1], "Greenplum Database Backup Report\n")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(buffer).To(gbytes.Say(`Backup 20170102010101
+
+Configuration:
+`))
+			Expect(buffer).To(gbytes.Say("incremental: true\n"))
+			Expect(buffer).To(gbytes.Say(`withstatistics: false
+
+Restore Plan:
+20170101010101: 1 tables
+	public.foo
+20170102010101: 1 tables
+	public.bar
+
+Report:
+Greenplum Database Backup Report
+`))
+		})
+		It("notes a missing report file", func() {
+			buffer := gbytes.NewBuffer()
+
+			err := manager.WriteBackupDescription(buffer, &history.BackupConfigs[2], "")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(buffer).To(gbytes.Say("Restore Plan:\nNo restore plan\n\nReport:\nNo report file found\n"))
+		})
+	})
+	Describe("GetDependentBackups", func() {
+		It("returns the backups whose restore plans include the backup", func() {
+			Expect(manager.GetDependentBackups(history, "20170101010101")).To(Equal([]string{"20170102010101"}))
+		})
+		It("returns no backups for the latest backup in an incremental chain", func() {
+			Expect(manager.GetDependentBackups(history, "20170102010101")).To(BeEmpty())
+		})
+		It("ignores dependent backups that have been deleted", func() {
+			history.BackupConfigs[1].Deleted = true
+
+			Expect(manager.GetDependentBackups(history, "20170101010101")).To(BeEmpty())
+		})
+	})
+	Describe("DeleteBackupUsingPlugin", func() {
+		var testExecutor *testhelper.TestExecutor
+		BeforeEach(func() {
+			testExecutor = &testhelper.TestExecutor{}
+			testCluster := cluster.NewCluster([]cluster.SegConfig{{ContentID: -1, Hostname: "localhost", DataDir: "/data/gpseg-1"}})
+			testCluster.Executor = testExecutor
+			manager.SetCluster(testCluster)
+			manager.SetPluginConfig(&utils.PluginConfig{ExecutablePath: "/usr/local/bin/s3_plugin", ConfigPath: "/tmp/s3_config.yaml"})
+		})
+		AfterEach(func() {
+			manager.SetPluginConfig(nil)
+		})
+		It("calls the plugin's delete_backup command", func() {
+			manager.DeleteBackupUsingPlugin("20170101010101")
+
+			Expect(testExecutor.LocalCommands).To(Equal([]string{"/usr/local/bin/s3_plugin delete_backup /tmp/s3_config.yaml 20170101010101"}))
+		})
+		It("panics if the plugin fails", func() {
+			testExecutor.LocalError = errors.New("exit status 1")
+			testExecutor.LocalOutput = "bucket not found"
+
+			defer testhelper.ShouldPanicWithMessage("Plugin failed to delete backup 20170101010101. bucket not found")
+			manager.DeleteBackupUsingPlugin("20170101010101")
+		})
+	})
+	Describe("MarkBackupDeleted", func() {
+		historyFilename := "/tmp/gpbackup_manager_history_test.yaml"
+		AfterEach(func() {
+			_ = os.Remove(historyFilename)
+			_ = os.Remove(historyFilename + ".bak")
+			operating.System = operating.InitializeSystemFunctions()
+		})
+		It("rewrites the history file with the backup marked as deleted", func() {
+			err := history.WriteToFileAndMakeReadOnly(historyFilename)
+			Expect(err).ToNot(HaveOccurred())
+
+			manager.MarkBackupDeleted("20170101010101", historyFilename)
+
+			resultHistory, err := backup_history.NewHistory(historyFilename)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resultHistory.BackupConfigs[0].Deleted).To(BeTrue())
+			Expect(resultHistory.BackupConfigs[1].Deleted).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,152 @@
+package manager
+
+/*
+ * gpbackup_manager works with backups that already exist, reading the files
+ * gpbackup leaves in the master backup directory or in plugin storage rather
+ * than connecting to the database.
+ */
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+
+	"github.com/greenplum-db/gp-common-go-libs/cluster"
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/greenplum-db/gpbackup/backup_filepath"
+	"github.com/greenplum-db/gpbackup/backup_history"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func SetFlagDefaults(flagSet *pflag.FlagSet) {
+	flagSet.String(utils.BACKUP_DIR, "", "The absolute path of the directory in which the backup files are located")
+	flagSet.Bool(utils.DEBUG, false, "Print verbose and debug log messages")
+	flagSet.String(utils.PLUGIN_CONFIG, "", "The configuration file to use for a plugin")
+	flagSet.Bool(utils.QUIET, false, "Suppress non-warning, non-error log messages")
+	flagSet.Bool(utils.VERBOSE, false, "Print verbose log messages")
+}
+
+// This function handles setup that can be done before parsing flags.
+func DoInit(cmd *cobra.Command) {
+	gplog.InitializeLogging("gpbackup_manager", "")
+	SetFlagDefaults(cmd.PersistentFlags())
+	cmd.AddCommand(NewCompareCommand())
+	cmd.AddCommand(NewListCommand())
+	cmd.AddCommand(NewDescribeCommand())
+	cmd.AddCommand(NewDeleteCommand())
+	cmd.AddCommand(NewRebuildHistoryCommand())
+	cmd.AddCommand(NewFindTableCommand())
+}
+
+// This function handles setup that must be done after parsing flags.
+func DoSetup(cmd *cobra.Command) {
+	SetCmdFlags(cmd.Flags())
+	utils.CheckExclusiveFlags(cmdFlags, utils.DEBUG, utils.QUIET, utils.VERBOSE)
+	utils.CheckExclusiveFlags(cmdFlags, utils.PLUGIN_CONFIG, utils.BACKUP_DIR)
+	SetLoggerVerbosity()
+	err := utils.ValidateFullPath(MustGetFlagString(utils.BACKUP_DIR))
+	gplog.FatalOnError(err)
+	err = utils.ValidateFullPath(MustGetFlagString(utils.PLUGIN_CONFIG))
+	gplog.FatalOnError(err)
+
+	globalCluster = NewMasterCluster()
+	if MustGetFlagString(utils.PLUGIN_CONFIG) != "" {
+		InitializePluginConfig(MustGetFlagString(utils.PLUGIN_CONFIG))
+	}
+}
+
+func SetLoggerVerbosity() {
+	if MustGetFlagBool(utils.QUIET) {
+		gplog.SetVerbosity(gplog.LOGERROR)
+	} else if MustGetFlagBool(utils.DEBUG) {
+		gplog.SetVerbosity(gplog.LOGDEBUG)
+	} else if MustGetFlagBool(utils.VERBOSE) {
+		gplog.SetVerbosity(gplog.LOGVERBOSE)
+	}
+}
+
+/*
+ * Backup files on the master are located relative to the master data directory
+ * unless --backup-dir is used, so a cluster containing only the master is
+ * enough to construct their paths without a database connection.
+ */
+func NewMasterCluster() *cluster.Cluster {
+	masterDataDir := operating.System.Getenv("MASTER_DATA_DIRECTORY")
+	if masterDataDir == "" {
+		gplog.Fatal(errors.Errorf("The MASTER_DATA_DIRECTORY environment variable must be set."), "")
+	}
+	hostname, _ := operating.System.Hostname()
+	return cluster.NewCluster([]cluster.SegConfig{{ContentID: -1, Hostname: hostname, DataDir: masterDataDir}})
+}
+
+/*
+ * Plugins expect their configuration file in /tmp on every host on which they
+ * run, and gpbackup_manager only ever runs the plugin on the master.
+ */
+func InitializePluginConfig(configFile string) {
+	var err error
+	pluginConfig, err = utils.ReadPluginConfig(configFile)
+	gplog.FatalOnError(err)
+	if pluginConfig.ConfigPath != configFile {
+		_, err = globalCluster.ExecuteLocalCommand(fmt.Sprintf("cp %s %s", configFile, pluginConfig.ConfigPath))
+		gplog.FatalOnError(err, fmt.Sprintf("Unable to copy plugin config to %s", pluginConfig.ConfigPath))
+	}
+}
+
+func GetFPInfoForTimestamp(timestamp string) backup_filepath.FilePathInfo {
+	if !backup_filepath.IsValidTimestamp(timestamp) {
+		gplog.Fatal(errors.Errorf("Timestamp %s is invalid.  Timestamps must be in the format YYYYMMDDHHMMSS.", timestamp), "")
+	}
+	segPrefix := backup_filepath.ParseSegPrefix(MustGetFlagString(utils.BACKUP_DIR), timestamp)
+	return backup_filepath.NewFilePathInfo(globalCluster, MustGetFlagString(utils.BACKUP_DIR), timestamp, segPrefix)
+}
+
+/*
+ * When a plugin is in use, the requested master files are first retrieved
+ * from plugin storage into the local backup directory.
+ */
+func RecoverFilesUsingPlugin(filenames ...string) {
+	if pluginConfig == nil {
+		return
+	}
+	for _, filename := range filenames {
+		pluginConfig.MustRestoreFile(filename)
+	}
+}
+
+func ReadBackupConfig(fpInfo backup_filepath.FilePathInfo) *backup_history.BackupConfig {
+	RecoverFilesUsingPlugin(fpInfo.GetConfigFilePath())
+	return backup_history.ReadConfigFile(fpInfo.GetConfigFilePath())
+}
+
+func ReadBackupTOC(fpInfo backup_filepath.FilePathInfo) *utils.TOC {
+	RecoverFilesUsingPlugin(fpInfo.GetTOCFilePath())
+	toc := utils.NewTOC(fpInfo.GetTOCFilePath())
+	toc.InitializeMetadataEntryMap()
+	return toc
+}
+
+func DoTeardown() {
+	errStr := ""
+	if err := recover(); err != nil {
+		// Check if gplog.Fatal did not cause the panic
+		if gplog.GetErrorCode() != 2 {
+			gplog.Error(fmt.Sprintf("%v: %s", err, debug.Stack()))
+			gplog.SetErrorCode(2)
+		} else {
+			errStr = fmt.Sprintf("%v", err)
+		}
+	}
+	if errStr != "" {
+		fmt.Println(errStr)
+	}
+	os.Exit(gplog.GetErrorCode())
+}
+
+func GetVersion() string {
+	return version
+}
@@ -0,0 +1,288 @@
+package manager
+
+/*
+ * This file contains structs and functions related to rebuilding the backup
+ * history file from the backups found in the backup directories or in plugin
+ * storage.
+ */
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+
+	"github.com/greenplum-db/gp-common-go-libs/cluster"
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gp-common-go-libs/iohelper"
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/greenplum-db/gpbackup/backup_filepath"
+	"github.com/greenplum-db/gpbackup/backup_history"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+const DRY_RUN = "dry-run"
+
+var configFilenameRegex = regexp.MustCompile(`^gpbackup_([0-9]{14})_config\.yaml$`)
+
+/*
+ * A backup is incomplete if any of the files needed to restore it are
+ * missing, which is usually because the backup failed or was interrupted.
+ */
+type FoundBackup struct {
+	Config   *backup_history.BackupConfig
+	Problems []string
+}
+
+func (backup *FoundBackup) IsComplete() bool {
+	return len(backup.Problems) == 0
+}
+
+/*
+ * Backups taken with --metadata-directory have a metadata directory on the
+ * master instead of a single metadata file.
+ */
+func getMasterFilesToCheck(config *backup_history.BackupConfig, fpInfo backup_filepath.FilePathInfo) []string {
+	filenames := []string{fpInfo.GetTOCFilePath(), fpInfo.GetBackupReportFilePath()}
+	if config.MetadataDirectory {
+		return filenames
+	}
+	return append(filenames, fpInfo.GetMetadataFilePath())
+}
+
+func NewRebuildHistoryCommand() *cobra.Command {
+	rebuildCmd := &cobra.Command{
+		Use:   "rebuild-history",
+		Short: "Add the backups found in the backup directories or plugin storage to the backup history file",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer DoTeardown()
+			DoSetup(cmd)
+			DoRebuildHistory()
+		},
+	}
+	rebuildCmd.Flags().Bool(DRY_RUN, false, "Print the backups that would be added to the backup history file without changing it")
+	return rebuildCmd
+}
+
+func DoRebuildHistory() {
+	var foundBackups []FoundBackup
+	if pluginConfig != nil {
+		foundBackups = FindBackupsUsingPlugin()
+	} else {
+		foundBackups = FindBackupsInBackupDirectories()
+	}
+
+	historyFilename := GetHistoryFilePath()
+	existingHistory := &backup_history.History{}
+	if iohelper.FileExistsAndIsReadable(historyFilename) {
+		existingHistory = ReadHistory()
+	}
+	newConfigs := GetConfigsToAdd(existingHistory, foundBackups)
+	WriteRebuildSummary(os.Stdout, foundBackups, newConfigs)
+	if MustGetFlagBool(DRY_RUN) || len(newConfigs) == 0 {
+		return
+	}
+
+	err := backup_history.UpdateHistoryFile(historyFilename, func(history *backup_history.History) {
+		for _, config := range GetConfigsToAdd(history, foundBackups) {
+			history.AddBackupConfig(config)
+		}
+	})
+	gplog.FatalOnError(err, fmt.Sprintf("Unable to update backup history file %s", historyFilename))
+	gplog.Info("Added %d backups to backup history file %s", len(newConfigs), historyFilename)
+}
+
+/*
+ * Returns the complete backups that are not already in the history, oldest
+ * first.  Backups already in the history are left as they are, so that
+ * entries of deleted backups keep their deleted status.
+ */
+func GetConfigsToAdd(history *backup_history.History, foundBackups []FoundBackup) []*backup_history.BackupConfig {
+	configs := make([]*backup_history.BackupConfig, 0)
+	seen := make(map[string]bool, 0)
+	for _, config := range history.BackupConfigs {
+		seen[config.Timestamp] = true
+	}
+	for _, backup := range foundBackups {
+		if !backup.IsComplete() || seen[backup.Config.Timestamp] {
+			continue
+		}
+		seen[backup.Config.Timestamp] = true
+		configs = append(configs, backup.Config)
+	}
+	sort.Slice(configs, func(i, j int) bool {
+		return configs[i].Timestamp < configs[j].Timestamp
+	})
+	return configs
+}
+
+func WriteRebuildSummary(output io.Writer, foundBackups []FoundBackup, newConfigs []*backup_history.BackupConfig) {
+	fmt.Fprintf(output, "Found %d backups\n", len(foundBackups))
+	if len(newConfigs) > 0 {
+		fmt.Fprintln(output, "\nBackups to add to the backup history file:")
+		for _, config := range newConfigs {
+			fmt.Fprintf(output, "\t%s %s\n", config.Timestamp, config.DatabaseName)
+		}
+	} else {
+		fmt.Fprintln(output, "\nAll complete backups are already in the backup history file")
+	}
+	for _, backup := range foundBackups {
+		if backup.IsComplete() {
+			continue
+		}
+		fmt.Fprintf(output, "\nIncomplete backup %s will not be added:\n", backup.Config.Timestamp)
+		for _, problem := range backup.Problems {
+			fmt.Fprintf(output, "\t%s\n", problem)
+		}
+	}
+}
+
+/*
+ * Config files are only written to the master backup directory, so the
+ * backups are found there and the segment backup directories are only
+ * checked for their data files.
+ */
+func FindBackupsInBackupDirectories() []FoundBackup {
+	backupDir := MustGetFlagString(utils.BACKUP_DIR)
+	configFilenames, err := FindConfigFiles(globalCluster.GetDirForContent(-1), backupDir)
+	gplog.FatalOnError(err)
+	if len(configFilenames) == 0 {
+		return []FoundBackup{}
+	}
+
+	segmentCluster := NewClusterFromDatabase()
+	foundBackups := make([]FoundBackup, 0)
+	fpInfos := make(map[string]backup_filepath.FilePathInfo, 0)
+	for _, configFilename := range configFilenames {
+		timestamp := configFilenameRegex.FindStringSubmatch(path.Base(configFilename))[1]
+		segPrefix := backup_filepath.ParseSegPrefix(backupDir, timestamp)
+		fpInfo := backup_filepath.NewFilePathInfo(segmentCluster, backupDir, timestamp, segPrefix)
+		config, err := readFoundConfigFile(configFilename, timestamp)
+		if err != nil {
+			gplog.Warn("%v", err)
+			continue
+		}
+		backup := FoundBackup{Config: config, Problems: make([]string, 0)}
+		for _, filename := range getMasterFilesToCheck(config, fpInfo) {
+			if !iohelper.FileExistsAndIsReadable(filename) {
+				backup.Problems = append(backup.Problems, fmt.Sprintf("Missing %s", filename))
+			}
+		}
+		if config.MetadataDirectory && !utils.MetadataDirectoryExists(fpInfo.GetMetadataDirectoryPath()) {
+			backup.Problems = append(backup.Problems, fmt.Sprintf("Missing %s", fpInfo.GetMetadataDirectoryPath()))
+		}
+		fpInfos[timestamp] = fpInfo
+		foundBackups = append(foundBackups, backup)
+	}
+	CheckSegmentDataFiles(segmentCluster, foundBackups, fpInfos)
+	return foundBackups
+}
+
+func FindConfigFiles(masterDataDir string, backupDir string) ([]string, error) {
+	pattern := path.Join(masterDataDir, "backups", "*", "*", "gpbackup_*_config.yaml")
+	if backupDir != "" {
+		pattern = path.Join(backupDir, "*-1", "backups", "*", "*", "gpbackup_*_config.yaml")
+	}
+	matches, err := operating.System.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	configFilenames := make([]string, 0)
+	for _, match := range matches {
+		if configFilenameRegex.MatchString(path.Base(match)) {
+			configFilenames = append(configFilenames, match)
+		}
+	}
+	sort.Strings(configFilenames)
+	return configFilenames, nil
+}
+
+func readFoundConfigFile(configFilename string, timestamp string) (*backup_history.BackupConfig, error) {
+	contents, err := operating.System.ReadFile(configFilename)
+	if err != nil {
+		return nil, errors.Errorf("Unable to read config file %s: %v", configFilename, err)
+	}
+	config := &backup_history.BackupConfig{}
+	err = yaml.Unmarshal(contents, config)
+	if err != nil || config.Timestamp != timestamp {
+		return nil, errors.Errorf("Config file %s is not a valid config file for backup %s", configFilename, timestamp)
+	}
+	return config, nil
+}
+
+/*
+ * Every segment counts the data files of all backups in a single command, and
+ * each count is compared to the number of data files the backup should have,
+ * as when validating an incremental backup chain before a restore.  Backups
+ * without a table of contents file cannot be checked, and metadata-only
+ * backups have no segment files to check.
+ */
+func CheckSegmentDataFiles(c *cluster.Cluster, foundBackups []FoundBackup, fpInfos map[string]backup_filepath.FilePathInfo) {
+	backupsWithData := make([]*FoundBackup, 0)
+	fpInfosToCount := make([]backup_filepath.FilePathInfo, 0)
+	expectedCounts := make([]int, 0)
+	for i := range foundBackups {
+		config := foundBackups[i].Config
+		fpInfo := fpInfos[config.Timestamp]
+		tocFilename := fpInfo.GetTOCFilePath()
+		if config.MetadataOnly || !iohelper.FileExistsAndIsReadable(tocFilename) {
+			continue
+		}
+		backupsWithData = append(backupsWithData, &foundBackups[i])
+		fpInfosToCount = append(fpInfosToCount, fpInfo)
+		expectedCounts = append(expectedCounts, utils.GetExpectedDataFileCount(config, tocFilename))
+	}
+	if len(backupsWithData) == 0 {
+		return
+	}
+	counts := utils.CountDataFilesOnSegments(c, fpInfosToCount)
+	for i, backup := range backupsWithData {
+		for _, contentID := range c.ContentIDs {
+			if contentID == -1 {
+				continue
+			}
+			if numFound := counts[contentID][i]; numFound != expectedCounts[i] {
+				backup.Problems = append(backup.Problems, fmt.Sprintf("Found %d data files in %s on segment %d, but expected %d",
+					numFound, fpInfosToCount[i].GetDirForContent(contentID), contentID, expectedCounts[i]))
+			}
+		}
+	}
+}
+
+/*
+ * Plugin storage is listed with the plugin's list_backups command, and the
+ * master files of each backup are retrieved to check that they exist.
+ */
+func FindBackupsUsingPlugin() []FoundBackup {
+	timestamps, err := pluginConfig.ListBackups(globalCluster)
+	gplog.FatalOnError(err)
+
+	foundBackups := make([]FoundBackup, 0)
+	for _, timestamp := range timestamps {
+		fpInfo := backup_filepath.NewFilePathInfo(globalCluster, "", timestamp, "")
+		err = pluginConfig.RestoreFile(fpInfo.GetConfigFilePath())
+		if err != nil {
+			gplog.Warn("Unable to recover config file of backup %s: %v", timestamp, err)
+			continue
+		}
+		config, err := readFoundConfigFile(fpInfo.GetConfigFilePath(), timestamp)
+		if err != nil {
+			gplog.Warn("%v", err)
+			continue
+		}
+		backup := FoundBackup{Config: config, Problems: make([]string, 0)}
+		for _, filename := range getMasterFilesToCheck(config, fpInfo) {
+			if err := pluginConfig.RestoreFile(filename); err != nil {
+				backup.Problems = append(backup.Problems, fmt.Sprintf("Missing %s", filename))
+			}
+		}
+		foundBackups = append(foundBackups, backup)
+	}
+	return foundBackups
+}
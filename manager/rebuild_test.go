@@ -0,0 +1,136 @@
+package manager_test
+
+import (
+	"os"
+
+	"github.com/greenplum-db/gp-common-go-libs/cluster"
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/greenplum-db/gpbackup/backup_filepath"
+	"github.com/greenplum-db/gpbackup/backup_history"
+	"github.com/greenplum-db/gpbackup/manager"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("manager/rebuild tests", func() {
+	var foundBackups []manager.FoundBackup
+	BeforeEach(func() {
+		foundBackups = []manager.FoundBackup{
+			{Config: &backup_history.BackupConfig{DatabaseName: "testdb", Timestamp: "20170103010101"}, Problems: []string{}},
+			{Config: &backup_history.BackupConfig{DatabaseName: "testdb", Timestamp: "20170101010101"}, Problems: []string{}},
+			{Config: &backup_history.BackupConfig{DatabaseName: "testdb", Timestamp: "20170102010101"},
+				Problems: []string{"Missing /data/gpseg-1/backups/20170102/20170102010101/gpbackup_20170102010101_toc.yaml"}},
+		}
+	})
+	Describe("GetConfigsToAdd", func() {
+		It("returns the complete backups that are not in the history, oldest first", func() {
+			history := &backup_history.History{BackupConfigs: []backup_history.BackupConfig{{Timestamp: "20170103010101", Deleted: true}}}
+			foundBackups = append(foundBackups, manager.FoundBackup{Config: &backup_history.BackupConfig{Timestamp: "20170101010101"}})
+
+			configs := manager.GetConfigsToAdd(history, foundBackups)
+
+			Expect(configs).To(HaveLen(1))
+			Expect(configs[0].Timestamp).To(Equal("20170101010101"))
+		})
+	})
+	Describe("WriteRebuildSummary", func() {
+		It("lists the backups to add and the incomplete backups", func() {
+			buffer := gbytes.NewBuffer()
+
+			manager.WriteRebuildSummary(buffer, foundBackups, manager.GetConfigsToAdd(&backup_history.History{}, foundBackups))
+
+			Expect(string(buffer.Contents())).To(Equal(`Found 3 backups
+
+Backups to add to the backup history file:
+	20170101010101 testdb
+	20170103010101 testdb
+
+Incomplete backup 20170102010101 will not be added:
+	Missing /data/gpseg-1/backups/20170102/20170102010101/gpbackup_20170102010101_toc.yaml
+`))
+		})
+	})
+	Describe("FindConfigFiles", func() {
+		var globPattern string
+		BeforeEach(func() {
+			operating.System.Glob = func(pattern string) ([]string, error) {
+				globPattern = pattern
+				return []string{
+					"/data/gpseg-1/backups/20170102/20170102010101/gpbackup_20170102010101_config.yaml",
+					"/data/gpseg-1/backups/20170101/20170101010101/gpbackup_20170101010101_config.yaml",
+					"/data/gpseg-1/backups/20170101/20170101010101/gpbackup_old_config.yaml",
+				}, nil
+			}
+		})
+		AfterEach(func() {
+			operating.System = operating.InitializeSystemFunctions()
+		})
+		It("finds the config files in the master data directory", func() {
+			configFilenames, err := manager.FindConfigFiles("/data/gpseg-1", "")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(globPattern).To(Equal("/data/gpseg-1/backups/*/*/gpbackup_*_config.yaml"))
+			Expect(configFilenames).To(Equal([]string{
+				"/data/gpseg-1/backups/20170101/20170101010101/gpbackup_20170101010101_config.yaml",
+				"/data/gpseg-1/backups/20170102/20170102010101/gpbackup_20170102010101_config.yaml",
+			}))
+		})
+		It("finds the config files in the master directory under the backup directory", func() {
+			_, err := manager.FindConfigFiles("/data/gpseg-1", "/backups")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(globPattern).To(Equal("/backups/*-1/backups/*/*/gpbackup_*_config.yaml"))
+		})
+	})
+	Describe("CheckSegmentDataFiles", func() {
+		var testExecutor *testhelper.TestExecutor
+		var testCluster *cluster.Cluster
+		var fpInfos map[string]backup_filepath.FilePathInfo
+		BeforeEach(func() {
+			testExecutor = &testhelper.TestExecutor{ClusterOutput: &cluster.RemoteOutput{Stdouts: map[int]string{0: "2\n2\n", 1: "2\n1\n"}}}
+			testCluster = cluster.NewCluster([]cluster.SegConfig{
+				{ContentID: -1, Hostname: "localhost", DataDir: "/data/gpseg-1"},
+				{ContentID: 0, Hostname: "remotehost1", DataDir: "/data/gpseg0"},
+				{ContentID: 1, Hostname: "remotehost2", DataDir: "/data/gpseg1"},
+			})
+			testCluster.Executor = testExecutor
+			fpInfos = make(map[string]backup_filepath.FilePathInfo, 0)
+			for _, backup := range foundBackups {
+				fpInfos[backup.Config.Timestamp] = backup_filepath.NewFilePathInfo(testCluster, "", backup.Config.Timestamp, "")
+			}
+			operating.System.Stat = func(string) (os.FileInfo, error) { return nil, nil }
+			operating.System.OpenFileRead = func(string, int, os.FileMode) (operating.ReadCloserAt, error) { return nil, nil }
+			operating.System.ReadFile = func(string) ([]byte, error) {
+				return []byte("dataentries:\n- schema: public\n  name: foo\n- schema: public\n  name: bar\n"), nil
+			}
+		})
+		AfterEach(func() {
+			operating.System = operating.InitializeSystemFunctions()
+		})
+		It("records the segments that do not have one data file for each table", func() {
+			foundBackups[1].Config.MetadataOnly = true
+
+			manager.CheckSegmentDataFiles(testCluster, foundBackups, fpInfos)
+
+			Expect(testExecutor.ClusterCommands[0][0]).To(ContainElement("find /data/gpseg0/backups/20170103/20170103010101 -type f 2>/dev/null | wc -l; " +
+				"find /data/gpseg0/backups/20170102/20170102010101 -type f 2>/dev/null | wc -l"))
+			Expect(foundBackups[0].Problems).To(BeEmpty())
+			Expect(foundBackups[2].Problems).To(Equal([]string{
+				"Missing /data/gpseg-1/backups/20170102/20170102010101/gpbackup_20170102010101_toc.yaml",
+				"Found 1 data files in /data/gpseg1/backups/20170102/20170102010101 on segment 1, but expected 2",
+			}))
+		})
+		It("expects a data file and a table of contents file for a single data file backup", func() {
+			foundBackups = foundBackups[:1]
+			foundBackups[0].Config.SingleDataFile = true
+			testExecutor.ClusterOutput.Stdouts = map[int]string{0: "2\n", 1: "1\n"}
+
+			manager.CheckSegmentDataFiles(testCluster, foundBackups, fpInfos)
+
+			Expect(foundBackups[0].Problems).To(Equal([]string{"Found 1 data files in /data/gpseg1/backups/20170103/20170103010101 on segment 1, but expected 2"}))
+		})
+	})
+
+})
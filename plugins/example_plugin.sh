@@ -81,6 +81,11 @@ delete_backup() {
 
 }
 
+list_backups() {
+  echo "list_backups $1" >> /tmp/plugin_out.txt
+  ls /tmp/plugin_dest
+}
+
 plugin_api_version(){
   echo "0.4.0"
   echo "0.4.0" >> /tmp/plugin_out.txt
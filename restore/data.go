@@ -6,11 +6,13 @@ package restore
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 	"sync/atomic"
 
 	"github.com/greenplum-db/gp-common-go-libs/dbconn"
 	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gp-common-go-libs/operating"
 	"github.com/greenplum-db/gpbackup/backup_filepath"
 	"github.com/greenplum-db/gpbackup/utils"
 	"github.com/pkg/errors"
@@ -21,8 +23,7 @@ var (
 	tableDelim = ","
 )
 
-func CopyTableIn(connectionPool *dbconn.DBConn, tableName string, tableAttributes string, destinationToRead string, singleDataFile bool, whichConn int) (int64, error) {
-	whichConn = connectionPool.ValidateConnNum(whichConn)
+func GetCopyTableInQuery(tableName string, tableAttributes string, destinationToRead string, singleDataFile bool) string {
 	copyCommand := ""
 	readFromDestinationCommand := "cat"
 	customPipeThroughCommand := utils.GetPipeThroughProgram().InputCommand
@@ -35,8 +36,16 @@ func CopyTableIn(connectionPool *dbconn.DBConn, tableName string, tableAttribute
 	}
 
 	copyCommand = fmt.Sprintf("PROGRAM '%s %s | %s'", readFromDestinationCommand, destinationToRead, customPipeThroughCommand)
+	if MustGetFlagBool(utils.RESIZE_CLUSTER) {
+		copyCommand = fmt.Sprintf("PROGRAM '%s'", GetResizeReadCommand(readFromDestinationCommand, destinationToRead, customPipeThroughCommand))
+	}
+
+	return fmt.Sprintf("COPY %s%s FROM %s WITH CSV DELIMITER '%s' ON SEGMENT;", tableName, tableAttributes, copyCommand, tableDelim)
+}
 
-	query := fmt.Sprintf("COPY %s%s FROM %s WITH CSV DELIMITER '%s' ON SEGMENT;", tableName, tableAttributes, copyCommand, tableDelim)
+func CopyTableIn(connectionPool *dbconn.DBConn, tableName string, tableAttributes string, destinationToRead string, singleDataFile bool, whichConn int) (int64, error) {
+	whichConn = connectionPool.ValidateConnNum(whichConn)
+	query := GetCopyTableInQuery(tableName, tableAttributes, destinationToRead, singleDataFile)
 	result, err := connectionPool.Exec(query, whichConn)
 	if err != nil {
 		return 0, errors.Wrap(err, fmt.Sprintf("Error loading data into table %s", tableName))
@@ -45,30 +54,241 @@ func CopyTableIn(connectionPool *dbconn.DBConn, tableName string, tableAttribute
 	return numRows, err
 }
 
+/*
+ * Each segment reads the files of its source segments, as given by
+ * GetSourceContentIDs, one after another.  COPY replaces <SEGID> with the
+ * content ID of the segment, which is the first source content ID, and the
+ * remaining ones are every target segment count after it.  A segment with a
+ * content ID above the highest source content ID reads no files.
+ */
+func GetResizeReadCommand(readFromDestinationCommand string, destinationToRead string, customPipeThroughCommand string) string {
+	targetSegmentCount := len(globalCluster.ContentIDs) - 1
+	sourceFile := strings.Replace(destinationToRead, "<SEGID>", "${segid}", -1)
+	return fmt.Sprintf("for segid in $(seq <SEGID> %d %d); do %s %s | %s; done", targetSegmentCount, backupConfig.SegmentCount-1,
+		readFromDestinationCommand, sourceFile, customPipeThroughCommand)
+}
+
+/*
+ * With --resize-cluster, each segment restores the data backed up by every
+ * source segment whose content ID is equal to its own modulo the number of
+ * segments in the restore cluster, so that the data of each source segment is
+ * restored exactly once.
+ */
+func GetSourceContentIDs(contentID int) []int {
+	if !MustGetFlagBool(utils.RESIZE_CLUSTER) {
+		return []int{contentID}
+	}
+	targetSegmentCount := len(globalCluster.ContentIDs) - 1
+	sourceContentIDs := make([]int, 0)
+	for sourceContentID := contentID; sourceContentID < backupConfig.SegmentCount; sourceContentID += targetSegmentCount {
+		sourceContentIDs = append(sourceContentIDs, sourceContentID)
+	}
+	return sourceContentIDs
+}
+
+/*
+ * Rows loaded from other source segments are not on the segments to which
+ * their distribution key hashes, so the table is redistributed after loading.
+ */
+func RedistributeTableData(connectionPool *dbconn.DBConn, tableName string, whichConn int) error {
+	_, err := connectionPool.Exec(fmt.Sprintf("ALTER TABLE %s SET WITH (REORGANIZE=true);", tableName), whichConn)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("Error redistributing data of table %s", tableName))
+	}
+	return nil
+}
+
+/*
+ * The table is truncated in the same transaction as the COPY, so if the COPY
+ * fails the table keeps the data it had before the restore.
+ */
+func TruncateAndCopyTableIn(connectionPool *dbconn.DBConn, tableName string, tableAttributes string, destinationToRead string, singleDataFile bool, whichConn int) (int64, error) {
+	whichConn = connectionPool.ValidateConnNum(whichConn)
+	err := connectionPool.Begin(whichConn)
+	if err != nil {
+		return 0, errors.Wrap(err, fmt.Sprintf("Error loading data into table %s", tableName))
+	}
+	_, err = connectionPool.Exec(fmt.Sprintf("TRUNCATE TABLE %s;", tableName), whichConn)
+	if err != nil {
+		_ = connectionPool.Rollback(whichConn)
+		return 0, errors.Wrap(err, fmt.Sprintf("Error truncating table %s", tableName))
+	}
+	numRows, err := CopyTableIn(connectionPool, tableName, tableAttributes, destinationToRead, singleDataFile, whichConn)
+	if err != nil {
+		_ = connectionPool.Rollback(whichConn)
+		return 0, err
+	}
+	err = connectionPool.Commit(whichConn)
+	if err != nil {
+		return 0, errors.Wrap(err, fmt.Sprintf("Error loading data into table %s", tableName))
+	}
+	return numRows, nil
+}
+
+func GetPrimaryKeyColumns(connectionPool *dbconn.DBConn, tableName string, whichConn int) ([]string, error) {
+	query := fmt.Sprintf(`
+SELECT quote_ident(a.attname)
+FROM pg_index i
+JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+WHERE i.indrelid = '%s'::regclass
+AND i.indisprimary
+ORDER BY a.attnum`, utils.EscapeSingleQuotes(tableName))
+	columns := make([]string, 0)
+	err := connectionPool.Select(&columns, query, whichConn)
+	return columns, err
+}
+
+/*
+ * The data is loaded into a temporary staging table, and then the rows of the
+ * target table with the same primary key as a staged row are replaced by the
+ * staged rows, all in one transaction.  INSERT ... ON CONFLICT is not
+ * available in all supported Greenplum versions, so rows are replaced with a
+ * DELETE followed by an INSERT.  A staging table created with LIKE has the
+ * same distribution policy as the target table, which COPY ON SEGMENT needs
+ * for the backed up rows to be loaded on the segments they came from.
+ */
+func UpsertTableIn(connectionPool *dbconn.DBConn, tableName string, tableAttributes string, destinationToRead string, singleDataFile bool, whichConn int) (int64, error) {
+	whichConn = connectionPool.ValidateConnNum(whichConn)
+	keyColumns, err := GetPrimaryKeyColumns(connectionPool, tableName, whichConn)
+	if err != nil {
+		return 0, errors.Wrap(err, fmt.Sprintf("Error finding primary key of table %s", tableName))
+	}
+	if len(keyColumns) == 0 {
+		return 0, errors.Errorf("Table %s has no primary key, so its data cannot be upserted", tableName)
+	}
+
+	err = connectionPool.Begin(whichConn)
+	if err != nil {
+		return 0, errors.Wrap(err, fmt.Sprintf("Error loading data into table %s", tableName))
+	}
+	stagingTable := "gprestore_upsert_staging"
+	_, err = connectionPool.Exec(fmt.Sprintf("CREATE TEMPORARY TABLE %s (LIKE %s) ON COMMIT DROP;", stagingTable, tableName), whichConn)
+	if err != nil {
+		_ = connectionPool.Rollback(whichConn)
+		return 0, errors.Wrap(err, fmt.Sprintf("Error creating staging table for table %s", tableName))
+	}
+	result, err := connectionPool.Exec(GetCopyTableInQuery(stagingTable, tableAttributes, destinationToRead, singleDataFile), whichConn)
+	if err != nil {
+		_ = connectionPool.Rollback(whichConn)
+		return 0, errors.Wrap(err, fmt.Sprintf("Error loading data into table %s", tableName))
+	}
+	numRows, _ := result.RowsAffected()
+	keyConditions := make([]string, 0)
+	for _, column := range keyColumns {
+		keyConditions = append(keyConditions, fmt.Sprintf("t.%s = s.%s", column, column))
+	}
+	columnList := strings.Trim(tableAttributes, "()")
+	selectList := "*"
+	if columnList != "" {
+		selectList = columnList
+	}
+	upsertStatements := []string{
+		fmt.Sprintf("DELETE FROM %s t USING %s s WHERE %s;", tableName, stagingTable, strings.Join(keyConditions, " AND ")),
+		fmt.Sprintf("INSERT INTO %s%s SELECT %s FROM %s;", tableName, tableAttributes, selectList, stagingTable),
+	}
+	for _, statement := range upsertStatements {
+		_, err = connectionPool.Exec(statement, whichConn)
+		if err != nil {
+			_ = connectionPool.Rollback(whichConn)
+			return 0, errors.Wrap(err, fmt.Sprintf("Error upserting data into table %s", tableName))
+		}
+	}
+	err = connectionPool.Commit(whichConn)
+	if err != nil {
+		return 0, errors.Wrap(err, fmt.Sprintf("Error loading data into table %s", tableName))
+	}
+	return numRows, nil
+}
+
 func restoreSingleTableData(fpInfo *backup_filepath.FilePathInfo, entry utils.MasterDataEntry, tableNum uint32, totalTables int, whichConn int) error {
-	name := utils.MakeFQN(entry.Schema, entry.Name)
+	schema, table := RedirectTable(entry.Schema, entry.Name)
+	name := utils.MakeFQN(schema, table)
 	if gplog.GetVerbosity() > gplog.LOGINFO {
 		// No progress bar at this log level, so we note table count here
 		gplog.Verbose("Reading data for table %s from file (table %d of %d)", name, tableNum, totalTables)
 	} else {
 		gplog.Verbose("Reading data for table %s from file", name)
 	}
-	destinationToRead := ""
+	destinationToRead := getDestinationToRead(fpInfo, entry)
+	startTime := operating.System.Now()
+	/*
+	 * A COPY may have committed just before its connection was lost, so after
+	 * a lost connection a table that started out empty is truncated before its
+	 * data is loaded again.
+	 */
+	copyTableIn, retryCopyTableIn := CopyTableIn, CopyTableIn
+	if restoredTablesStartEmpty() {
+		retryCopyTableIn = TruncateAndCopyTableIn
+	}
+	if MustGetFlagBool(utils.UPSERT) {
+		copyTableIn, retryCopyTableIn = UpsertTableIn, UpsertTableIn
+	} else if MustGetFlagBool(utils.TRUNCATE_TABLE) {
+		copyTableIn, retryCopyTableIn = TruncateAndCopyTableIn, TruncateAndCopyTableIn
+	}
+	var numRowsRestored int64
+	loadTableData := func() error {
+		var err error
+		numRowsRestored, err = copyTableIn(connectionPool, name, entry.AttributeString, destinationToRead, backupConfig.SingleDataFile, whichConn)
+		return err
+	}
+	resetSession := func() error {
+		copyTableIn = retryCopyTableIn
+		return resetDataConnection(whichConn)
+	}
+	var err error
 	if backupConfig.SingleDataFile {
-		destinationToRead = fmt.Sprintf("%s_%d", fpInfo.GetSegmentPipePathForCopyCommand(), entry.Oid)
+		// The data of a single data file backup is read from a pipe that cannot be read again
+		err = loadTableData()
 	} else {
-		destinationToRead = fpInfo.GetTableBackupFilePathForCopyCommand(entry.Oid, utils.GetPipeThroughProgram().Extension, backupConfig.SingleDataFile)
+		err = RetryOnTransientError(fmt.Sprintf("restoring data of table %s", name), loadTableData, resetSession)
 	}
-	numRowsRestored, err := CopyTableIn(connectionPool, name, entry.AttributeString, destinationToRead, backupConfig.SingleDataFile, whichConn)
-	if err != nil {
-		return err
+	if err == nil {
+		numRowsBackedUp := entry.RowsCopied
+		err = CheckRowsRestored(numRowsRestored, numRowsBackedUp, name)
+	}
+	if err == nil && MustGetFlagBool(utils.RESIZE_CLUSTER) {
+		err = RetryOnTransientError(fmt.Sprintf("redistributing data of table %s", name), func() error {
+			return RedistributeTableData(connectionPool, name, whichConn)
+		}, resetSession)
 	}
-	numRowsBackedUp := entry.RowsCopied
-	err = CheckRowsRestored(numRowsRestored, numRowsBackedUp, name)
+	if err == nil && entry.Checksum != "" && shouldVerifyChecksums() {
+		err = CheckTableChecksum(connectionPool, name, entry.Checksum, whichConn)
+	}
+	tableDataReports.AddRestoredTable(schema, table, numRowsRestored, entry.RowsCopied, startTime, operating.System.Now(), err)
 	if err != nil {
-		return err
+		replayStatement := utils.StatementWithType{Schema: schema, Name: table, ObjectType: "TABLE DATA", Statement: getTableDataReplayStatements(name, entry, destinationToRead)}
+		failedDataStatements.AddStatement(replayStatement, err)
 	}
-	return nil
+	return err
+}
+
+/*
+ * Returns the statements that restore the data of a table again, for the
+ * errors file.  Single data file backups are read through pipes that only
+ * exist during a restore, and upserts go through a staging table, so the
+ * data of those tables is left to be restored again by gprestore.
+ */
+func getTableDataReplayStatements(tableName string, entry utils.MasterDataEntry, destinationToRead string) string {
+	if backupConfig.SingleDataFile || MustGetFlagBool(utils.UPSERT) {
+		return fmt.Sprintf("-- The data of table %s can only be restored again by gprestore", tableName)
+	}
+	statements := GetCopyTableInQuery(tableName, entry.AttributeString, destinationToRead, backupConfig.SingleDataFile)
+	if restoredTablesStartEmpty() {
+		statements = fmt.Sprintf("TRUNCATE TABLE %s;\n%s", tableName, statements)
+	} else {
+		statements = fmt.Sprintf("-- Remove any rows of table %s that were loaded before the error before running this COPY\n%s", tableName, statements)
+	}
+	if MustGetFlagBool(utils.RESIZE_CLUSTER) {
+		statements += fmt.Sprintf("\nALTER TABLE %s SET WITH (REORGANIZE=true);", tableName)
+	}
+	return statements
+}
+
+func getDestinationToRead(fpInfo *backup_filepath.FilePathInfo, entry utils.MasterDataEntry) string {
+	if backupConfig.SingleDataFile {
+		return fmt.Sprintf("%s_%d", fpInfo.GetSegmentPipePathForCopyCommand(), entry.Oid)
+	}
+	return fpInfo.GetTableBackupFilePathForCopyCommand(entry.Oid, utils.GetPipeThroughProgram().Extension, backupConfig.SingleDataFile)
 }
 
 func CheckRowsRestored(rowsRestored int64, rowsBackedUp int64, tableName string) error {
@@ -79,6 +299,65 @@ func CheckRowsRestored(rowsRestored int64, rowsBackedUp int64, tableName string)
 	return nil
 }
 
+func CheckTableChecksum(connectionPool *dbconn.DBConn, tableName string, checksumBackedUp string, whichConn int) error {
+	checksumRestored, err := utils.GetTableChecksum(connectionPool, tableName, whichConn)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("Error computing checksum of table %s", tableName))
+	}
+	if checksumRestored != checksumBackedUp {
+		checksumErrMsg := fmt.Sprintf("Expected checksum %s for table %s, but restored data has checksum %s", checksumBackedUp, tableName, checksumRestored)
+		return errors.New(checksumErrMsg)
+	}
+	return nil
+}
+
+func restoredTablesStartEmpty() bool {
+	if MustGetFlagBool(utils.UPSERT) {
+		return false
+	}
+	isDataOnly := MustGetFlagBool(utils.DATA_ONLY) || backupConfig.DataOnly
+	return !isDataOnly || MustGetFlagBool(utils.TRUNCATE_TABLE)
+}
+
+/*
+ * A checksum taken at backup time only describes the restored table if the
+ * table was empty before its data was restored.
+ */
+func shouldVerifyChecksums() bool {
+	return backupConfig.WithChecksums && restoredTablesStartEmpty()
+}
+
+/*
+ * Data connections have these settings in addition to the session GUCs
+ * recorded in the backup.
+ */
+func setDataConnectionSettings(whichConn int) error {
+	if MustGetFlagBool(utils.RESIZE_CLUSTER) {
+		// Rows from other source segments must be loaded before they are redistributed
+		_, err := connectionPool.Exec("SET gp_enable_segment_copy_checking TO off;", whichConn)
+		if err != nil {
+			return err
+		}
+	}
+	if shouldVerifyChecksums() {
+		for _, setting := range utils.GetChecksumSessionSettings(connectionPool) {
+			_, err := connectionPool.Exec(setting, whichConn)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func resetDataConnection(whichConn int) error {
+	err := resetSessionForConnection(whichConn)
+	if err != nil {
+		return err
+	}
+	return setDataConnectionSettings(whichConn)
+}
+
 func restoreDataFromTimestamp(fpInfo backup_filepath.FilePathInfo, dataEntries []utils.MasterDataEntry,
 	gucStatements []utils.StatementWithType, dataProgressBar utils.ProgressBar) {
 	if len(dataEntries) == 0 {
@@ -116,6 +395,7 @@ func restoreDataFromTimestamp(fpInfo backup_filepath.FilePathInfo, dataEntries [
 		go func(whichConn int) {
 			defer workerPool.Done()
 			setGUCsForConnection(gucStatements, whichConn)
+			gplog.FatalOnError(setDataConnectionSettings(whichConn))
 			for entry := range tasks {
 				if wasTerminated || fatalErr != nil {
 					dataProgressBar.(*pb.ProgressBar).NotPrint = true
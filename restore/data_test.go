@@ -1,9 +1,12 @@
 package restore_test
 
 import (
+	"errors"
 	"regexp"
 
+	"github.com/greenplum-db/gp-common-go-libs/cluster"
 	"github.com/greenplum-db/gpbackup/backup"
+	"github.com/greenplum-db/gpbackup/backup_history"
 	"github.com/greenplum-db/gpbackup/restore"
 	"github.com/greenplum-db/gpbackup/utils"
 
@@ -70,6 +73,132 @@ var _ = Describe("restore/data tests", func() {
 			Expect(err).ShouldNot(HaveOccurred())
 		})
 	})
+	Describe("restoring to a cluster with a different number of segments", func() {
+		BeforeEach(func() {
+			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "cat", OutputCommand: "cat -", InputCommand: "cat -", Extension: ""})
+			cmdFlags.Set(utils.PLUGIN_CONFIG, "")
+			cmdFlags.Set(utils.RESIZE_CLUSTER, "true")
+			restore.SetCluster(cluster.NewCluster([]cluster.SegConfig{{ContentID: -1}, {ContentID: 0}, {ContentID: 1}}))
+			restore.SetBackupConfig(&backup_history.BackupConfig{SegmentCount: 5})
+		})
+		AfterEach(func() {
+			cmdFlags.Set(utils.RESIZE_CLUSTER, "false")
+		})
+		It("reads the files of every source segment that maps to each segment", func() {
+			execStr := regexp.QuoteMeta("COPY public.foo(i,j) FROM PROGRAM 'for segid in $(seq <SEGID> 2 4); do cat /backups/gpseg${segid}/backups/20170101/20170101010101/gpbackup_${segid}_20170101010101_3456 | cat -; done' WITH CSV DELIMITER ',' ON SEGMENT;")
+			mock.ExpectExec(execStr).WillReturnResult(sqlmock.NewResult(10, 0))
+			filename := "/backups/gpseg<SEGID>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456"
+			_, err := restore.CopyTableIn(connectionPool, "public.foo", "(i,j)", filename, false, 0)
+
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+		It("redistributes the data of a table", func() {
+			mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE public.foo SET WITH (REORGANIZE=true);")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+			Expect(restore.RedistributeTableData(connectionPool, "public.foo", 0)).To(Succeed())
+		})
+		It("returns an error if the data of a table cannot be redistributed", func() {
+			mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE public.foo SET WITH (REORGANIZE=true);")).WillReturnError(errors.New("reorganize failed"))
+
+			err := restore.RedistributeTableData(connectionPool, "public.foo", 0)
+
+			Expect(err).To(MatchError("Error redistributing data of table public.foo: reorganize failed"))
+		})
+	})
+	Describe("GetSourceContentIDs", func() {
+		BeforeEach(func() {
+			restore.SetCluster(cluster.NewCluster([]cluster.SegConfig{{ContentID: -1}, {ContentID: 0}, {ContentID: 1}, {ContentID: 2}}))
+		})
+		AfterEach(func() {
+			cmdFlags.Set(utils.RESIZE_CLUSTER, "false")
+		})
+		It("returns the content ID of the segment if the cluster is not resized", func() {
+			Expect(restore.GetSourceContentIDs(1)).To(Equal([]int{1}))
+		})
+		It("maps the segments of a larger source cluster onto the segments of the cluster", func() {
+			cmdFlags.Set(utils.RESIZE_CLUSTER, "true")
+			restore.SetBackupConfig(&backup_history.BackupConfig{SegmentCount: 8})
+			Expect(restore.GetSourceContentIDs(0)).To(Equal([]int{0, 3, 6}))
+			Expect(restore.GetSourceContentIDs(2)).To(Equal([]int{2, 5}))
+		})
+		It("maps no source segments to the extra segments of a larger cluster", func() {
+			cmdFlags.Set(utils.RESIZE_CLUSTER, "true")
+			restore.SetBackupConfig(&backup_history.BackupConfig{SegmentCount: 2})
+			Expect(restore.GetSourceContentIDs(1)).To(Equal([]int{1}))
+			Expect(restore.GetSourceContentIDs(2)).To(BeEmpty())
+		})
+	})
+	Describe("TruncateAndCopyTableIn", func() {
+		filename := "<SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456"
+		BeforeEach(func() {
+			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "cat", OutputCommand: "cat -", InputCommand: "cat -", Extension: ""})
+			cmdFlags.Set(utils.PLUGIN_CONFIG, "")
+		})
+		It("truncates the table in the same transaction as the COPY", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta("TRUNCATE TABLE public.foo;")).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(regexp.QuoteMeta("COPY public.foo(i,j) FROM PROGRAM")).WillReturnResult(sqlmock.NewResult(0, 10))
+			mock.ExpectCommit()
+
+			numRows, err := restore.TruncateAndCopyTableIn(connectionPool, "public.foo", "(i,j)", filename, false, 0)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(numRows).To(Equal(int64(10)))
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+		It("rolls back the truncate if the COPY fails", func() {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta("TRUNCATE TABLE public.foo;")).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(regexp.QuoteMeta("COPY public.foo(i,j) FROM PROGRAM")).WillReturnError(errors.New("missing data file"))
+			mock.ExpectRollback()
+
+			_, err := restore.TruncateAndCopyTableIn(connectionPool, "public.foo", "(i,j)", filename, false, 0)
+
+			Expect(err).To(MatchError("Error loading data into table public.foo: missing data file"))
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
+	Describe("UpsertTableIn", func() {
+		filename := "<SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456"
+		BeforeEach(func() {
+			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "cat", OutputCommand: "cat -", InputCommand: "cat -", Extension: ""})
+			cmdFlags.Set(utils.PLUGIN_CONFIG, "")
+		})
+		It("loads the data into a staging table and replaces rows with the same primary key", func() {
+			mock.ExpectQuery("SELECT quote_ident").WillReturnRows(sqlmock.NewRows([]string{"quote_ident"}).AddRow("i").AddRow("j"))
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta("CREATE TEMPORARY TABLE gprestore_upsert_staging (LIKE public.foo) ON COMMIT DROP;")).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(regexp.QuoteMeta("COPY gprestore_upsert_staging(i,j) FROM PROGRAM")).WillReturnResult(sqlmock.NewResult(0, 10))
+			mock.ExpectExec(regexp.QuoteMeta("DELETE FROM public.foo t USING gprestore_upsert_staging s WHERE t.i = s.i AND t.j = s.j;")).WillReturnResult(sqlmock.NewResult(0, 4))
+			mock.ExpectExec(regexp.QuoteMeta("INSERT INTO public.foo(i,j) SELECT i,j FROM gprestore_upsert_staging;")).WillReturnResult(sqlmock.NewResult(0, 10))
+			mock.ExpectCommit()
+
+			numRows, err := restore.UpsertTableIn(connectionPool, "public.foo", "(i,j)", filename, false, 0)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(numRows).To(Equal(int64(10)))
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+		It("returns an error if the table has no primary key", func() {
+			mock.ExpectQuery("SELECT quote_ident").WillReturnRows(sqlmock.NewRows([]string{"quote_ident"}))
+
+			_, err := restore.UpsertTableIn(connectionPool, "public.foo", "(i,j)", filename, false, 0)
+
+			Expect(err).To(MatchError("Table public.foo has no primary key, so its data cannot be upserted"))
+		})
+		It("names only the restored table if the data cannot be loaded", func() {
+			mock.ExpectQuery("SELECT quote_ident").WillReturnRows(sqlmock.NewRows([]string{"quote_ident"}).AddRow("i"))
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta("CREATE TEMPORARY TABLE gprestore_upsert_staging (LIKE public.foo) ON COMMIT DROP;")).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(regexp.QuoteMeta("COPY gprestore_upsert_staging(i,j) FROM PROGRAM")).WillReturnError(errors.New("missing data file"))
+			mock.ExpectRollback()
+
+			_, err := restore.UpsertTableIn(connectionPool, "public.foo", "(i,j)", filename, false, 0)
+
+			Expect(err).To(MatchError("Error loading data into table public.foo: missing data file"))
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
 	Describe("CheckRowsRestored", func() {
 		var (
 			expectedRows int64 = 10
@@ -84,4 +213,22 @@ var _ = Describe("restore/data tests", func() {
 			Expect(err.Error()).To(Equal("Expected to restore 10 rows to table public.foo, but restored 5 instead"))
 		})
 	})
+	Describe("CheckTableChecksum", func() {
+		checksumQuery := regexp.QuoteMeta("SELECT count(*) || ':' || coalesce(sum(")
+		It("does nothing if the checksum of the restored data matches", func() {
+			mock.ExpectQuery(checksumQuery).WillReturnRows(sqlmock.NewRows([]string{"string"}).AddRow("10:123456789"))
+			err := restore.CheckTableChecksum(connectionPool, "public.foo", "10:123456789", 0)
+			Expect(err).ToNot(HaveOccurred())
+		})
+		It("returns an error if the checksum of the restored data does not match", func() {
+			mock.ExpectQuery(checksumQuery).WillReturnRows(sqlmock.NewRows([]string{"string"}).AddRow("10:987654321"))
+			err := restore.CheckTableChecksum(connectionPool, "public.foo", "10:123456789", 0)
+			Expect(err).To(MatchError("Expected checksum 10:123456789 for table public.foo, but restored data has checksum 10:987654321"))
+		})
+		It("returns an error if the checksum cannot be computed", func() {
+			mock.ExpectQuery(checksumQuery).WillReturnError(errors.New("relation does not exist"))
+			err := restore.CheckTableChecksum(connectionPool, "public.foo", "10:123456789", 0)
+			Expect(err).To(MatchError("Error computing checksum of table public.foo: relation does not exist"))
+		})
+	})
 })
@@ -2,6 +2,7 @@ package restore
 
 import (
 	"sync"
+	"time"
 
 	"github.com/greenplum-db/gp-common-go-libs/cluster"
 	"github.com/greenplum-db/gp-common-go-libs/dbconn"
@@ -27,10 +28,25 @@ var (
 	globalFPInfo     backup_filepath.FilePathInfo
 	globalTOC        *utils.TOC
 	pluginConfig     *utils.PluginConfig
+	redirectSchema   string
+	tableMap         map[string]string
 	restoreStartTime string
 	version          string
 	wasTerminated    bool
 
+	// Per-table and per-statement results of the restore, for the JSON report
+	tableDataReports utils.TableDataReportList
+	failedStatements utils.FailedStatementList
+
+	// Statements to restore the data of the tables whose data restore failed, for the errors file
+	failedDataStatements utils.FailedStatementList
+
+	// The objects that the included relations depend on, restored with --with-dependencies
+	relationDependencies map[utils.UniqueID]bool
+
+	// How long to wait before the first retry of a statement that failed with a transient error
+	retryDelay = time.Second
+
 	/*
 	 * Used for synchronizing DoCleanup.  In DoInit() we increment the group
 	 * and then wait for at least one DoCleanup to finish, either in DoTeardown
@@ -60,6 +76,10 @@ func SetConnection(conn *dbconn.DBConn) {
 	connectionPool = conn
 }
 
+func SetRetryDelay(delay time.Duration) {
+	retryDelay = delay
+}
+
 func SetCluster(cluster *cluster.Cluster) {
 	globalCluster = cluster
 }
@@ -72,6 +92,26 @@ func SetPluginConfig(config *utils.PluginConfig) {
 	pluginConfig = config
 }
 
+func SetRedirectSchema(schema string) {
+	redirectSchema = schema
+}
+
+func SetTableMap(newTableMap map[string]string) {
+	tableMap = newTableMap
+}
+
+func SetRelationDependencies(dependencies map[utils.UniqueID]bool) {
+	relationDependencies = dependencies
+}
+
+func GetTableDataReports() []utils.TableDataReport {
+	return tableDataReports.GetTables()
+}
+
+func GetFailedStatements() []utils.FailedStatement {
+	return failedStatements.GetStatements()
+}
+
 func SetTOC(toc *utils.TOC) {
 	globalTOC = toc
 }
@@ -93,3 +133,7 @@ func MustGetFlagBool(flagName string) bool {
 func MustGetFlagStringSlice(flagName string) []string {
 	return utils.MustGetFlagStringSlice(cmdFlags, flagName)
 }
+
+func MustGetFlagStringArray(flagName string) []string {
+	return utils.MustGetFlagStringArray(cmdFlags, flagName)
+}
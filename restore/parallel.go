@@ -19,16 +19,27 @@ func executeStatementsForConn(statements chan utils.StatementWithType, fatalErr
 		if wasTerminated || *fatalErr != nil {
 			return
 		}
+		executeStatement(statement, fatalErr, numErrors, whichConn)
+		progressBar.Increment()
+	}
+}
+
+func executeStatement(statement utils.StatementWithType, fatalErr *error, numErrors *int32, whichConn int) {
+	description := fmt.Sprintf("executing statement for %s %s", statement.ObjectType, statement.GetObjectName())
+	err := RetryOnTransientError(description, func() error {
 		_, err := connectionPool.Exec(statement.Statement, whichConn)
-		if err != nil {
-			gplog.Verbose("Error encountered when executing statement: %s Error was: %s", strings.TrimSpace(statement.Statement), err.Error())
-			if MustGetFlagBool(utils.ON_ERROR_CONTINUE) {
-				atomic.AddInt32(numErrors, 1)
-			} else {
-				*fatalErr = err
-			}
+		return err
+	}, func() error {
+		return resetSessionForConnection(whichConn)
+	})
+	if err != nil {
+		gplog.Verbose("Error encountered when executing statement: %s Error was: %s", strings.TrimSpace(statement.Statement), err.Error())
+		failedStatements.AddStatement(statement, err)
+		if MustGetFlagBool(utils.ON_ERROR_CONTINUE) {
+			atomic.AddInt32(numErrors, 1)
+		} else {
+			*fatalErr = err
 		}
-		progressBar.Increment()
 	}
 }
 
@@ -60,6 +71,10 @@ func ExecuteStatements(statements []utils.StatementWithType, progressBar utils.P
 		}
 		workerPool.Wait()
 	}
+	reportStatementErrors(fatalErr, numErrors)
+}
+
+func reportStatementErrors(fatalErr error, numErrors int32) {
 	if fatalErr != nil {
 		gplog.Fatal(fatalErr, "")
 	} else if numErrors > 0 {
@@ -67,6 +82,113 @@ func ExecuteStatements(statements []utils.StatementWithType, progressBar utils.P
 	}
 }
 
+/*
+ * Statements with dependency information in the TOC are executed as a graph
+ * across all connections, where each object is created as soon as every
+ * object it depends on has been created.  The statements for a single object,
+ * such as its CREATE, COMMENT, and ALTER OWNER statements, are executed in
+ * order on one connection.  Statements without dependency information, which
+ * includes every statement in backups taken before dependencies were recorded,
+ * are executed in order on one connection once all statements before them
+ * have finished.
+ */
+func ExecuteStatementsWithDependencies(statements []utils.StatementWithType, progressBar utils.ProgressBar) {
+	var fatalErr error
+	var numErrors int32
+	for start := 0; start < len(statements) && !wasTerminated && fatalErr == nil; {
+		hasDependencyInfo := statements[start].ID != utils.UniqueID{}
+		end := start + 1
+		for end < len(statements) && (statements[end].ID != utils.UniqueID{}) == hasDependencyInfo {
+			end++
+		}
+		if hasDependencyInfo {
+			executeDependencyGraph(BuildDependencyGraph(statements[start:end]), &fatalErr, &numErrors, progressBar)
+		} else {
+			tasks := make(chan utils.StatementWithType, end-start)
+			for _, statement := range statements[start:end] {
+				tasks <- statement
+			}
+			close(tasks)
+			executeStatementsForConn(tasks, &fatalErr, &numErrors, progressBar, 0)
+		}
+		start = end
+	}
+	reportStatementErrors(fatalErr, numErrors)
+}
+
+type DependencyNode struct {
+	Statements      []utils.StatementWithType
+	Dependents      []int
+	NumDependencies int
+}
+
+/*
+ * Only dependencies on objects that come earlier in the metadata file are
+ * kept, which ignores objects that are not being restored and guarantees that
+ * the graph has no cycles, as the file is already in a valid creation order.
+ */
+func BuildDependencyGraph(statements []utils.StatementWithType) []DependencyNode {
+	nodes := make([]DependencyNode, 0)
+	nodeForID := make(map[utils.UniqueID]int, 0)
+	for _, statement := range statements {
+		index, ok := nodeForID[statement.ID]
+		if !ok {
+			index = len(nodes)
+			nodeForID[statement.ID] = index
+			nodes = append(nodes, DependencyNode{})
+			for _, dependency := range statement.Dependencies {
+				if dependencyIndex, ok := nodeForID[dependency]; ok {
+					nodes[dependencyIndex].Dependents = append(nodes[dependencyIndex].Dependents, index)
+					nodes[index].NumDependencies++
+				}
+			}
+		}
+		nodes[index].Statements = append(nodes[index].Statements, statement)
+	}
+	return nodes
+}
+
+func executeDependencyGraph(nodes []DependencyNode, fatalErr *error, numErrors *int32, progressBar utils.ProgressBar) {
+	var workerPool sync.WaitGroup
+	ready := make(chan int, len(nodes))
+	finished := make(chan int, len(nodes))
+	remainingDependencies := make([]int, len(nodes))
+	for i, node := range nodes {
+		remainingDependencies[i] = node.NumDependencies
+		if node.NumDependencies == 0 {
+			ready <- i
+		}
+	}
+
+	for i := 0; i < connectionPool.NumConns; i++ {
+		workerPool.Add(1)
+		go func(connNum int) {
+			defer workerPool.Done()
+			for index := range ready {
+				for _, statement := range nodes[index].Statements {
+					if wasTerminated || *fatalErr != nil {
+						break
+					}
+					executeStatement(statement, fatalErr, numErrors, connNum)
+					progressBar.Increment()
+				}
+				finished <- index
+			}
+		}(i)
+	}
+	// Objects are still marked as finished after an error so that every worker exits
+	for numFinished := 0; numFinished < len(nodes); numFinished++ {
+		for _, dependent := range nodes[<-finished].Dependents {
+			remainingDependencies[dependent]--
+			if remainingDependencies[dependent] == 0 {
+				ready <- dependent
+			}
+		}
+	}
+	close(ready)
+	workerPool.Wait()
+}
+
 func ExecuteStatementsAndCreateProgressBar(statements []utils.StatementWithType, objectsTitle string, showProgressBar int, executeInParallel bool, whichConn ...int) {
 	progressBar := utils.NewProgressBar(len(statements), fmt.Sprintf("%s restored: ", objectsTitle), showProgressBar)
 	progressBar.Start()
@@ -1,11 +1,17 @@
 package restore_test
 
 import (
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
 	"github.com/greenplum-db/gpbackup/restore"
 	"github.com/greenplum-db/gpbackup/utils"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
 )
 
 var _ = Describe("restore/parallel tests", func() {
@@ -34,4 +40,93 @@ var _ = Describe("restore/parallel tests", func() {
 		})
 
 	})
+	Describe("BuildDependencyGraph", func() {
+		typeID := utils.UniqueID{ClassID: 1247, Oid: 1}
+		funcID := utils.UniqueID{ClassID: 1255, Oid: 2}
+		tableID := utils.UniqueID{ClassID: 1259, Oid: 3}
+		createType := utils.StatementWithType{ObjectType: "TYPE", Statement: "CREATE TYPE public.type1;", ID: typeID}
+		createFunc := utils.StatementWithType{ObjectType: "FUNCTION", Statement: "CREATE FUNCTION public.func1() RETURNS public.type1;", ID: funcID, Dependencies: []utils.UniqueID{typeID}}
+		commentFunc := utils.StatementWithType{ObjectType: "FUNCTION", Statement: "COMMENT ON FUNCTION public.func1() IS 'comment';", ID: funcID, Dependencies: []utils.UniqueID{typeID}}
+		createTable := utils.StatementWithType{ObjectType: "TABLE", Statement: "CREATE TABLE public.table1 (i public.type1);", ID: tableID, Dependencies: []utils.UniqueID{typeID, funcID}}
+		It("groups the statements for each object into one node", func() {
+			nodes := restore.BuildDependencyGraph([]utils.StatementWithType{createType, createFunc, commentFunc, createTable})
+			Expect(nodes).To(HaveLen(3))
+			Expect(nodes[0].Statements).To(Equal([]utils.StatementWithType{createType}))
+			Expect(nodes[1].Statements).To(Equal([]utils.StatementWithType{createFunc, commentFunc}))
+			Expect(nodes[2].Statements).To(Equal([]utils.StatementWithType{createTable}))
+		})
+		It("links each object to the objects that depend on it", func() {
+			nodes := restore.BuildDependencyGraph([]utils.StatementWithType{createType, createFunc, commentFunc, createTable})
+			Expect(nodes[0].Dependents).To(Equal([]int{1, 2}))
+			Expect(nodes[0].NumDependencies).To(Equal(0))
+			Expect(nodes[1].Dependents).To(Equal([]int{2}))
+			Expect(nodes[1].NumDependencies).To(Equal(1))
+			Expect(nodes[2].Dependents).To(BeEmpty())
+			Expect(nodes[2].NumDependencies).To(Equal(2))
+		})
+		It("ignores dependencies on objects that are not being restored", func() {
+			nodes := restore.BuildDependencyGraph([]utils.StatementWithType{createFunc, createTable})
+			Expect(nodes[0].NumDependencies).To(Equal(0))
+			Expect(nodes[1].NumDependencies).To(Equal(1))
+		})
+		It("ignores dependencies on objects that come later in the metadata file", func() {
+			nodes := restore.BuildDependencyGraph([]utils.StatementWithType{createTable, createType})
+			Expect(nodes[0].NumDependencies).To(Equal(0))
+			Expect(nodes[1].Dependents).To(BeEmpty())
+		})
+	})
+	Describe("ExecuteStatementsWithDependencies", func() {
+		It("executes statements with and without dependency information in order on a single connection", func() {
+			typeID := utils.UniqueID{ClassID: 1247, Oid: 1}
+			statements := []utils.StatementWithType{
+				{ObjectType: "SEQUENCE", Statement: "CREATE SEQUENCE public.seq1;"},
+				{ObjectType: "TYPE", Statement: "CREATE TYPE public.type1;", ID: typeID},
+				{ObjectType: "TABLE", Statement: "CREATE TABLE public.table1 (i public.type1);", ID: utils.UniqueID{ClassID: 1259, Oid: 2}, Dependencies: []utils.UniqueID{typeID}},
+				{ObjectType: "CONSTRAINT", Statement: "ALTER TABLE public.table1 ADD CONSTRAINT pk PRIMARY KEY (i);"},
+			}
+			for _, statement := range statements {
+				mock.ExpectExec(regexp.QuoteMeta(statement.Statement)).WillReturnResult(sqlmock.NewResult(0, 0))
+			}
+			restore.ExecuteStatementsWithDependencies(statements, utils.NewProgressBar(len(statements), "", utils.PB_NONE))
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+		Context("with multiple connections", func() {
+			typeID := utils.UniqueID{ClassID: 1247, Oid: 1}
+			funcID := utils.UniqueID{ClassID: 1255, Oid: 2}
+			tableID := utils.UniqueID{ClassID: 1259, Oid: 3}
+			BeforeEach(func() {
+				connectionPool, mock = testhelper.CreateAndConnectMockDB(3)
+				restore.SetConnection(connectionPool)
+			})
+			It("does not execute the statements for an object until the objects it depends on have been created", func() {
+				statements := []utils.StatementWithType{
+					{ObjectType: "TYPE", Statement: "CREATE TYPE public.type1;", ID: typeID},
+					{ObjectType: "TYPE", Statement: "COMMENT ON TYPE public.type1 IS 'comment';", ID: typeID},
+					{ObjectType: "FUNCTION", Statement: "CREATE FUNCTION public.func1() RETURNS public.type1;", ID: funcID, Dependencies: []utils.UniqueID{typeID}},
+					{ObjectType: "TABLE", Statement: "CREATE TABLE public.table1 (i public.type1 DEFAULT public.func1());", ID: tableID, Dependencies: []utils.UniqueID{typeID, funcID}},
+					{ObjectType: "TABLE", Statement: "ALTER TABLE public.table1 OWNER TO testrole;", ID: tableID, Dependencies: []utils.UniqueID{typeID, funcID}},
+				}
+				// Expectations are matched in order, so executing a statement early fails the test
+				mock.ExpectExec(regexp.QuoteMeta(statements[0].Statement)).WillDelayFor(50 * time.Millisecond).WillReturnResult(sqlmock.NewResult(0, 0))
+				for _, statement := range statements[1:] {
+					mock.ExpectExec(regexp.QuoteMeta(statement.Statement)).WillReturnResult(sqlmock.NewResult(0, 0))
+				}
+				restore.ExecuteStatementsWithDependencies(statements, utils.NewProgressBar(len(statements), "", utils.PB_NONE))
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+			It("stops executing statements on every connection after an error", func() {
+				statements := []utils.StatementWithType{
+					{ObjectType: "TYPE", Statement: "CREATE TYPE public.type1;", ID: typeID},
+					{ObjectType: "FUNCTION", Statement: "CREATE FUNCTION public.func1() RETURNS public.type1;", ID: funcID, Dependencies: []utils.UniqueID{typeID}},
+					{ObjectType: "TABLE", Statement: "CREATE TABLE public.table1 (i public.type1);", ID: tableID, Dependencies: []utils.UniqueID{typeID}},
+				}
+				mock.ExpectExec(regexp.QuoteMeta(statements[0].Statement)).WillReturnError(errors.New("type already exists"))
+				defer func() {
+					Expect(mock.ExpectationsWereMet()).To(Succeed())
+				}()
+				defer testhelper.ShouldPanicWithMessage("type already exists")
+				restore.ExecuteStatementsWithDependencies(statements, utils.NewProgressBar(len(statements), "", utils.PB_NONE))
+			})
+		})
+	})
 })
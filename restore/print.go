@@ -0,0 +1,176 @@
+package restore
+
+/*
+ * This file contains functions related to writing the statements that a
+ * restore would execute to a file instead of executing them, and to writing
+ * the statements that failed during a restore to a file so they can be re-run.
+ */
+
+import (
+	"strings"
+
+	"github.com/greenplum-db/gp-common-go-libs/cluster"
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/greenplum-db/gpbackup/backup_filepath"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
+)
+
+/*
+ * Only the backup files in the master backup directory are read, so no
+ * database connection or segment configuration is needed.  Without a
+ * connection, identifiers given on the command line are quoted locally and the
+ * existence of the objects the restore would use is not checked.
+ */
+func DoPrintSQL() {
+	SetLoggerVerbosity()
+	masterDataDir := operating.System.Getenv("MASTER_DATA_DIRECTORY")
+	if masterDataDir == "" && MustGetFlagString(utils.BACKUP_DIR) == "" {
+		gplog.Fatal(errors.Errorf("The MASTER_DATA_DIRECTORY environment variable must be set to use --print-sql without --backup-dir"), "")
+	}
+	globalCluster = cluster.NewCluster([]cluster.SegConfig{{ContentID: -1, Hostname: "localhost", DataDir: masterDataDir}})
+	if MustGetFlagString(utils.TIMESTAMP) == "" {
+		SetTimestampFromBackupHistory()
+	}
+	segPrefix := backup_filepath.ParseSegPrefix(MustGetFlagString(utils.BACKUP_DIR), MustGetFlagString(utils.TIMESTAMP))
+	globalFPInfo = backup_filepath.NewFilePathInfo(globalCluster, MustGetFlagString(utils.BACKUP_DIR), MustGetFlagString(utils.TIMESTAMP), segPrefix)
+	readBackupConfig()
+	BackupConfigurationValidation()
+	if unquotedRedirectSchema := MustGetFlagString(utils.REDIRECT_SCHEMA); unquotedRedirectSchema != "" {
+		redirectSchema = utils.QuoteIdentWithoutConnection(unquotedRedirectSchema)
+	}
+
+	sqlFilename := MustGetFlagString(utils.PRINT_SQL)
+	sqlFile := utils.NewFileWithByteCountFromFile(sqlFilename)
+	defer sqlFile.Close()
+	PrintRestoreSQL(sqlFile)
+	gplog.Info("Restore statements written to %s", sqlFilename)
+}
+
+// The statements are written in the order in which DoSetup and DoRestore execute them
+func PrintRestoreSQL(sqlFile *utils.FileWithByteCount) {
+	metadataFilename := globalFPInfo.GetMetadataFilePath()
+	isDataOnly := backupConfig.DataOnly || MustGetFlagBool(utils.DATA_ONLY)
+	isMetadataOnly := backupConfig.MetadataOnly || MustGetFlagBool(utils.METADATA_ONLY)
+	restoreDatabase := backupConfig.DatabaseName
+	quotedRedirectDB := ""
+	if MustGetFlagString(utils.REDIRECT_DB) != "" {
+		quotedRedirectDB = utils.QuoteIdentWithoutConnection(MustGetFlagString(utils.REDIRECT_DB))
+		restoreDatabase = quotedRedirectDB
+	}
+
+	sqlFile.MustPrintf("-- Statements to restore backup %s of database %s\n", globalFPInfo.Timestamp, backupConfig.DatabaseName)
+	if MustGetFlagBool(utils.WITH_GLOBALS) {
+		printStatements(sqlFile, "Global objects", getGlobalStatements(metadataFilename, quotedRedirectDB, getActiveUser()))
+	} else if MustGetFlagBool(utils.CREATE_DB) {
+		printStatements(sqlFile, "Database creation", getCreateDatabaseStatements(metadataFilename, quotedRedirectDB))
+	}
+	sqlFile.MustPrintf("\n\\connect %s\n", restoreDatabase)
+	gucStatements := GetRestoreMetadataStatements("global", metadataFilename, []string{"SESSION GUCS"}, []string{}, false, false)
+	printStatements(sqlFile, "Session settings, set on every connection", gucStatements)
+
+	if !isDataOnly {
+		schemaStatements, statements := getPredataStatements(metadataFilename)
+		printStatements(sqlFile, "Schemas", schemaStatements)
+		printStatements(sqlFile, "Pre-data objects", statements)
+	}
+	if !isMetadataOnly {
+		printDataStatements(sqlFile)
+	}
+	if !isDataOnly {
+		statements := GetRestoreMetadataStatements("postdata", metadataFilename, []string{}, []string{}, true, true)
+		firstBatch, secondBatch := BatchPostdataStatements(statements)
+		printStatements(sqlFile, "Post-data objects, first batch", firstBatch)
+		printStatements(sqlFile, "Post-data objects, second batch", secondBatch)
+	}
+	if MustGetFlagBool(utils.WITH_STATS) && backupConfig.WithStatistics {
+		statements := GetRestoreMetadataStatements("statistics", globalFPInfo.GetStatisticsFilePath(), []string{}, []string{}, true, false)
+		printStatements(sqlFile, "Table statistics", statements)
+	}
+}
+
+func printStatements(sqlFile *utils.FileWithByteCount, title string, statements []utils.StatementWithType) {
+	if len(statements) == 0 {
+		return
+	}
+	sqlFile.MustPrintf("\n-- %s\n", title)
+	for _, statement := range statements {
+		sqlFile.MustPrintf("\n%s\n", strings.TrimSpace(statement.Statement))
+	}
+}
+
+/*
+ * The COPY commands read the data files on the segment hosts, and single data
+ * file backups also need gpbackup_helper to be running on every segment, so
+ * they are written as comments rather than as statements to be run.
+ */
+func printDataStatements(sqlFile *utils.FileWithByteCount) {
+	fpInfoList := GetBackupFPInfoListFromRestorePlan()
+	filteredDataEntries := getFilteredDataEntries(fpInfoList)
+	sqlFile.MustPrintf("\n-- Table data, restored by COPY on the segments\n")
+	for i := range fpInfoList {
+		for _, entry := range filteredDataEntries[i] {
+			schema, table := RedirectTable(entry.Schema, entry.Name)
+			name := utils.MakeFQN(schema, table)
+			sqlFile.MustPrintf("\n-- Table %s, %d rows, from backup %s\n", name, entry.RowsCopied, fpInfoList[i].Timestamp)
+			if MustGetFlagBool(utils.TRUNCATE_TABLE) {
+				sqlFile.MustPrintf("-- TRUNCATE TABLE %s;\n", name)
+			} else if MustGetFlagBool(utils.UPSERT) {
+				sqlFile.MustPrintf("-- Rows are loaded into a staging table and replace the rows of %s with the same primary key\n", name)
+			}
+			copyQuery := GetCopyTableInQuery(name, entry.AttributeString, getDestinationToRead(&fpInfoList[i], entry), backupConfig.SingleDataFile)
+			sqlFile.MustPrintf("-- %s\n", copyQuery)
+		}
+	}
+}
+
+// The connecting role is not recreated by a global restore, so it is not printed either
+func getActiveUser() string {
+	if user := operating.System.Getenv("PGUSER"); user != "" {
+		return user
+	}
+	currentUser, err := operating.System.CurrentUser()
+	gplog.FatalOnError(err, "Unable to determine the current user")
+	return currentUser.Username
+}
+
+/*
+ * Each failed statement is preceded by a comment with its error, so that the
+ * failures can be fixed in the file and only the failed statements re-run
+ * with psql.  The table data statements follow the metadata statements, as
+ * a table whose CREATE statement failed cannot be loaded until it is created.
+ */
+func PrintFailedStatements(sqlFile *utils.FileWithByteCount, restoreDatabase string, gucStatements []utils.StatementWithType,
+	statements []utils.FailedStatement, dataStatements []utils.FailedStatement) {
+	sqlFile.MustPrintf("-- Statements that failed during the restore of backup %s\n", globalFPInfo.Timestamp)
+	sqlFile.MustPrintf("\n\\connect %s\n", restoreDatabase)
+	printStatements(sqlFile, "Session settings", gucStatements)
+	printFailedStatementList(sqlFile, "Failed statements", statements)
+	printFailedStatementList(sqlFile, "Failed table data restores", dataStatements)
+}
+
+func printFailedStatementList(sqlFile *utils.FileWithByteCount, title string, statements []utils.FailedStatement) {
+	if len(statements) == 0 {
+		return
+	}
+	sqlFile.MustPrintf("\n-- %s\n", title)
+	for _, statement := range statements {
+		sqlFile.MustPrintf("\n-- %s %s\n", statement.ObjectType, statement.GetObjectName())
+		if statement.SQLState != "" {
+			sqlFile.MustPrintf("-- Error (SQLSTATE %s): %s\n", statement.SQLState, strings.Replace(statement.Error, "\n", "\n-- ", -1))
+		} else {
+			sqlFile.MustPrintf("-- Error: %s\n", strings.Replace(statement.Error, "\n", "\n-- ", -1))
+		}
+		sqlFile.MustPrintf("%s\n", statement.Statement)
+	}
+}
+
+func writeErrorsFile(errorsFilename string) {
+	gucStatements := GetRestoreMetadataStatements("global", globalFPInfo.GetMetadataFilePath(), []string{"SESSION GUCS"}, []string{}, false, false)
+	sqlFile := utils.NewFileWithByteCountFromFile(errorsFilename)
+	defer sqlFile.Close()
+	PrintFailedStatements(sqlFile, utils.QuoteIdent(connectionPool, connectionPool.DBName), gucStatements,
+		failedStatements.GetStatements(), failedDataStatements.GetStatements())
+	gplog.Info("Failed statements written to %s", errorsFilename)
+}
@@ -0,0 +1,161 @@
+package restore_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/greenplum-db/gp-common-go-libs/cluster"
+	"github.com/greenplum-db/gpbackup/backup_filepath"
+	"github.com/greenplum-db/gpbackup/backup_history"
+	"github.com/greenplum-db/gpbackup/restore"
+	"github.com/greenplum-db/gpbackup/utils"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("restore/print tests", func() {
+	Describe("PrintRestoreSQL", func() {
+		var (
+			backupDir string
+			fpInfo    backup_filepath.FilePathInfo
+			toc       *utils.TOC
+			metadata  string
+		)
+		addEntry := func(section string, entry utils.MetadataEntry, statement string) {
+			start := uint64(len(metadata))
+			metadata += statement
+			toc.AddMetadataEntry(section, entry, start, uint64(len(metadata)))
+		}
+		BeforeEach(func() {
+			var err error
+			backupDir, err = ioutil.TempDir("", "print_sql")
+			Expect(err).ToNot(HaveOccurred())
+			testCluster := cluster.NewCluster([]cluster.SegConfig{{ContentID: -1, Hostname: "localhost", DataDir: backupDir}})
+			restore.SetCluster(testCluster)
+			fpInfo = backup_filepath.NewFilePathInfo(testCluster, "", "20170101010101", "")
+			restore.SetFPInfo(fpInfo)
+			Expect(os.MkdirAll(filepath.Dir(fpInfo.GetMetadataFilePath()), 0755)).To(Succeed())
+
+			toc = &utils.TOC{}
+			toc.InitializeMetadataEntryMap()
+			metadata = ""
+			addEntry("global", utils.MetadataEntry{ObjectType: "SESSION GUCS"}, "\nSET client_encoding = 'UTF8';\n")
+			addEntry("global", utils.MetadataEntry{Name: "testdb", ObjectType: "DATABASE"}, "\n\nCREATE DATABASE testdb TEMPLATE template0;\n")
+			addEntry("predata", utils.MetadataEntry{Schema: "sales", Name: "sales", ObjectType: "SCHEMA"}, "\n\nCREATE SCHEMA sales;\n")
+			addEntry("predata", utils.MetadataEntry{Schema: "sales", Name: "orders", ObjectType: "TABLE"}, "\n\nCREATE TABLE sales.orders (\n\tid integer\n) DISTRIBUTED BY (id);\n")
+			addEntry("postdata", utils.MetadataEntry{Schema: "sales", Name: "orders_idx", ObjectType: "INDEX", ReferenceObject: "sales.orders"}, "\n\nCREATE INDEX orders_idx ON sales.orders USING btree (id);\n")
+			Expect(ioutil.WriteFile(fpInfo.GetMetadataFilePath(), []byte(metadata), 0644)).To(Succeed())
+			restore.SetTOC(toc)
+			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "cat", OutputCommand: "cat -", InputCommand: "cat -", Extension: ""})
+		})
+		AfterEach(func() {
+			os.RemoveAll(backupDir)
+			restore.SetRedirectSchema("")
+		})
+		It("writes the metadata statements of a metadata-only backup in restore order", func() {
+			restore.SetBackupConfig(&backup_history.BackupConfig{DatabaseName: "testdb", MetadataOnly: true})
+			cmdFlags.Set(utils.CREATE_DB, "true")
+			cmdFlags.Set(utils.REDIRECT_DB, "New DB")
+
+			restore.PrintRestoreSQL(utils.NewFileWithByteCount(buffer))
+
+			Expect(string(buffer.Contents())).To(Equal(`-- Statements to restore backup 20170101010101 of database testdb
+
+-- Database creation
+
+SET client_encoding = 'UTF8';
+
+CREATE DATABASE "New DB" TEMPLATE template0;
+
+\connect "New DB"
+
+-- Session settings, set on every connection
+
+SET client_encoding = 'UTF8';
+
+-- Schemas
+
+CREATE SCHEMA sales;
+
+-- Pre-data objects
+
+CREATE TABLE sales.orders (
+	id integer
+) DISTRIBUTED BY (id);
+
+-- Post-data objects, first batch
+
+CREATE INDEX orders_idx ON sales.orders USING btree (id);
+`))
+		})
+		It("writes the data statements of a data-only restore as comments", func() {
+			restore.SetBackupConfig(&backup_history.BackupConfig{DatabaseName: "testdb", RestorePlan: []backup_history.RestorePlanEntry{
+				{Timestamp: "20170101010101", TableFQNs: []string{"sales.orders"}},
+			}})
+			cmdFlags.Set(utils.DATA_ONLY, "true")
+			cmdFlags.Set(utils.TRUNCATE_TABLE, "true")
+			toc.AddMasterDataEntry("sales", "orders", 16384, "(id)", 10, "", "")
+			toc.WriteToFileAndMakeReadOnly(fpInfo.GetTOCFilePath())
+
+			restore.PrintRestoreSQL(utils.NewFileWithByteCount(buffer))
+
+			Expect(string(buffer.Contents())).To(ContainSubstring(`\connect testdb`))
+			Expect(string(buffer.Contents())).ToNot(ContainSubstring("CREATE"))
+			Expect(string(buffer.Contents())).To(ContainSubstring(`
+-- Table data, restored by COPY on the segments
+
+-- Table sales.orders, 10 rows, from backup 20170101010101
+-- TRUNCATE TABLE sales.orders;
+-- COPY sales.orders(id) FROM PROGRAM 'cat <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_16384 | cat -' WITH CSV DELIMITER ',' ON SEGMENT;
+`))
+		})
+		It("writes objects redirected to another schema into that schema without creating it", func() {
+			restore.SetBackupConfig(&backup_history.BackupConfig{DatabaseName: "testdb", MetadataOnly: true})
+			restore.SetRedirectSchema("archive")
+
+			restore.PrintRestoreSQL(utils.NewFileWithByteCount(buffer))
+
+			Expect(string(buffer.Contents())).ToNot(ContainSubstring("CREATE SCHEMA"))
+			Expect(string(buffer.Contents())).To(ContainSubstring("CREATE TABLE archive.orders ("))
+			Expect(string(buffer.Contents())).To(ContainSubstring("CREATE INDEX orders_idx ON archive.orders USING btree (id);"))
+		})
+	})
+	Describe("PrintFailedStatements", func() {
+		It("writes each failed statement after a comment with its error", func() {
+			restore.SetFPInfo(backup_filepath.FilePathInfo{Timestamp: "20170101010101"})
+			gucStatements := []utils.StatementWithType{{ObjectType: "SESSION GUCS", Statement: "\nSET client_encoding = 'UTF8';\n"}}
+			statements := []utils.FailedStatement{
+				{Schema: "public", Name: "baz", ObjectType: "VIEW", Statement: "CREATE VIEW public.baz AS SELECT 1;", Error: "pq: permission denied for schema public", SQLState: "42501"},
+			}
+			dataStatements := []utils.FailedStatement{
+				{Schema: "public", Name: "foo", ObjectType: "TABLE DATA", Statement: "TRUNCATE TABLE public.foo;\nCOPY public.foo(i) FROM PROGRAM 'cat <SEG_DATA_DIR>/gpbackup_<SEGID>_20170101010101_16384' WITH CSV DELIMITER ',' ON SEGMENT;",
+					Error: "Expected to restore 10 rows to table public.foo, but restored 5 instead"},
+			}
+
+			restore.PrintFailedStatements(utils.NewFileWithByteCount(buffer), `"New DB"`, gucStatements, statements, dataStatements)
+
+			Expect(string(buffer.Contents())).To(Equal(`-- Statements that failed during the restore of backup 20170101010101
+
+\connect "New DB"
+
+-- Session settings
+
+SET client_encoding = 'UTF8';
+
+-- Failed statements
+
+-- VIEW public.baz
+-- Error (SQLSTATE 42501): pq: permission denied for schema public
+CREATE VIEW public.baz AS SELECT 1;
+
+-- Failed table data restores
+
+-- TABLE DATA public.foo
+-- Error: Expected to restore 10 rows to table public.foo, but restored 5 instead
+TRUNCATE TABLE public.foo;
+COPY public.foo(i) FROM PROGRAM 'cat <SEG_DATA_DIR>/gpbackup_<SEGID>_20170101010101_16384' WITH CSV DELIMITER ',' ON SEGMENT;
+`))
+		})
+	})
+})
@@ -0,0 +1,66 @@
+package restore
+
+/*
+ * This file contains functions related to restoring objects and table data
+ * to other schemas and tables than the ones they were backed up from.
+ */
+
+import (
+	"github.com/greenplum-db/gpbackup/utils"
+)
+
+/*
+ * The objects restored to another schema are the ones in the included
+ * schemas, the schemas of the included relations, or every schema in the
+ * backup that is not excluded, so references to objects in schemas that are
+ * not restored are left as they are.
+ */
+func GetRedirectedSchemas() []string {
+	if includeSchemas := MustGetFlagStringSlice(utils.INCLUDE_SCHEMA); len(includeSchemas) > 0 {
+		return includeSchemas
+	}
+	schemaSet := make(map[string]bool, 0)
+	schemas := make([]string, 0)
+	addSchema := func(schema string) {
+		if schema != "" && !schemaSet[schema] {
+			schemaSet[schema] = true
+			schemas = append(schemas, schema)
+		}
+	}
+	if includeRelations := MustGetFlagStringSlice(utils.INCLUDE_RELATION); len(includeRelations) > 0 {
+		for _, fqn := range includeRelations {
+			schema, _ := utils.SplitFQN(fqn)
+			addSchema(schema)
+		}
+		return schemas
+	}
+	excludedSchemaSet := utils.NewExcludeSet(MustGetFlagStringSlice(utils.EXCLUDE_SCHEMA))
+	for _, entry := range globalTOC.PredataEntries {
+		if excludedSchemaSet.MatchesFilter(entry.Schema) {
+			addSchema(entry.Schema)
+		}
+	}
+	return schemas
+}
+
+func RedirectRelations(relationList []string) []string {
+	if redirectSchema == "" && len(tableMap) == 0 {
+		return relationList
+	}
+	redirectedRelations := make([]string, 0, len(relationList))
+	for _, fqn := range relationList {
+		schema, name := RedirectTable(utils.SplitFQN(fqn))
+		redirectedRelations = append(redirectedRelations, utils.MakeFQN(schema, name))
+	}
+	return redirectedRelations
+}
+
+// Returns the schema and name of the table into which a table's data is restored
+func RedirectTable(schema string, name string) (string, string) {
+	if newFQN, ok := tableMap[utils.MakeFQN(schema, name)]; ok {
+		return utils.SplitFQN(newFQN)
+	} else if redirectSchema != "" {
+		return redirectSchema, name
+	}
+	return schema, name
+}
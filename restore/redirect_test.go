@@ -0,0 +1,28 @@
+package restore_test
+
+import (
+	"github.com/greenplum-db/gpbackup/restore"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("restore/redirect tests", func() {
+	Describe("RedirectRelations", func() {
+		AfterEach(func() {
+			restore.SetRedirectSchema("")
+		})
+		It("returns the relations unchanged if no schema is redirected", func() {
+			Expect(restore.RedirectRelations([]string{"sales.orders"})).To(Equal([]string{"sales.orders"}))
+		})
+		It("replaces each mapped relation with its new name", func() {
+			restore.SetTableMap(map[string]string{"sales.orders": "sales.orders_recovered"})
+			defer restore.SetTableMap(nil)
+			Expect(restore.RedirectRelations([]string{"sales.orders"})).To(Equal([]string{"sales.orders_recovered"}))
+		})
+		It("replaces the schema of each relation with the redirected schema", func() {
+			restore.SetRedirectSchema("archive")
+			Expect(restore.RedirectRelations([]string{"sales.orders", "public.customers"})).To(Equal([]string{"archive.orders", "archive.customers"}))
+		})
+	})
+})
@@ -31,7 +31,14 @@ func VerifyBackupDirectoriesExistOnAllHosts() {
 
 func VerifyBackupFileCountOnSegments(fileCount int) {
 	remoteOutput := globalCluster.GenerateAndExecuteCommand("Verifying backup file count", func(contentID int) string {
-		return fmt.Sprintf("find %s -type f | wc -l", globalFPInfo.GetDirForContent(contentID))
+		sourceDirs := make([]string, 0)
+		for _, sourceContentID := range GetSourceContentIDs(contentID) {
+			sourceDirs = append(sourceDirs, globalFPInfo.GetDirForContent(sourceContentID))
+		}
+		if len(sourceDirs) == 0 {
+			return "echo 0"
+		}
+		return fmt.Sprintf("find %s -type f | wc -l", strings.Join(sourceDirs, " "))
 	}, cluster.ON_SEGMENTS)
 	globalCluster.CheckClusterError(remoteOutput, "Could not verify backup file count", func(contentID int) string {
 		return "Could not verify backup file count"
@@ -40,8 +47,9 @@ func VerifyBackupFileCountOnSegments(fileCount int) {
 	numIncorrect := 0
 	for contentID := range remoteOutput.Stdouts {
 		numFound, _ := strconv.Atoi(strings.TrimSpace(remoteOutput.Stdouts[contentID]))
-		if numFound != fileCount {
-			gplog.Verbose("Expected to find %d file(s) on segment %d on host %s, but found %d instead.", fileCount, contentID, globalCluster.GetHostForContent(contentID), numFound)
+		expectedCount := fileCount * len(GetSourceContentIDs(contentID))
+		if numFound != expectedCount {
+			gplog.Verbose("Expected to find %d file(s) on segment %d on host %s, but found %d instead.", expectedCount, contentID, globalCluster.GetHostForContent(contentID), numFound)
 			numIncorrect++
 		}
 	}
@@ -51,7 +59,11 @@ func VerifyBackupFileCountOnSegments(fileCount int) {
 }
 
 func VerifyMetadataFilePaths(withStats bool) {
-	filetypes := []string{"config", "table of contents", "metadata"}
+	filetypes := []string{"config", "table of contents"}
+	isMetadataDirectory := backupConfig != nil && backupConfig.MetadataDirectory
+	if !isMetadataDirectory {
+		filetypes = append(filetypes, "metadata")
+	}
 	missing := false
 	for _, filetype := range filetypes {
 		filepath := globalFPInfo.GetBackupFilePath(filetype)
@@ -60,7 +72,14 @@ func VerifyMetadataFilePaths(withStats bool) {
 			gplog.Error("Cannot access %s file %s", filetype, filepath)
 		}
 	}
-	if withStats {
+	if isMetadataDirectory {
+		directory := globalFPInfo.GetMetadataDirectoryPath()
+		if !utils.MetadataDirectoryExists(directory) {
+			missing = true
+			gplog.Error("Cannot access metadata directory %s", directory)
+		}
+	}
+	if withStats && !isMetadataDirectory {
 		filepath := globalFPInfo.GetStatisticsFilePath()
 		if !iohelper.FileExistsAndIsReadable(filepath) {
 			missing = true
@@ -7,7 +7,9 @@ import (
 	"github.com/greenplum-db/gp-common-go-libs/operating"
 	"github.com/greenplum-db/gp-common-go-libs/testhelper"
 	"github.com/greenplum-db/gpbackup/backup_filepath"
+	"github.com/greenplum-db/gpbackup/backup_history"
 	"github.com/greenplum-db/gpbackup/restore"
+	"github.com/greenplum-db/gpbackup/utils"
 	"github.com/pkg/errors"
 
 	. "github.com/onsi/ginkgo"
@@ -43,6 +45,22 @@ var _ = Describe("restore/remote tests", func() {
 			restore.VerifyBackupFileCountOnSegments(2)
 			Expect((*testExecutor).NumExecutions).To(Equal(1))
 		})
+		It("counts the files of every source segment when restoring to a cluster with fewer segments", func() {
+			cmdFlags.Set(utils.RESIZE_CLUSTER, "true")
+			defer cmdFlags.Set(utils.RESIZE_CLUSTER, "false")
+			restore.SetBackupConfig(&backup_history.BackupConfig{SegmentCount: 3})
+			restore.SetFPInfo(backup_filepath.NewFilePathInfo(testCluster, "/backups", "20170101010101", "gpseg"))
+			testExecutor.ClusterOutput = &cluster.RemoteOutput{
+				Stdouts: map[int]string{
+					0: "4",
+					1: "2",
+				},
+			}
+			restore.SetCluster(testCluster)
+			restore.VerifyBackupFileCountOnSegments(2)
+			Expect(testExecutor.ClusterCommands[0][0]).To(ContainElement("find /backups/gpseg0/backups/20170101/20170101010101 /backups/gpseg2/backups/20170101/20170101010101 -type f | wc -l"))
+			Expect(testExecutor.ClusterCommands[0][1]).To(ContainElement("find /backups/gpseg1/backups/20170101/20170101010101 -type f | wc -l"))
+		})
 		It("panics if backup file counts do not match on all segments", func() {
 			testExecutor.ClusterOutput = &cluster.RemoteOutput{
 				Stdouts: map[int]string{
@@ -23,15 +23,17 @@ import (
 func initializeFlags(cmd *cobra.Command) {
 	SetFlagDefaults(cmd.Flags())
 
-	_ = cmd.MarkFlagRequired(utils.TIMESTAMP)
-
 	cmdFlags = cmd.Flags()
 }
 func SetFlagDefaults(flagSet *pflag.FlagSet) {
 	flagSet.String(utils.BACKUP_DIR, "", "The absolute path of the directory in which the backup files to be restored are located")
+	flagSet.String(utils.BEFORE, "", "With --label or --latest, restore the latest backup taken before this time, in the format YYYYMMDDHHMMSS")
+	flagSet.String(utils.CONFIG, "", "A YAML file containing values for any of these flags, which are used for flags not specified on the command line")
 	flagSet.Bool(utils.CREATE_DB, false, "Create the database before metadata restore")
 	flagSet.Bool(utils.DATA_ONLY, false, "Only restore data, do not restore metadata")
+	flagSet.String(utils.DBNAME, "", "With --label or --latest, restore the latest backup of this database")
 	flagSet.Bool(utils.DEBUG, false, "Print verbose and debug log messages")
+	flagSet.String(utils.EMAIL_CONFIG, "", "The configuration file listing the SMTP server and contacts to email when the restore completes")
 	flagSet.StringSlice(utils.EXCLUDE_SCHEMA, []string{}, "Restore all metadata except objects in the specified schema(s). --exclude-schema can be specified multiple times.")
 	flagSet.StringSlice(utils.EXCLUDE_RELATION, []string{}, "Restore all metadata except the specified relation(s). --exclude-table can be specified multiple times.")
 	flagSet.String(utils.EXCLUDE_RELATION_FILE, "", "A file containing a list of fully-qualified relation(s) that will not be restored")
@@ -39,16 +41,28 @@ func SetFlagDefaults(flagSet *pflag.FlagSet) {
 	flagSet.StringSlice(utils.INCLUDE_SCHEMA, []string{}, "Restore only the specified schema(s). --include-schema can be specified multiple times.")
 	flagSet.StringSlice(utils.INCLUDE_RELATION, []string{}, "Restore only the specified relation(s). --include-table can be specified multiple times.")
 	flagSet.String(utils.INCLUDE_RELATION_FILE, "", "A file containing a list of fully-qualified relation(s) that will be restored")
+	flagSet.Int(utils.MAX_RETRIES, 0, "Number of times to retry a metadata statement or the data restore of a table that fails with a transient error, such as a lost connection or a deadlock")
 	flagSet.Bool(utils.METADATA_ONLY, false, "Only restore metadata, do not restore data")
-	flagSet.Int(utils.JOBS, 1, "Number of parallel connections to use when restoring table data and post-data")
+	flagSet.String(utils.METRICS_FILE, "", "The absolute path of a file to which to write Prometheus metrics about the restore")
+	flagSet.Int(utils.JOBS, 1, "Number of parallel connections to use when restoring table data, pre-data, and post-data")
+	flagSet.StringArray(utils.LABEL, []string{}, "Restore the latest successful backup with this label, in the format key=value, instead of specifying --timestamp. --label can be specified multiple times.")
+	flagSet.Bool(utils.LATEST, false, "Restore the latest successful backup instead of specifying --timestamp")
+	flagSet.String(utils.NOTIFICATION_CONFIG, "", "The configuration file listing webhooks to notify when the restore completes")
 	flagSet.Bool(utils.ON_ERROR_CONTINUE, false, "Log errors and continue restore, instead of exiting on first error")
 	flagSet.String(utils.PLUGIN_CONFIG, "", "The configuration file to use for a plugin")
+	flagSet.String(utils.PRINT_SQL, "", "Write the statements that the restore would execute to the specified file instead of restoring, without connecting to the database")
 	flagSet.Bool("version", false, "Print version number and exit")
 	flagSet.Bool(utils.QUIET, false, "Suppress non-warning, non-error log messages")
 	flagSet.String(utils.REDIRECT_DB, "", "Restore to the specified database instead of the database that was backed up")
+	flagSet.String(utils.REDIRECT_SCHEMA, "", "Restore all relations and other schema objects to the specified existing schema instead of their original schemas")
+	flagSet.Bool(utils.RESIZE_CLUSTER, false, "Restore a backup taken on a cluster with a different number of segments.  Requires --backup-dir.")
 	flagSet.Bool(utils.WITH_GLOBALS, false, "Restore global metadata")
+	flagSet.String(utils.TABLE_MAP_FILE, "", "A file mapping the fully-qualified tables to restore to new names, one \"schema.table -> schema.newtable\" per line")
 	flagSet.String(utils.TIMESTAMP, "", "The timestamp to be restored, in the format YYYYMMDDHHMMSS")
+	flagSet.Bool(utils.TRUNCATE_TABLE, false, "With --data-only, truncate each table before restoring its data")
+	flagSet.Bool(utils.UPSERT, false, "With --data-only, replace the rows of each table that have the same primary key as a restored row instead of appending duplicates")
 	flagSet.Bool(utils.VERBOSE, false, "Print verbose log messages")
+	flagSet.Bool(utils.WITH_DEPENDENCIES, false, "With --include-table, --include-table-file, or --table-map-file, also restore the metadata of the types, functions, sequences, and other objects that the included relations depend on")
 	flagSet.Bool(utils.WITH_STATS, false, "Restore query plan statistics")
 }
 
@@ -66,14 +80,33 @@ func DoInit(cmd *cobra.Command) {
 * It should only validate; initialization with any sort of side effects should go in DoInit or DoSetup.
  */
 func DoValidation(cmd *cobra.Command) {
+	if configFile := MustGetFlagString(utils.CONFIG); configFile != "" {
+		err := utils.ApplyConfigFile(cmd.Flags(), configFile)
+		gplog.FatalOnError(err)
+	}
 	ValidateFlagCombinations(cmd.Flags())
 	err := utils.ValidateFullPath(MustGetFlagString(utils.BACKUP_DIR))
 	gplog.FatalOnError(err)
 	err = utils.ValidateFullPath(MustGetFlagString(utils.PLUGIN_CONFIG))
 	gplog.FatalOnError(err)
-	if !backup_filepath.IsValidTimestamp(MustGetFlagString(utils.TIMESTAMP)) {
+	err = utils.ValidateFullPath(MustGetFlagString(utils.METRICS_FILE))
+	gplog.FatalOnError(err)
+	if MustGetFlagString(utils.NOTIFICATION_CONFIG) != "" {
+		_, err = utils.ReadNotificationConfig(MustGetFlagString(utils.NOTIFICATION_CONFIG))
+		gplog.FatalOnError(err)
+	}
+	if MustGetFlagString(utils.EMAIL_CONFIG) != "" {
+		_, err = utils.ReadEmailConfig(MustGetFlagString(utils.EMAIL_CONFIG))
+		gplog.FatalOnError(err)
+	}
+	if cmd.Flags().Changed(utils.TIMESTAMP) && !backup_filepath.IsValidTimestamp(MustGetFlagString(utils.TIMESTAMP)) {
 		gplog.Fatal(errors.Errorf("Timestamp %s is invalid.  Timestamps must be in the format YYYYMMDDHHMMSS.", MustGetFlagString(utils.TIMESTAMP)), "")
 	}
+	if cmd.Flags().Changed(utils.BEFORE) && !backup_filepath.IsValidTimestamp(MustGetFlagString(utils.BEFORE)) {
+		gplog.Fatal(errors.Errorf("Timestamp %s is invalid.  Timestamps must be in the format YYYYMMDDHHMMSS.", MustGetFlagString(utils.BEFORE)), "")
+	}
+	_, err = utils.ParseLabels(MustGetFlagStringArray(utils.LABEL))
+	gplog.FatalOnError(err)
 }
 
 // This function handles setup that must be done after parsing flags.
@@ -81,11 +114,14 @@ func DoSetup() {
 	SetLoggerVerbosity()
 	utils.CheckGpexpandRunning(utils.RestorePreventedByGpexpandMessage)
 	restoreStartTime = utils.CurrentTimestamp()
-	gplog.Info("Restore Key = %s", MustGetFlagString(utils.TIMESTAMP))
 
 	InitializeConnectionPool("postgres")
 	segConfig := cluster.MustGetSegmentConfiguration(connectionPool)
 	globalCluster = cluster.NewCluster(segConfig)
+	if MustGetFlagString(utils.TIMESTAMP) == "" {
+		SetTimestampFromBackupHistory()
+	}
+	gplog.Info("Restore Key = %s", MustGetFlagString(utils.TIMESTAMP))
 	segPrefix := backup_filepath.ParseSegPrefix(MustGetFlagString(utils.BACKUP_DIR), MustGetFlagString(utils.TIMESTAMP))
 	globalFPInfo = backup_filepath.NewFilePathInfo(globalCluster, MustGetFlagString(utils.BACKUP_DIR), MustGetFlagString(utils.TIMESTAMP), segPrefix)
 
@@ -94,12 +130,17 @@ func DoSetup() {
 		RecoverMetadataFilesUsingPlugin()
 	} else {
 		InitializeBackupConfig()
+		ValidateIncrementalRestorePlan()
 	}
 
 	BackupConfigurationValidation()
 	metadataFilename := globalFPInfo.GetMetadataFilePath()
 	if !backupConfig.DataOnly {
-		gplog.Verbose("Metadata will be restored from %s", metadataFilename)
+		if backupConfig.MetadataDirectory {
+			gplog.Verbose("Metadata will be restored from %s", globalFPInfo.GetMetadataDirectoryPath())
+		} else {
+			gplog.Verbose("Metadata will be restored from %s", metadataFilename)
+		}
 	}
 	unquotedRestoreDatabase := utils.UnquoteIdent(backupConfig.DatabaseName)
 	if MustGetFlagString(utils.REDIRECT_DB) != "" {
@@ -115,6 +156,10 @@ func DoSetup() {
 		connectionPool.Close()
 	}
 	InitializeConnectionPool(unquotedRestoreDatabase)
+	if unquotedRedirectSchema := MustGetFlagString(utils.REDIRECT_SCHEMA); unquotedRedirectSchema != "" {
+		ValidateRedirectSchema(connectionPool, unquotedRedirectSchema)
+		redirectSchema = utils.QuoteIdentWithoutConnection(unquotedRedirectSchema)
+	}
 
 	/*
 	 * We don't need to validate anything if we're creating the database; we
@@ -123,7 +168,7 @@ func DoSetup() {
 	 * but since they will not stop the restore, it is not necessary to log them twice.
 	 */
 	if !MustGetFlagBool(utils.CREATE_DB) && !MustGetFlagBool(utils.ON_ERROR_CONTINUE) {
-		relationsToRestore := GenerateRestoreRelationList()
+		relationsToRestore := RedirectRelations(GenerateRestoreRelationList())
 		ValidateRelationsInRestoreDatabase(connectionPool, relationsToRestore)
 	}
 }
@@ -134,7 +179,7 @@ func DoRestore() {
 	isDataOnly := backupConfig.DataOnly || MustGetFlagBool(utils.DATA_ONLY)
 	isMetadataOnly := backupConfig.MetadataOnly || MustGetFlagBool(utils.METADATA_ONLY)
 	if !isDataOnly {
-		restorePredata(metadataFilename)
+		restorePredata(metadataFilename, gucStatements)
 	}
 
 	if !isMetadataOnly {
@@ -158,49 +203,71 @@ func DoRestore() {
 }
 
 func createDatabase(metadataFilename string) {
-	objectTypes := []string{"SESSION GUCS", "DATABASE GUC", "DATABASE", "DATABASE METADATA"}
 	dbName := backupConfig.DatabaseName
-	gplog.Info("Creating database")
-	statements := GetRestoreMetadataStatements("global", metadataFilename, objectTypes, []string{}, false, false)
+	quotedRedirectDB := ""
 	if MustGetFlagString(utils.REDIRECT_DB) != "" {
-		quotedDBName := utils.QuoteIdent(connectionPool, MustGetFlagString(utils.REDIRECT_DB))
-		dbName = quotedDBName
-		statements = utils.SubstituteRedirectDatabaseInStatements(statements, backupConfig.DatabaseName, quotedDBName)
+		quotedRedirectDB = utils.QuoteIdentWithoutConnection(MustGetFlagString(utils.REDIRECT_DB))
+		dbName = quotedRedirectDB
 	}
+	gplog.Info("Creating database")
+	statements := getCreateDatabaseStatements(metadataFilename, quotedRedirectDB)
 	ExecuteRestoreMetadataStatements(statements, "", nil, utils.PB_NONE, false)
 	gplog.Info("Database creation complete for: %s", dbName)
 }
 
+func getCreateDatabaseStatements(metadataFilename string, quotedRedirectDB string) []utils.StatementWithType {
+	objectTypes := []string{"SESSION GUCS", "DATABASE GUC", "DATABASE", "DATABASE METADATA"}
+	statements := GetRestoreMetadataStatements("global", metadataFilename, objectTypes, []string{}, false, false)
+	if quotedRedirectDB != "" {
+		statements = utils.SubstituteRedirectDatabaseInStatements(statements, backupConfig.DatabaseName, quotedRedirectDB)
+	}
+	return statements
+}
+
 func restoreGlobal(metadataFilename string) {
+	quotedRedirectDB := ""
+	if MustGetFlagString(utils.REDIRECT_DB) != "" {
+		quotedRedirectDB = utils.QuoteIdentWithoutConnection(MustGetFlagString(utils.REDIRECT_DB))
+	}
+	gplog.Info("Restoring global metadata")
+	statements := getGlobalStatements(metadataFilename, quotedRedirectDB, connectionPool.User)
+	ExecuteRestoreMetadataStatements(statements, "Global objects", nil, utils.PB_VERBOSE, false)
+	gplog.Info("Global database metadata restore complete")
+}
+
+func getGlobalStatements(metadataFilename string, quotedRedirectDB string, activeUser string) []utils.StatementWithType {
 	objectTypes := []string{"SESSION GUCS", "DATABASE GUC", "DATABASE METADATA", "RESOURCE QUEUE", "RESOURCE GROUP", "ROLE", "ROLE GUCS", "ROLE GRANT", "TABLESPACE"}
 	if MustGetFlagBool(utils.CREATE_DB) {
 		objectTypes = append(objectTypes, "DATABASE")
 	}
-	gplog.Info("Restoring global metadata")
 	statements := GetRestoreMetadataStatements("global", metadataFilename, objectTypes, []string{}, false, false)
-	if MustGetFlagString(utils.REDIRECT_DB) != "" {
-		quotedDBName := utils.QuoteIdent(connectionPool, MustGetFlagString(utils.REDIRECT_DB))
-		statements = utils.SubstituteRedirectDatabaseInStatements(statements, backupConfig.DatabaseName, quotedDBName)
+	if quotedRedirectDB != "" {
+		statements = utils.SubstituteRedirectDatabaseInStatements(statements, backupConfig.DatabaseName, quotedRedirectDB)
 	}
-	statements = utils.RemoveActiveRole(connectionPool.User, statements)
-	ExecuteRestoreMetadataStatements(statements, "Global objects", nil, utils.PB_VERBOSE, false)
-	gplog.Info("Global database metadata restore complete")
+	return utils.RemoveActiveRole(activeUser, statements)
 }
 
-func restorePredata(metadataFilename string) {
+func restorePredata(metadataFilename string, gucStatements []utils.StatementWithType) {
 	if wasTerminated {
 		return
 	}
 	gplog.Info("Restoring pre-data metadata")
 
-	schemaStatements := GetRestoreMetadataStatements("predata", metadataFilename, []string{"SCHEMA"}, []string{}, true, false)
-	statements := GetRestoreMetadataStatements("predata", metadataFilename, []string{}, []string{"SCHEMA"}, true, true)
+	schemaStatements, statements := getPredataStatements(metadataFilename)
 
 	progressBar := utils.NewProgressBar(len(schemaStatements)+len(statements), "Pre-data objects restored: ", utils.PB_VERBOSE)
 	progressBar.Start()
 
 	RestoreSchemas(schemaStatements, progressBar)
-	ExecuteRestoreMetadataStatements(statements, "Pre-data objects", progressBar, utils.PB_VERBOSE, false)
+	if connectionPool.NumConns > 1 {
+		// The session GUCs have only been set on the first connection so far
+		for whichConn := 1; whichConn < connectionPool.NumConns; whichConn++ {
+			setGUCsForConnection(gucStatements, whichConn)
+		}
+		ExecuteStatementsWithDependencies(statements, progressBar)
+	} else {
+		ExecuteRestoreMetadataStatements(statements, "Pre-data objects", progressBar, utils.PB_VERBOSE, false)
+	}
 
 	progressBar.Finish()
 	if wasTerminated {
@@ -210,24 +277,32 @@ func restorePredata(metadataFilename string) {
 	}
 }
 
+func getPredataStatements(metadataFilename string) ([]utils.StatementWithType, []utils.StatementWithType) {
+	// Objects redirected to another schema are created in that existing schema instead
+	schemaStatements := make([]utils.StatementWithType, 0)
+	if redirectSchema == "" {
+		schemaStatements = GetRestoreMetadataStatements("predata", metadataFilename, []string{"SCHEMA"}, []string{}, true, false)
+	}
+	statements := GetRestoreMetadataStatements("predata", metadataFilename, []string{}, []string{"SCHEMA"}, true, true)
+	return schemaStatements, statements
+}
+
 func restoreData(fpInfoList []backup_filepath.FilePathInfo, gucStatements []utils.StatementWithType) {
 	if wasTerminated {
 		return
 	}
-	latestRestorePlan := backupConfig.RestorePlan
-
 	totalTables := 0
-	filteredDataEntries := make([][]utils.MasterDataEntry, 0)
-	for i, fpInfo := range fpInfoList {
-		tocFilename := fpInfo.GetTOCFilePath()
-		toc := utils.NewTOC(tocFilename)
-		restorePlanTableFQNs := latestRestorePlan[i].TableFQNs
-		filteredDataEntriesForTimestamp := toc.GetDataEntriesMatching(MustGetFlagStringSlice(utils.INCLUDE_SCHEMA),
-			MustGetFlagStringSlice(utils.EXCLUDE_SCHEMA), MustGetFlagStringSlice(utils.INCLUDE_RELATION),
-			MustGetFlagStringSlice(utils.EXCLUDE_RELATION), restorePlanTableFQNs)
-		filteredDataEntries = append(filteredDataEntries, filteredDataEntriesForTimestamp)
-
-		totalTables += len(filteredDataEntriesForTimestamp)
+	filteredDataEntries := getFilteredDataEntries(fpInfoList)
+	restoredTableFQNs := make([]string, 0)
+	for _, dataEntries := range filteredDataEntries {
+		totalTables += len(dataEntries)
+		for _, entry := range dataEntries {
+			restoredTableFQNs = append(restoredTableFQNs, utils.MakeFQN(RedirectTable(entry.Schema, entry.Name)))
+		}
+	}
+	ValidateNoReplicatedTables(connectionPool, restoredTableFQNs)
+	if backupConfig.WithChecksums && !shouldVerifyChecksums() {
+		gplog.Info("Table checksums will not be verified, as the restored tables may already contain data")
 	}
 	dataProgressBar := utils.NewProgressBar(totalTables, "Tables restored: ", utils.PB_INFO)
 	dataProgressBar.Start()
@@ -238,6 +313,7 @@ func restoreData(fpInfoList []backup_filepath.FilePathInfo, gucStatements []util
 	}
 
 	dataProgressBar.Finish()
+	tableDataReports.SetTableSizes(utils.GetTableSizesByName(connectionPool, tableDataReports.GetTables()))
 	if wasTerminated {
 		gplog.Info("Data restore incomplete")
 	} else {
@@ -245,6 +321,21 @@ func restoreData(fpInfoList []backup_filepath.FilePathInfo, gucStatements []util
 	}
 }
 
+func getFilteredDataEntries(fpInfoList []backup_filepath.FilePathInfo) [][]utils.MasterDataEntry {
+	latestRestorePlan := backupConfig.RestorePlan
+	filteredDataEntries := make([][]utils.MasterDataEntry, 0)
+	for i, fpInfo := range fpInfoList {
+		tocFilename := fpInfo.GetTOCFilePath()
+		toc := utils.NewTOC(tocFilename)
+		restorePlanTableFQNs := latestRestorePlan[i].TableFQNs
+		filteredDataEntriesForTimestamp := toc.GetDataEntriesMatching(MustGetFlagStringSlice(utils.INCLUDE_SCHEMA),
+			MustGetFlagStringSlice(utils.EXCLUDE_SCHEMA), MustGetFlagStringSlice(utils.INCLUDE_RELATION),
+			MustGetFlagStringSlice(utils.EXCLUDE_RELATION), restorePlanTableFQNs)
+		filteredDataEntries = append(filteredDataEntries, filteredDataEntriesForTimestamp)
+	}
+	return filteredDataEntries
+}
+
 func restorePostdata(metadataFilename string) {
 	if wasTerminated {
 		return
@@ -312,15 +403,40 @@ func DoTeardown() {
 	}
 	errMsg := utils.ParseErrorMessage(errStr)
 
-	if globalFPInfo.Timestamp != "" {
+	// A restore that only writes its SQL to a file does not write report files
+	if globalFPInfo.Timestamp != "" && MustGetFlagString(utils.PRINT_SQL) == "" {
 		_, statErr := os.Stat(globalFPInfo.GetDirForContent(-1))
 		if statErr != nil { // Even if this isn't os.IsNotExist, don't try to write a report file in case of further errors
 			return
 		}
 
 		reportFilename := globalFPInfo.GetRestoreReportFilePath(restoreStartTime)
-		utils.WriteRestoreReportFile(reportFilename, globalFPInfo.Timestamp, restoreStartTime, connectionPool, version, errMsg)
-		utils.EmailReport(globalCluster, globalFPInfo.Timestamp, reportFilename, "gprestore")
+		var effectiveFlags []string
+		if MustGetFlagString(utils.CONFIG) != "" {
+			effectiveFlags = utils.GetEffectiveFlags(cmdFlags)
+		}
+		errorsFilename := ""
+		if len(failedStatements.GetStatements()) > 0 || len(failedDataStatements.GetStatements()) > 0 {
+			errorsFilename = globalFPInfo.GetRestoreErrorsFilePath(restoreStartTime)
+			writeErrorsFile(errorsFilename)
+		}
+		utils.WriteRestoreReportFile(reportFilename, globalFPInfo.Timestamp, restoreStartTime, connectionPool, version, effectiveFlags,
+			tableDataReports.GetTables(), failedStatements.GetStatements(), errorsFilename, errMsg)
+		jsonReport := utils.ConstructRestoreJSONReport(backupConfig, globalFPInfo.Timestamp, restoreStartTime, connectionPool, version, effectiveFlags,
+			tableDataReports.GetTables(), failedStatements.GetStatements(), errMsg)
+		jsonReportFilename := globalFPInfo.GetRestoreJSONReportFilePath(restoreStartTime)
+		utils.WriteJSONReportFile(jsonReportFilename, jsonReport)
+		if metricsFilename := MustGetFlagString(utils.METRICS_FILE); metricsFilename != "" {
+			utils.WriteMetricsFile(metricsFilename, utils.ConstructRestoreMetrics(metricsFilename, jsonReport))
+		}
+		if emailConfig := MustGetFlagString(utils.EMAIL_CONFIG); emailConfig != "" {
+			utils.SendEmailReport(emailConfig, "gprestore", globalFPInfo.Timestamp, jsonReport.Status, reportFilename, jsonReportFilename)
+		} else {
+			utils.EmailReport(globalCluster, globalFPInfo.Timestamp, reportFilename, "gprestore")
+		}
+		if notificationConfig := MustGetFlagString(utils.NOTIFICATION_CONFIG); notificationConfig != "" {
+			utils.SendWebhookNotifications(notificationConfig, "gprestore", globalFPInfo.Timestamp, jsonReport.Status, jsonReport)
+		}
 		if pluginConfig != nil {
 			pluginConfig.CleanupPluginForRestore(globalCluster, globalFPInfo)
 		}
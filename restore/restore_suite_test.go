@@ -60,7 +60,17 @@ var _ = BeforeEach(func() {
 
 	cmdFlags.Bool(utils.ON_ERROR_CONTINUE, false, "")
 	cmdFlags.Bool(utils.DATA_ONLY, false, "")
+	cmdFlags.Bool(utils.METADATA_ONLY, false, "")
+	cmdFlags.Bool(utils.RESIZE_CLUSTER, false, "")
+	cmdFlags.Bool(utils.CREATE_DB, false, "")
+	cmdFlags.Bool(utils.WITH_GLOBALS, false, "")
+	cmdFlags.Bool(utils.WITH_STATS, false, "")
+	cmdFlags.Bool(utils.TRUNCATE_TABLE, false, "")
+	cmdFlags.Bool(utils.UPSERT, false, "")
+	cmdFlags.Int(utils.MAX_RETRIES, 0, "")
 	cmdFlags.String(utils.PLUGIN_CONFIG, "", "")
+	cmdFlags.String(utils.BACKUP_DIR, "", "")
+	cmdFlags.String(utils.REDIRECT_DB, "", "")
 	cmdFlags.StringSlice(utils.INCLUDE_RELATION, []string{}, "")
 	cmdFlags.StringSlice(utils.EXCLUDE_RELATION, []string{}, "")
 	cmdFlags.StringSlice(utils.INCLUDE_SCHEMA, []string{}, "")
@@ -0,0 +1,101 @@
+package restore
+
+/*
+ * This file contains functions related to retrying statements that fail
+ * because of transient errors, such as lost connections and deadlocks.
+ */
+
+import (
+	"database/sql/driver"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+const maxRetryDelay = time.Minute
+
+/*
+ * Deadlocks, serialization failures, and lock timeouts are reported by the
+ * server with a SQLSTATE.  A lost connection is reported with a class 08
+ * SQLSTATE if the server noticed it first, and otherwise by the driver.
+ */
+var transientErrorCodes = []pq.ErrorCode{"40001", "40P01", "55P03"}
+
+func isConnectionError(err error) bool {
+	err = errors.Cause(err)
+	if pqErr, ok := err.(*pq.Error); ok {
+		return strings.HasPrefix(string(pqErr.Code), "08")
+	}
+	if _, ok := err.(net.Error); ok {
+		return true
+	}
+	return err == driver.ErrBadConn || err == io.EOF || err == io.ErrUnexpectedEOF
+}
+
+func IsTransientError(err error) bool {
+	if isConnectionError(err) {
+		return true
+	}
+	if pqErr, ok := errors.Cause(err).(*pq.Error); ok {
+		for _, code := range transientErrorCodes {
+			if pqErr.Code == code {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/*
+ * Calls run until it succeeds, fails with an error that is not transient, or
+ * has been retried --max-retries times, waiting twice as long before each
+ * retry as before the last one.  When the connection was lost, database/sql
+ * opens a new connection for the next statement, so resetSession is called
+ * before retrying to set the session settings of the lost connection again.
+ */
+func RetryOnTransientError(description string, run func() error, resetSession func() error) error {
+	err := run()
+	maxRetries := MustGetFlagInt(utils.MAX_RETRIES)
+	delay := retryDelay
+	for retry := 1; err != nil && retry <= maxRetries && IsTransientError(err) && !wasTerminated; retry++ {
+		gplog.Warn("Transient error %s, retrying in %s (retry %d of %d): %s", description, delay, retry, maxRetries, err.Error())
+		time.Sleep(delay)
+		delay *= 2
+		if delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+		if isConnectionError(err) {
+			if resetErr := resetSession(); resetErr != nil {
+				return errors.Wrap(resetErr, "Error resetting session after lost connection")
+			}
+		}
+		err = run()
+	}
+	return err
+}
+
+/*
+ * Sets the settings that every restore connection has, followed by the
+ * session GUCs recorded in the backup.
+ */
+func resetSessionForConnection(whichConn int) error {
+	_, err := connectionPool.Exec(getConnectionSetupQuery(), whichConn)
+	if err != nil {
+		return err
+	}
+	objectTypes := []string{"SESSION GUCS"}
+	gucStatements := GetRestoreMetadataStatements("global", globalFPInfo.GetMetadataFilePath(), objectTypes, []string{}, false, false)
+	for _, statement := range gucStatements {
+		_, err = connectionPool.Exec(statement.Statement, whichConn)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package restore_test
+
+import (
+	"errors"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/greenplum-db/gpbackup/restore"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/lib/pq"
+	pkgerrors "github.com/pkg/errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+var _ = Describe("restore/retry tests", func() {
+	deadlock := &pq.Error{Code: "40P01", Message: "deadlock detected"}
+	connectionFailure := &pq.Error{Code: "08006", Message: "connection failure"}
+	undefinedTable := &pq.Error{Code: "42P01", Message: "relation does not exist"}
+	Describe("IsTransientError", func() {
+		It("returns true for deadlocks, serialization failures, and lock timeouts", func() {
+			Expect(restore.IsTransientError(deadlock)).To(BeTrue())
+			Expect(restore.IsTransientError(&pq.Error{Code: "40001"})).To(BeTrue())
+			Expect(restore.IsTransientError(&pq.Error{Code: "55P03"})).To(BeTrue())
+		})
+		It("returns true for lost connections", func() {
+			Expect(restore.IsTransientError(connectionFailure)).To(BeTrue())
+			Expect(restore.IsTransientError(io.ErrUnexpectedEOF)).To(BeTrue())
+		})
+		It("returns true for transient errors wrapped with context", func() {
+			Expect(restore.IsTransientError(pkgerrors.Wrap(deadlock, "Error loading data into table public.foo"))).To(BeTrue())
+		})
+		It("returns false for other errors", func() {
+			Expect(restore.IsTransientError(undefinedTable)).To(BeFalse())
+			Expect(restore.IsTransientError(errors.New("permission denied"))).To(BeFalse())
+		})
+	})
+	Describe("RetryOnTransientError", func() {
+		var (
+			numRuns   int
+			numResets int
+			runErrors []error
+		)
+		run := func() error {
+			numRuns++
+			if numRuns <= len(runErrors) {
+				return runErrors[numRuns-1]
+			}
+			return nil
+		}
+		resetSession := func() error {
+			numResets++
+			return nil
+		}
+		BeforeEach(func() {
+			_, _, logfile = testhelper.SetupTestLogger()
+			numRuns, numResets = 0, 0
+			restore.SetRetryDelay(0)
+			cmdFlags.Set(utils.MAX_RETRIES, "2")
+		})
+		AfterEach(func() {
+			cmdFlags.Set(utils.MAX_RETRIES, "0")
+			restore.SetRetryDelay(time.Second)
+		})
+		It("does not retry by default", func() {
+			cmdFlags.Set(utils.MAX_RETRIES, "0")
+			runErrors = []error{deadlock}
+			err := restore.RetryOnTransientError("restoring data of table public.foo", run, resetSession)
+			Expect(err).To(Equal(deadlock))
+			Expect(numRuns).To(Equal(1))
+		})
+		It("retries until the function succeeds", func() {
+			runErrors = []error{deadlock, deadlock}
+			err := restore.RetryOnTransientError("restoring data of table public.foo", run, resetSession)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(numRuns).To(Equal(3))
+			Expect(numResets).To(Equal(0))
+			testhelper.ExpectRegexp(logfile, "[WARNING]:-Transient error restoring data of table public.foo, retrying in 0s (retry 1 of 2): pq: deadlock detected")
+		})
+		It("returns the last error once the retries are used up", func() {
+			runErrors = []error{deadlock, deadlock, connectionFailure}
+			err := restore.RetryOnTransientError("restoring data of table public.foo", run, resetSession)
+			Expect(err).To(Equal(connectionFailure))
+			Expect(numRuns).To(Equal(3))
+		})
+		It("does not retry errors that are not transient", func() {
+			runErrors = []error{undefinedTable}
+			err := restore.RetryOnTransientError("restoring data of table public.foo", run, resetSession)
+			Expect(err).To(Equal(undefinedTable))
+			Expect(numRuns).To(Equal(1))
+		})
+		It("resets the session before retrying after a lost connection", func() {
+			runErrors = []error{connectionFailure}
+			err := restore.RetryOnTransientError("restoring data of table public.foo", run, resetSession)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(numRuns).To(Equal(2))
+			Expect(numResets).To(Equal(1))
+		})
+		It("stops retrying if the session cannot be reset", func() {
+			runErrors = []error{connectionFailure}
+			err := restore.RetryOnTransientError("restoring data of table public.foo", run, func() error {
+				return errors.New("connection refused")
+			})
+			Expect(err).To(MatchError("Error resetting session after lost connection: connection refused"))
+			Expect(numRuns).To(Equal(1))
+		})
+	})
+	Describe("ExecuteStatements", func() {
+		AfterEach(func() {
+			cmdFlags.Set(utils.MAX_RETRIES, "0")
+			restore.SetRetryDelay(time.Second)
+		})
+		It("retries a statement that fails with a transient error", func() {
+			_, _, logfile = testhelper.SetupTestLogger()
+			restore.SetRetryDelay(0)
+			cmdFlags.Set(utils.MAX_RETRIES, "1")
+			statement := utils.StatementWithType{ObjectType: "TABLE", Schema: "public", Name: "foo", Statement: "CREATE TABLE public.foo (i int);"}
+			mock.ExpectExec(regexp.QuoteMeta(statement.Statement)).WillReturnError(deadlock)
+			mock.ExpectExec(regexp.QuoteMeta(statement.Statement)).WillReturnResult(sqlmock.NewResult(0, 0))
+
+			restore.ExecuteStatements([]utils.StatementWithType{statement}, utils.NewProgressBar(1, "", utils.PB_NONE), false)
+
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+			testhelper.ExpectRegexp(logfile, "[WARNING]:-Transient error executing statement for TABLE public.foo, retrying in 0s (retry 1 of 1): pq: deadlock detected")
+		})
+	})
+})
@@ -7,6 +7,7 @@ import (
 
 	"github.com/greenplum-db/gp-common-go-libs/dbconn"
 	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gpbackup/backup_filepath"
 	"github.com/greenplum-db/gpbackup/utils"
 	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
@@ -196,6 +197,95 @@ func getFilterRelationsInBackupSet(relationList []string) []string {
 	return keys
 }
 
+/*
+ * An incremental backup is restored using every backup in its restore plan,
+ * so the whole chain is checked before anything is restored rather than
+ * failing partway through the data restore.
+ */
+func ValidateIncrementalRestorePlan() {
+	if !backupConfig.Incremental || MustGetFlagBool(utils.METADATA_ONLY) {
+		return
+	}
+	fpInfoList := make([]backup_filepath.FilePathInfo, 0)
+	for _, entry := range backupConfig.RestorePlan {
+		fpInfoList = append(fpInfoList, backup_filepath.NewFilePathInfo(globalCluster, globalFPInfo.UserSpecifiedBackupDir,
+			entry.Timestamp, globalFPInfo.UserSpecifiedSegPrefix))
+	}
+	// Data files are counted per source segment below when resizing the cluster
+	problems := utils.ValidateBackupChain(globalCluster, fpInfoList, pluginConfig, !MustGetFlagBool(utils.RESIZE_CLUSTER))
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			gplog.Error(problem)
+		}
+		gplog.Fatal(errors.Errorf("Incremental backup chain of backup %s is incomplete", globalFPInfo.Timestamp), "Cannot proceed with restore")
+	}
+}
+
+/*
+ * Data files are written per segment, so a backup can only be restored to a
+ * cluster with a different number of segments using --resize-cluster.
+ * Backups taken before the number of segments was recorded are assumed to
+ * match the cluster.
+ */
+func ValidateSegmentCount() {
+	targetSegmentCount := len(globalCluster.ContentIDs) - 1
+	if !MustGetFlagBool(utils.RESIZE_CLUSTER) {
+		if backupConfig.SegmentCount != 0 && backupConfig.SegmentCount != targetSegmentCount && !backupConfig.MetadataOnly && !MustGetFlagBool(utils.METADATA_ONLY) {
+			gplog.Fatal(errors.Errorf("Backup %s was taken on a cluster with %d segments, but the restore cluster has %d segments", globalFPInfo.Timestamp, backupConfig.SegmentCount, targetSegmentCount),
+				"Use --resize-cluster to restore the backup to this cluster")
+		}
+		return
+	}
+	if backupConfig.SegmentCount == 0 {
+		gplog.Fatal(errors.Errorf("Backup %s does not record the number of segments in its cluster and cannot be restored with --resize-cluster", globalFPInfo.Timestamp), "")
+	}
+	if backupConfig.SingleDataFile {
+		gplog.Fatal(errors.Errorf("Cannot use --resize-cluster with a backup taken with --single-data-file"), "")
+	}
+}
+
+/*
+ * Every source segment backs up a full copy of a replicated table, so loading
+ * the files of several source segments into one segment would duplicate its
+ * rows, and a segment with no source segment would load none.  Replicated
+ * tables were added in GPDB 6, so only a GPDB 6 cluster can have them.
+ */
+func ValidateNoReplicatedTables(connectionPool *dbconn.DBConn, tableFQNs []string) {
+	if !MustGetFlagBool(utils.RESIZE_CLUSTER) || connectionPool.Version.Before("6") {
+		return
+	}
+	query := `
+SELECT
+	quote_ident(n.nspname) || '.' || quote_ident(c.relname) AS string
+FROM gp_distribution_policy p
+JOIN pg_class c ON p.localoid = c.oid
+JOIN pg_namespace n ON c.relnamespace = n.oid
+WHERE p.policytype = 'r'`
+	replicatedTableSet := utils.NewIncludeSet(dbconn.MustSelectStringSlice(connectionPool, query))
+	replicatedTables := make([]string, 0)
+	for _, fqn := range tableFQNs {
+		if replicatedTableSet.MatchesFilter(fqn) {
+			replicatedTables = append(replicatedTables, fqn)
+		}
+	}
+	if len(replicatedTables) > 0 {
+		gplog.Fatal(errors.Errorf("Cannot use --resize-cluster to restore replicated tables: %s", strings.Join(replicatedTables, ", ")), "")
+	}
+}
+
+func ValidateRedirectSchema(connectionPool *dbconn.DBConn, unquotedSchemaName string) {
+	query := fmt.Sprintf(`
+SELECT CASE
+	WHEN EXISTS (SELECT 1 FROM pg_namespace WHERE nspname='%s') THEN 'true'
+	ELSE 'false'
+END AS string;`, utils.EscapeSingleQuotes(unquotedSchemaName))
+	schemaExists, err := strconv.ParseBool(dbconn.MustSelectString(connectionPool, query))
+	gplog.FatalOnError(err)
+	if !schemaExists {
+		gplog.Fatal(errors.Errorf(`Schema "%s" must exist to restore to it with --redirect-schema`, unquotedSchemaName), "")
+	}
+}
+
 func ValidateDatabaseExistence(unquotedDBName string, createDatabase bool, isFiltered bool) {
 	qry := fmt.Sprintf(`
 SELECT CASE
@@ -228,6 +318,15 @@ func ValidateBackupFlagCombinations() {
 	if backupConfig.DataOnly && MustGetFlagBool(utils.METADATA_ONLY) {
 		gplog.Fatal(errors.Errorf("Cannot use metadata-only flag when restoring data-only backup"), "")
 	}
+	if backupConfig.DataOnly && MustGetFlagBool(utils.WITH_DEPENDENCIES) {
+		gplog.Fatal(errors.Errorf("Cannot use with-dependencies flag when restoring data-only backup"), "")
+	}
+	isDataOnly := backupConfig.DataOnly || MustGetFlagBool(utils.DATA_ONLY)
+	for _, flagName := range []string{utils.TRUNCATE_TABLE, utils.UPSERT} {
+		if MustGetFlagBool(flagName) && !isDataOnly {
+			gplog.Fatal(errors.Errorf("Cannot use %s flag unless restoring only data", flagName), "")
+		}
+	}
 	validateBackupFlagPluginCombinations()
 }
 
@@ -248,4 +347,28 @@ func ValidateFlagCombinations(flags *pflag.FlagSet) {
 	utils.CheckExclusiveFlags(flags, utils.EXCLUDE_SCHEMA, utils.EXCLUDE_RELATION, utils.INCLUDE_RELATION, utils.EXCLUDE_RELATION_FILE, utils.INCLUDE_RELATION_FILE)
 	utils.CheckExclusiveFlags(flags, utils.METADATA_ONLY, utils.DATA_ONLY)
 	utils.CheckExclusiveFlags(flags, utils.PLUGIN_CONFIG, utils.BACKUP_DIR)
+	utils.CheckExclusiveFlags(flags, utils.TIMESTAMP, utils.LABEL)
+	utils.CheckExclusiveFlags(flags, utils.TIMESTAMP, utils.LATEST)
+	utils.CheckExclusiveFlags(flags, utils.TRUNCATE_TABLE, utils.UPSERT)
+	utils.CheckExclusiveFlags(flags, utils.REDIRECT_SCHEMA, utils.CREATE_DB)
+	for _, filterFlag := range []string{utils.INCLUDE_SCHEMA, utils.INCLUDE_RELATION, utils.INCLUDE_RELATION_FILE,
+		utils.EXCLUDE_RELATION, utils.EXCLUDE_RELATION_FILE, utils.REDIRECT_SCHEMA} {
+		utils.CheckExclusiveFlags(flags, utils.TABLE_MAP_FILE, filterFlag)
+	}
+	utils.CheckExclusiveFlags(flags, utils.RESIZE_CLUSTER, utils.UPSERT)
+	utils.CheckExclusiveFlags(flags, utils.PRINT_SQL, utils.PLUGIN_CONFIG)
+	utils.CheckExclusiveFlags(flags, utils.PRINT_SQL, utils.RESIZE_CLUSTER)
+	if flags.Changed(utils.RESIZE_CLUSTER) && !flags.Changed(utils.BACKUP_DIR) {
+		gplog.Fatal(errors.Errorf("Cannot use --resize-cluster without --backup-dir"), "")
+	}
+	utils.CheckExclusiveFlags(flags, utils.WITH_DEPENDENCIES, utils.DATA_ONLY)
+	if flags.Changed(utils.WITH_DEPENDENCIES) && !flags.Changed(utils.INCLUDE_RELATION) && !flags.Changed(utils.INCLUDE_RELATION_FILE) && !flags.Changed(utils.TABLE_MAP_FILE) {
+		gplog.Fatal(errors.Errorf("Cannot use --with-dependencies without --include-table, --include-table-file, or --table-map-file"), "")
+	}
+	if !flags.Changed(utils.TIMESTAMP) && !flags.Changed(utils.LABEL) && !flags.Changed(utils.LATEST) {
+		gplog.Fatal(errors.Errorf("One of --timestamp, --label, or --latest must be specified"), "")
+	}
+	if (flags.Changed(utils.BEFORE) || flags.Changed(utils.DBNAME)) && !flags.Changed(utils.LABEL) && !flags.Changed(utils.LATEST) {
+		gplog.Fatal(errors.Errorf("--before and --dbname must be specified with --label or --latest"), "")
+	}
 }
@@ -1,7 +1,9 @@
 package restore_test
 
 import (
+	"github.com/greenplum-db/gp-common-go-libs/cluster"
 	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/greenplum-db/gpbackup/backup_filepath"
 	"github.com/greenplum-db/gpbackup/backup_history"
 	"github.com/greenplum-db/gpbackup/restore"
 	"github.com/greenplum-db/gpbackup/testutils"
@@ -30,10 +32,10 @@ var _ = Describe("restore/validate tests", func() {
 			toc, backupfile = testutils.InitializeTestTOC(buffer, "predata")
 			backupfile.ByteCount = table1Len
 			toc.AddMetadataEntry("predata", utils.MetadataEntry{Schema: "schema1", Name: "table1", ObjectType: "TABLE"}, 0, backupfile.ByteCount)
-			toc.AddMasterDataEntry("schema1", "table1", 1, "(i)", 0, "")
+			toc.AddMasterDataEntry("schema1", "table1", 1, "(i)", 0, "", "")
 			backupfile.ByteCount += table2Len
 			toc.AddMetadataEntry("predata", utils.MetadataEntry{Schema: "schema2", Name: "table2", ObjectType: "TABLE"}, table1Len, backupfile.ByteCount)
-			toc.AddMasterDataEntry("schema2", "table2", 2, "(j)", 0, "")
+			toc.AddMasterDataEntry("schema2", "table2", 2, "(j)", 0, "", "")
 			backupfile.ByteCount += sequenceLen
 			toc.AddMetadataEntry("predata", utils.MetadataEntry{Schema: "schema", Name: "somesequence", ObjectType: "SEQUENCE"}, table1Len+table2Len, backupfile.ByteCount)
 			restore.SetTOC(toc)
@@ -71,10 +73,10 @@ var _ = Describe("restore/validate tests", func() {
 	Describe("GenerateRestoreRelationList", func() {
 		BeforeEach(func() {
 			toc, _ = testutils.InitializeTestTOC(buffer, "metadata")
-			toc.AddMasterDataEntry("s1", "table1", 1, "(j)", 0, "")
-			toc.AddMasterDataEntry("s1", "table2", 2, "(j)", 0, "")
-			toc.AddMasterDataEntry("s2", "table1", 3, "(j)", 0, "")
-			toc.AddMasterDataEntry("s2", "table2", 4, "(j)", 0, "")
+			toc.AddMasterDataEntry("s1", "table1", 1, "(j)", 0, "", "")
+			toc.AddMasterDataEntry("s1", "table2", 2, "(j)", 0, "", "")
+			toc.AddMasterDataEntry("s2", "table1", 3, "(j)", 0, "", "")
+			toc.AddMasterDataEntry("s2", "table2", 4, "(j)", 0, "", "")
 			restore.SetTOC(toc)
 			cmdFlags.Set(utils.INCLUDE_RELATION, "")
 			cmdFlags.Set(utils.EXCLUDE_RELATION, "")
@@ -193,10 +195,10 @@ var _ = Describe("restore/validate tests", func() {
 		BeforeEach(func() {
 			toc, backupfile = testutils.InitializeTestTOC(buffer, "predata")
 			toc.AddMetadataEntry("predata", utils.MetadataEntry{Schema: "schema1", Name: "table1", ObjectType: "TABLE"}, 0, backupfile.ByteCount)
-			toc.AddMasterDataEntry("schema1", "table1", 1, "(i)", 0, "")
+			toc.AddMasterDataEntry("schema1", "table1", 1, "(i)", 0, "", "")
 
 			toc.AddMetadataEntry("predata", utils.MetadataEntry{Schema: "schema2", Name: "table2", ObjectType: "TABLE"}, 0, backupfile.ByteCount)
-			toc.AddMasterDataEntry("schema2", "table2", 2, "(j)", 0, "")
+			toc.AddMasterDataEntry("schema2", "table2", 2, "(j)", 0, "", "")
 
 			toc.AddMetadataEntry("predata", utils.MetadataEntry{Schema: "schema1", Name: "somesequence", ObjectType: "SEQUENCE"}, 0, backupfile.ByteCount)
 			toc.AddMetadataEntry("predata", utils.MetadataEntry{Schema: "schema1", Name: "someview", ObjectType: "VIEW"}, 0, backupfile.ByteCount)
@@ -289,4 +291,84 @@ var _ = Describe("restore/validate tests", func() {
 			restore.ValidateDatabaseExistence("testdb", false, false)
 		})
 	})
+	Describe("ValidateRedirectSchema", func() {
+		It("passes if the schema exists", func() {
+			schemaExists := sqlmock.NewRows([]string{"string"}).AddRow("true")
+			mock.ExpectQuery("SELECT (.*)").WillReturnRows(schemaExists)
+			restore.ValidateRedirectSchema(connectionPool, "archive")
+		})
+		It("panics if the schema does not exist", func() {
+			schemaExists := sqlmock.NewRows([]string{"string"}).AddRow("false")
+			mock.ExpectQuery("SELECT (.*)").WillReturnRows(schemaExists)
+			defer testhelper.ShouldPanicWithMessage(`Schema "archive" must exist to restore to it with --redirect-schema`)
+			restore.ValidateRedirectSchema(connectionPool, "archive")
+		})
+	})
+	Describe("ValidateSegmentCount", func() {
+		BeforeEach(func() {
+			restore.SetCluster(cluster.NewCluster([]cluster.SegConfig{{ContentID: -1}, {ContentID: 0}, {ContentID: 1}}))
+			restore.SetFPInfo(backup_filepath.FilePathInfo{Timestamp: "20170101010101"})
+		})
+		AfterEach(func() {
+			cmdFlags.Set(utils.RESIZE_CLUSTER, "false")
+		})
+		It("passes if the backup was taken on a cluster with the same number of segments", func() {
+			restore.SetBackupConfig(&backup_history.BackupConfig{SegmentCount: 2})
+			restore.ValidateSegmentCount()
+		})
+		It("passes if the backup does not record the number of segments", func() {
+			restore.SetBackupConfig(&backup_history.BackupConfig{})
+			restore.ValidateSegmentCount()
+		})
+		It("panics if the backup was taken on a cluster with a different number of segments", func() {
+			restore.SetBackupConfig(&backup_history.BackupConfig{SegmentCount: 4})
+			defer testhelper.ShouldPanicWithMessage("Backup 20170101010101 was taken on a cluster with 4 segments, but the restore cluster has 2 segments")
+			restore.ValidateSegmentCount()
+		})
+		It("passes for a different number of segments with --resize-cluster", func() {
+			cmdFlags.Set(utils.RESIZE_CLUSTER, "true")
+			restore.SetBackupConfig(&backup_history.BackupConfig{SegmentCount: 4})
+			restore.ValidateSegmentCount()
+		})
+		It("panics with --resize-cluster if the backup does not record the number of segments", func() {
+			cmdFlags.Set(utils.RESIZE_CLUSTER, "true")
+			restore.SetBackupConfig(&backup_history.BackupConfig{})
+			defer testhelper.ShouldPanicWithMessage("Backup 20170101010101 does not record the number of segments in its cluster and cannot be restored with --resize-cluster")
+			restore.ValidateSegmentCount()
+		})
+		It("panics with --resize-cluster for a single data file backup", func() {
+			cmdFlags.Set(utils.RESIZE_CLUSTER, "true")
+			restore.SetBackupConfig(&backup_history.BackupConfig{SegmentCount: 4, SingleDataFile: true})
+			defer testhelper.ShouldPanicWithMessage("Cannot use --resize-cluster with a backup taken with --single-data-file")
+			restore.ValidateSegmentCount()
+		})
+	})
+	Describe("ValidateNoReplicatedTables", func() {
+		BeforeEach(func() {
+			cmdFlags.Set(utils.RESIZE_CLUSTER, "true")
+			testhelper.SetDBVersion(connectionPool, "6.0.0")
+		})
+		AfterEach(func() {
+			cmdFlags.Set(utils.RESIZE_CLUSTER, "false")
+		})
+		It("passes if none of the restored tables are replicated", func() {
+			mock.ExpectQuery("SELECT (.*)").WillReturnRows(sqlmock.NewRows([]string{"string"}).AddRow("public.other"))
+			restore.ValidateNoReplicatedTables(connectionPool, []string{"public.foo", "public.bar"})
+		})
+		It("panics if any of the restored tables are replicated", func() {
+			mock.ExpectQuery("SELECT (.*)").WillReturnRows(sqlmock.NewRows([]string{"string"}).AddRow("public.foo").AddRow("public.bar"))
+			defer testhelper.ShouldPanicWithMessage("Cannot use --resize-cluster to restore replicated tables: public.foo, public.bar")
+			restore.ValidateNoReplicatedTables(connectionPool, []string{"public.foo", "public.bar", "public.baz"})
+		})
+		It("does not query the database without --resize-cluster", func() {
+			cmdFlags.Set(utils.RESIZE_CLUSTER, "false")
+			restore.ValidateNoReplicatedTables(connectionPool, []string{"public.foo"})
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+		It("does not query the database before GPDB 6", func() {
+			testhelper.SetDBVersion(connectionPool, "5.1.0")
+			restore.ValidateNoReplicatedTables(connectionPool, []string{"public.foo"})
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
 })
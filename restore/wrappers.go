@@ -2,6 +2,8 @@ package restore
 
 import (
 	"fmt"
+	"io"
+	"sort"
 	"strings"
 
 	"github.com/greenplum-db/gp-common-go-libs/dbconn"
@@ -10,6 +12,7 @@ import (
 	"github.com/greenplum-db/gpbackup/backup_filepath"
 	"github.com/greenplum-db/gpbackup/backup_history"
 	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
 )
 
 /*
@@ -37,6 +40,13 @@ func InitializeConnectionPool(unquotedDBName string) {
 	connectionPool = dbconn.NewDBConnFromEnvironment(unquotedDBName)
 	connectionPool.MustConnect(MustGetFlagInt(utils.JOBS))
 	utils.ValidateGPDBVersionCompatibility(connectionPool)
+	setupQuery := getConnectionSetupQuery()
+	for i := 0; i < connectionPool.NumConns; i++ {
+		connectionPool.MustExec(setupQuery, i)
+	}
+}
+
+func getConnectionSetupQuery() string {
 	setupQuery := `
 SET application_name TO 'gprestore';
 SET search_path TO pg_catalog;
@@ -62,10 +72,7 @@ SET default_with_oids = off;
 		setupQuery += "SET default_transaction_read_only = off;\n"
 	}
 	setupQuery += SetMaxCsvLineLengthQuery(connectionPool)
-
-	for i := 0; i < connectionPool.NumConns; i++ {
-		connectionPool.MustExec(setupQuery, i)
-	}
+	return setupQuery
 }
 
 func SetMaxCsvLineLengthQuery(connectionPool *dbconn.DBConn) string {
@@ -85,11 +92,61 @@ func SetMaxCsvLineLengthQuery(connectionPool *dbconn.DBConn) string {
 	return fmt.Sprintf("SET gp_max_csv_line_length = %d;\n", maxLineLength)
 }
 
+/*
+ * When --label or --latest is used instead of --timestamp, the backup to
+ * restore is the latest one in the backup history file that matches the
+ * label, database, and backup location flags.  The timestamp flag, and the
+ * backup directory flag if the backup used one, are then set from that backup.
+ */
+func SetTimestampFromBackupHistory() {
+	historyFPInfo := backup_filepath.NewFilePathInfo(globalCluster, "", "", "")
+	historyFilename := historyFPInfo.GetBackupHistoryFilePath()
+	history, err := backup_history.NewHistory(historyFilename)
+	gplog.FatalOnError(err, fmt.Sprintf("Unable to read backup history file %s", historyFilename))
+	labels, err := utils.ParseLabels(MustGetFlagStringArray(utils.LABEL))
+	gplog.FatalOnError(err)
+	config := FindBackupInHistory(history, labels, MustGetFlagString(utils.DBNAME), MustGetFlagString(utils.BACKUP_DIR),
+		MustGetFlagString(utils.PLUGIN_CONFIG) != "", MustGetFlagString(utils.BEFORE))
+	if config == nil {
+		gplog.Fatal(errors.Errorf("No backup matching the specified flags was found in backup history file %s", historyFilename), "")
+	}
+	gplog.Info("Selected backup %s of database %s from backup history", config.Timestamp, config.DatabaseName)
+	err = cmdFlags.Set(utils.TIMESTAMP, config.Timestamp)
+	gplog.FatalOnError(err)
+	if MustGetFlagString(utils.BACKUP_DIR) == "" && config.BackupDir != "" {
+		err = cmdFlags.Set(utils.BACKUP_DIR, config.BackupDir)
+		gplog.FatalOnError(err)
+	}
+}
+
+/*
+ * Backups taken with a plugin can only be restored with a plugin and backups
+ * taken without one can only be restored without one, so the usePlugin
+ * parameter ensures the selected backup can be found by this restore.
+ */
+func FindBackupInHistory(history *backup_history.History, labels map[string]string, dbName string, backupDir string, usePlugin bool, before string) *backup_history.BackupConfig {
+	return history.FindLatestBackup(func(config *backup_history.BackupConfig) bool {
+		return config.HasLabels(labels) &&
+			(dbName == "" || utils.UnquoteIdent(config.DatabaseName) == dbName) &&
+			(backupDir == "" || config.BackupDir == backupDir) &&
+			(config.Plugin != "") == usePlugin &&
+			(before == "" || config.Timestamp < before)
+	})
+}
+
 func InitializeBackupConfig() {
+	readBackupConfig()
+	utils.EnsureDatabaseVersionCompatibility(backupConfig.DatabaseVersion, connectionPool.Version)
+	ValidateSegmentCount()
+}
+
+func readBackupConfig() {
 	backupConfig = backup_history.ReadConfigFile(globalFPInfo.GetConfigFilePath())
+	if !backupConfig.IsSuccessful() {
+		gplog.Fatal(errors.Errorf("Backup %s did not complete successfully and cannot be restored.  Backup status: %s", globalFPInfo.Timestamp, backupConfig.Status), "")
+	}
 	utils.InitializePipeThroughParameters(backupConfig.Compressed, 0)
 	utils.EnsureBackupVersionCompatibility(backupConfig.BackupVersion, version)
-	utils.EnsureDatabaseVersionCompatibility(backupConfig.DatabaseVersion, connectionPool.Version)
 }
 
 func InitializeFilterLists() {
@@ -98,6 +155,16 @@ func InitializeFilterLists() {
 		err := cmdFlags.Set(utils.INCLUDE_RELATION, includeRelations)
 		gplog.FatalOnError(err)
 	}
+	if MustGetFlagString(utils.TABLE_MAP_FILE) != "" {
+		tableMap = utils.ParseTableMap(iohelper.MustReadLinesFromFile(MustGetFlagString(utils.TABLE_MAP_FILE)))
+		mappedRelations := make([]string, 0)
+		for fqn := range tableMap {
+			mappedRelations = append(mappedRelations, fqn)
+		}
+		sort.Strings(mappedRelations)
+		err := cmdFlags.Set(utils.INCLUDE_RELATION, strings.Join(mappedRelations, ","))
+		gplog.FatalOnError(err)
+	}
 	if MustGetFlagString(utils.EXCLUDE_RELATION_FILE) != "" {
 		excludeRelations := strings.Join(iohelper.MustReadLinesFromFile(MustGetFlagString(utils.EXCLUDE_RELATION_FILE)), ",")
 		err := cmdFlags.Set(utils.EXCLUDE_RELATION, excludeRelations)
@@ -125,6 +192,58 @@ func BackupConfigurationValidation() {
 	ValidateBackupFlagCombinations()
 
 	validateFilterListsInBackupSet()
+
+	if MustGetFlagBool(utils.WITH_DEPENDENCIES) {
+		IncludeRelationDependencies()
+	}
+}
+
+/*
+ * The objects that the included relations depend on are restored along with
+ * them.  Sequences are also added to the included relations so that their
+ * OWNED BY statements are restored, while the other objects are restored
+ * without the objects that depend on them, such as a parent table's indexes.
+ */
+func IncludeRelationDependencies() {
+	hasDependencyInfo := false
+	for _, entry := range globalTOC.PredataEntries {
+		if entry.ID != (utils.UniqueID{}) {
+			hasDependencyInfo = true
+			break
+		}
+	}
+	if !hasDependencyInfo {
+		gplog.Warn("Backup %s does not record object dependencies, so only the included relations will be restored", globalFPInfo.Timestamp)
+		return
+	}
+
+	relationDependencies = globalTOC.GetDependenciesOfRelations(MustGetFlagStringSlice(utils.INCLUDE_RELATION))
+	dependencyList := make([]string, 0)
+	sequences := make([]string, 0)
+	listed := make(map[utils.UniqueID]bool, 0)
+	for _, entry := range globalTOC.PredataEntries {
+		if !relationDependencies[entry.ID] || listed[entry.ID] {
+			continue
+		}
+		listed[entry.ID] = true
+		name := utils.MakeObjectName(entry.Schema, entry.Name)
+		dependencyList = append(dependencyList, fmt.Sprintf("%s %s", entry.ObjectType, name))
+		if entry.ObjectType == "SEQUENCE" {
+			sequences = append(sequences, name)
+		}
+	}
+	if len(dependencyList) == 0 {
+		gplog.Info("The included relations do not depend on any other objects in the backup")
+		return
+	}
+	gplog.Info("Restoring %d objects that the included relations depend on:", len(dependencyList))
+	for _, dependency := range dependencyList {
+		gplog.Info("\t%s", dependency)
+	}
+	if len(sequences) > 0 {
+		err := cmdFlags.Set(utils.INCLUDE_RELATION, strings.Join(sequences, ","))
+		gplog.FatalOnError(err)
+	}
 }
 
 func SetRestorePlanForLegacyBackup(toc *utils.TOC, backupTimestamp string, backupConfig *backup_history.BackupConfig) {
@@ -157,6 +276,7 @@ func RecoverMetadataFilesUsingPlugin() {
 	}
 
 	InitializeBackupConfig()
+	ValidateIncrementalRestorePlan()
 
 	var fpInfoList []backup_filepath.FilePathInfo
 	if backupConfig.MetadataOnly {
@@ -177,10 +297,22 @@ func RecoverMetadataFilesUsingPlugin() {
  * Metadata and/or data restore wrapper functions
  */
 
+/*
+ * Backups taken with --metadata-directory store each TOC entry in its own file,
+ * so the filename is only used to read statements from single-file backups.
+ */
+func openMetadataForSection(section string, filename string) io.ReaderAt {
+	if backupConfig != nil && backupConfig.MetadataDirectory {
+		return utils.NewMetadataDirectoryReader(globalFPInfo.GetMetadataDirectoryPath(), globalTOC, section)
+	}
+	return iohelper.MustOpenFileForReading(filename)
+}
+
 func GetRestoreMetadataStatements(section string, filename string, includeObjectTypes []string, excludeObjectTypes []string, filterSchemas bool, filterRelations bool) []utils.StatementWithType {
-	metadataFile := iohelper.MustOpenFileForReading(filename)
+	metadataFile := openMetadataForSection(section, filename)
 	var statements []utils.StatementWithType
 	var inSchemas, exSchemas, inRelations, exRelations []string
+	var dependencies map[utils.UniqueID]bool
 	if len(includeObjectTypes) > 0 || len(excludeObjectTypes) > 0 || filterSchemas || filterRelations {
 		if filterSchemas {
 			inSchemas = MustGetFlagStringSlice(utils.INCLUDE_SCHEMA)
@@ -189,6 +321,7 @@ func GetRestoreMetadataStatements(section string, filename string, includeObject
 		if filterRelations {
 			inRelations = MustGetFlagStringSlice(utils.INCLUDE_RELATION)
 			exRelations = MustGetFlagStringSlice(utils.EXCLUDE_RELATION)
+			dependencies = relationDependencies
 			fpInfoList := GetBackupFPInfoListFromRestorePlan()
 			for _, fpInfo := range fpInfoList {
 				tocFilename := fpInfo.GetTOCFilePath()
@@ -197,7 +330,14 @@ func GetRestoreMetadataStatements(section string, filename string, includeObject
 			}
 		}
 	}
-	statements = globalTOC.GetSQLStatementForObjectTypes(section, metadataFile, includeObjectTypes, excludeObjectTypes, inSchemas, exSchemas, inRelations, exRelations)
+	statements = globalTOC.GetSQLStatementForObjectTypesAndDependencies(section, metadataFile, includeObjectTypes, excludeObjectTypes, inSchemas, exSchemas, inRelations, exRelations, dependencies)
+	if redirectSchema != "" && section != "global" {
+		oldSchemas := GetRedirectedSchemas()
+		statements = utils.SubstituteRedirectSchemaInStatements(statements, oldSchemas, redirectSchema, globalTOC.GetObjectFQNsInSchemas(oldSchemas))
+	}
+	if len(tableMap) > 0 && section != "global" {
+		statements = utils.SubstituteTableMapInStatements(statements, tableMap)
+	}
 	return statements
 }
 
@@ -244,6 +384,7 @@ func RestoreSchemas(schemaStatements []utils.StatementWithType, progressBar util
 				gplog.Warn("Schema %s already exists", schema.Name)
 			} else {
 				errMsg := fmt.Sprintf("Error encountered while creating schema %s", schema.Name)
+				failedStatements.AddStatement(schema, err)
 				if MustGetFlagBool(utils.ON_ERROR_CONTINUE) {
 					gplog.Verbose(fmt.Sprintf("%s: %s", errMsg, err.Error()))
 					numErrors++
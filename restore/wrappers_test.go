@@ -2,6 +2,7 @@ package restore_test
 
 import (
 	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/greenplum-db/gpbackup/backup_filepath"
 	"github.com/greenplum-db/gpbackup/backup_history"
 	"github.com/greenplum-db/gpbackup/restore"
 	"github.com/greenplum-db/gpbackup/utils"
@@ -117,4 +118,81 @@ var _ = Describe("wrapper tests", func() {
 		})
 
 	})
+	Describe("FindBackupInHistory", func() {
+		history := &backup_history.History{BackupConfigs: []backup_history.BackupConfig{
+			{DatabaseName: "testdb", Timestamp: "20170101010101", Labels: map[string]string{"release": "pre-5.1"}},
+			{DatabaseName: `"Test DB"`, Timestamp: "20170102010101", Labels: map[string]string{"release": "pre-5.1"}},
+			{DatabaseName: "testdb", Timestamp: "20170103010101", BackupDir: "/data/backups", Labels: map[string]string{"release": "pre-5.1"}},
+			{DatabaseName: "testdb", Timestamp: "20170104010101", Plugin: "/tmp/plugin.sh", Labels: map[string]string{"release": "pre-5.1"}},
+			{DatabaseName: "testdb", Timestamp: "20170105010101"},
+		}}
+		releaseLabel := map[string]string{"release": "pre-5.1"}
+		It("returns the latest backup with the given labels", func() {
+			config := restore.FindBackupInHistory(history, releaseLabel, "", "", false, "")
+
+			Expect(config.Timestamp).To(Equal("20170103010101"))
+		})
+		It("returns the latest backup of the given database", func() {
+			config := restore.FindBackupInHistory(history, releaseLabel, "Test DB", "", false, "")
+
+			Expect(config.Timestamp).To(Equal("20170102010101"))
+		})
+		It("returns the latest backup in the given backup directory", func() {
+			config := restore.FindBackupInHistory(history, nil, "testdb", "/data/backups", false, "")
+
+			Expect(config.Timestamp).To(Equal("20170103010101"))
+		})
+		It("returns the latest backup taken with a plugin", func() {
+			config := restore.FindBackupInHistory(history, releaseLabel, "", "", true, "")
+
+			Expect(config.Timestamp).To(Equal("20170104010101"))
+		})
+		It("returns the latest backup before the given time", func() {
+			config := restore.FindBackupInHistory(history, nil, "testdb", "", false, "20170103010101")
+
+			Expect(config.Timestamp).To(Equal("20170101010101"))
+		})
+		It("returns nil if no backup matches", func() {
+			config := restore.FindBackupInHistory(history, map[string]string{"release": "pre-6.0"}, "", "", false, "")
+
+			Expect(config).To(BeNil())
+		})
+	})
+	Describe("IncludeRelationDependencies", func() {
+		var toc *utils.TOC
+		BeforeEach(func() {
+			toc = &utils.TOC{}
+			toc.InitializeMetadataEntryMap()
+			restore.SetTOC(toc)
+			restore.SetFPInfo(backup_filepath.FilePathInfo{Timestamp: "20170101010101"})
+		})
+		AfterEach(func() {
+			restore.SetRelationDependencies(nil)
+		})
+		It("lists the dependencies of the included relations and includes their sequences", func() {
+			typeID := utils.UniqueID{ClassID: 1247, Oid: 1}
+			seqID := utils.UniqueID{ClassID: 1259, Oid: 2}
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{Schema: "public", Name: "type1", ObjectType: "TYPE", ID: typeID}, 0, 0)
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{Schema: "public", Name: "type1", ObjectType: "TYPE", ID: typeID}, 0, 0)
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{Schema: "public", Name: "seq1", ObjectType: "SEQUENCE", ID: seqID}, 0, 0)
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{Schema: "public", Name: "table1", ObjectType: "TABLE", ID: utils.UniqueID{ClassID: 1259, Oid: 3}, Dependencies: []utils.UniqueID{typeID, seqID}}, 0, 0)
+			cmdFlags.Set(utils.INCLUDE_RELATION, "public.table1")
+
+			restore.IncludeRelationDependencies()
+
+			Expect(cmdFlags.GetStringSlice(utils.INCLUDE_RELATION)).To(Equal([]string{"public.table1", "public.seq1"}))
+			testhelper.ExpectRegexp(logfile, "Restoring 2 objects that the included relations depend on:")
+			testhelper.ExpectRegexp(logfile, "TYPE public.type1")
+			testhelper.ExpectRegexp(logfile, "SEQUENCE public.seq1")
+		})
+		It("warns if the backup does not record dependencies", func() {
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{Schema: "public", Name: "table1", ObjectType: "TABLE"}, 0, 0)
+			cmdFlags.Set(utils.INCLUDE_RELATION, "public.table1")
+
+			restore.IncludeRelationDependencies()
+
+			Expect(cmdFlags.GetStringSlice(utils.INCLUDE_RELATION)).To(Equal([]string{"public.table1"}))
+			testhelper.ExpectRegexp(logfile, "[WARNING]:-Backup 20170101010101 does not record object dependencies, so only the included relations will be restored")
+		})
+	})
 })
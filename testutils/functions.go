@@ -128,7 +128,7 @@ func DefaultMetadata(objType string, hasPrivileges bool, hasOwner bool, hasComme
 // objType should be an all-caps string like TABLE, INDEX, etc.
 func DefaultMetadataMap(objType string, hasPrivileges bool, hasOwner bool, hasComment bool, hasSecurityLabel bool) backup.MetadataMap {
 	return backup.MetadataMap{
-		backup.UniqueID{ClassID: ClassIDFromObjectName(objType), Oid: 1}: DefaultMetadata(objType, hasPrivileges, hasOwner, hasComment, hasSecurityLabel),
+		utils.UniqueID{ClassID: ClassIDFromObjectName(objType), Oid: 1}: DefaultMetadata(objType, hasPrivileges, hasOwner, hasComment, hasSecurityLabel),
 	}
 }
 
@@ -351,7 +351,7 @@ func AssertBufferContents(entries []utils.MetadataEntry, buffer *gbytes.Buffer,
 
 func ExpectEntry(entries []utils.MetadataEntry, index int, schema, referenceObject, name, objectType string) {
 	Expect(len(entries)).To(BeNumerically(">", index))
-	structmatcher.ExpectStructsToMatchExcluding(entries[index], utils.MetadataEntry{Schema: schema, Name: name, ObjectType: objectType, ReferenceObject: referenceObject, StartByte: 0, EndByte: 0}, "StartByte", "EndByte")
+	structmatcher.ExpectStructsToMatchExcluding(entries[index], utils.MetadataEntry{Schema: schema, Name: name, ObjectType: objectType, ReferenceObject: referenceObject, StartByte: 0, EndByte: 0}, "StartByte", "EndByte", "ID", "Dependencies")
 }
 
 func ExecuteSQLFile(connectionPool *dbconn.DBConn, filename string) {
@@ -406,7 +406,7 @@ func OidFromObjectName(connectionPool *dbconn.DBConn, schemaName string, objectN
 	return result.Oid
 }
 
-func UniqueIDFromObjectName(connectionPool *dbconn.DBConn, schemaName string, objectName string, params backup.MetadataQueryParams) backup.UniqueID {
+func UniqueIDFromObjectName(connectionPool *dbconn.DBConn, schemaName string, objectName string, params backup.MetadataQueryParams) utils.UniqueID {
 	query := fmt.Sprintf("SELECT '%s'::regclass::oid", params.CatalogTable)
 	result := struct {
 		Oid uint32
@@ -416,7 +416,7 @@ func UniqueIDFromObjectName(connectionPool *dbconn.DBConn, schemaName string, ob
 		Fail(fmt.Sprintf("Execution of query failed: %v", err))
 	}
 
-	return backup.UniqueID{ClassID: result.Oid, Oid: OidFromObjectName(connectionPool, schemaName, objectName, params)}
+	return utils.UniqueID{ClassID: result.Oid, Oid: OidFromObjectName(connectionPool, schemaName, objectName, params)}
 }
 
 func GetUserByID(connectionPool *dbconn.DBConn, oid uint32) string {
@@ -165,6 +165,25 @@ func CleanUpSegmentHelperProcesses(c *cluster.Cluster, fpInfo backup_filepath.Fi
 	})
 }
 
+/*
+ * This function returns an error rather than Fataling so that a backup that
+ * cannot be deleted does not stop gpbackup from deleting other backups.
+ */
+func DeleteBackupDirectories(c *cluster.Cluster, fpInfo backup_filepath.FilePathInfo) error {
+	remoteOutput := c.GenerateAndExecuteCommand(fmt.Sprintf("Deleting backup directories of backup %s", fpInfo.Timestamp), func(contentID int) string {
+		return fmt.Sprintf("rm -rf %s", fpInfo.GetDirForContent(contentID))
+	}, cluster.ON_SEGMENTS_AND_MASTER)
+	if remoteOutput.NumErrors > 0 {
+		for contentID, err := range remoteOutput.Errors {
+			if err != nil {
+				gplog.Verbose("Unable to delete backup directory %s for segment %d: %s", fpInfo.GetDirForContent(contentID), contentID, remoteOutput.Stderrs[contentID])
+			}
+		}
+		return errors.Errorf("Unable to delete backup directories on %d segments", remoteOutput.NumErrors)
+	}
+	return nil
+}
+
 func CheckAgentErrorsOnSegments(c *cluster.Cluster, fpInfo backup_filepath.FilePathInfo) error {
 	remoteOutput := c.GenerateAndExecuteCommand("Checking whether segment agents had errors", func(contentID int) string {
 		errorFile := fmt.Sprintf("%s_error", fpInfo.GetSegmentPipeFilePath(contentID))
@@ -122,6 +122,35 @@ var _ = Describe("agent remote", func() {
 			Expect(err).To(Equal(tw.WriteErr))
 		})
 	})
+	Describe("DeleteBackupDirectories", func() {
+		var testExecutor *testhelper.TestExecutor
+		var testCluster *cluster.Cluster
+		var fpInfo backup_filepath.FilePathInfo
+		BeforeEach(func() {
+			testExecutor = &testhelper.TestExecutor{ClusterOutput: &cluster.RemoteOutput{}}
+			testCluster = cluster.NewCluster([]cluster.SegConfig{
+				{ContentID: -1, Hostname: "localhost", DataDir: "/data/gpseg-1"},
+				{ContentID: 0, Hostname: "remotehost1", DataDir: "/data/gpseg0"},
+			})
+			testCluster.Executor = testExecutor
+			fpInfo = backup_filepath.NewFilePathInfo(testCluster, "", "20170101010101", "gpseg")
+		})
+		It("removes the backup directory on the master and every segment", func() {
+			err := utils.DeleteBackupDirectories(testCluster, fpInfo)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(testExecutor.ClusterCommands[0][-1]).To(ContainElement("rm -rf /data/gpseg-1/backups/20170101/20170101010101"))
+			Expect(testExecutor.ClusterCommands[0][0]).To(ContainElement("rm -rf /data/gpseg0/backups/20170101/20170101010101"))
+		})
+		It("returns an error if a backup directory cannot be removed", func() {
+			testExecutor.ClusterOutput = &cluster.RemoteOutput{NumErrors: 1, Errors: map[int]error{0: errors.New("exit status 1")}, Stderrs: map[int]string{0: "Permission denied"}}
+
+			err := utils.DeleteBackupDirectories(testCluster, fpInfo)
+
+			Expect(err).To(MatchError("Unable to delete backup directories on 1 segments"))
+			Expect(string(logfile.Contents())).To(ContainSubstring("Unable to delete backup directory /data/gpseg0/backups/20170101/20170101010101 for segment 0: Permission denied"))
+		})
+	})
 })
 
 type testWriter struct {
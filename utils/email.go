@@ -0,0 +1,235 @@
+package utils
+
+/*
+ * This file contains structs and functions related to sending email reports
+ * directly to an SMTP server, as an alternative to piping them to sendmail.
+ */
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	defaultSMTPPort = 25
+	smtpDialTimeout = 30 * time.Second
+)
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	StartTLS bool `yaml:"starttls"`
+	Username string
+	Password string
+	From     string
+}
+
+/*
+ * Contacts are listed per utility in the same format as gp_email_contacts.yaml,
+ * so an existing contacts file can be turned into an email config file by
+ * adding an smtp section.
+ */
+type EmailConfig struct {
+	SMTP     SMTPConfig `yaml:"smtp"`
+	Contacts map[string][]EmailContact
+}
+
+func ReadEmailConfig(filename string) (*EmailConfig, error) {
+	config := &EmailConfig{}
+	contents, err := operating.System.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	err = yaml.UnmarshalStrict(contents, config)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("Unable to parse email config file %s", filename))
+	}
+	if config.SMTP.Host == "" || config.SMTP.From == "" {
+		return nil, errors.Errorf("The smtp section of email config file %s must contain a host and a from address", filename)
+	}
+	if config.SMTP.Port == 0 {
+		config.SMTP.Port = defaultSMTPPort
+	}
+	return config, nil
+}
+
+func (config *EmailConfig) GetRecipients(utility string, status string) []string {
+	recipients := make([]string, 0)
+	for _, contact := range config.Contacts[utility] {
+		if contact.Status[status] {
+			recipients = append(recipients, contact.Address)
+		}
+	}
+	return recipients
+}
+
+// Base64-encoded MIME parts are limited to 76 characters per line
+func writeBase64Lines(buffer *bytes.Buffer, contents []byte) {
+	encoded := base64.StdEncoding.EncodeToString(contents)
+	for len(encoded) > 76 {
+		buffer.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buffer.WriteString(encoded + "\r\n")
+}
+
+/*
+ * Constructs a multipart/mixed message with the text report as a plain-text
+ * body and the JSON report as an attachment.
+ */
+func ConstructMIMEMessage(from string, to []string, subject string, body string, attachmentName string, attachment []byte) ([]byte, error) {
+	var message bytes.Buffer
+	writer := multipart.NewWriter(&message)
+	fmt.Fprintf(&message, "From: %s\r\n", from)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", operating.System.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&message, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", writer.Boundary())
+
+	bodyPart, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	bodyWriter := quotedprintable.NewWriter(bodyPart)
+	_, err = bodyWriter.Write([]byte(body))
+	if err != nil {
+		return nil, err
+	}
+	err = bodyWriter.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if attachment != nil {
+		attachmentPart, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {"application/json"},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachmentName})},
+		})
+		if err != nil {
+			return nil, err
+		}
+		var encoded bytes.Buffer
+		writeBase64Lines(&encoded, attachment)
+		_, err = attachmentPart.Write(encoded.Bytes())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+	return message.Bytes(), nil
+}
+
+func (config *EmailConfig) SendMail(to []string, message []byte) error {
+	address := net.JoinHostPort(config.SMTP.Host, strconv.Itoa(config.SMTP.Port))
+	conn, err := net.DialTimeout("tcp", address, smtpDialTimeout)
+	if err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, config.SMTP.Host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if config.SMTP.StartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.Errorf("SMTP server %s does not support STARTTLS", address)
+		}
+		err = client.StartTLS(&tls.Config{ServerName: config.SMTP.Host})
+		if err != nil {
+			return err
+		}
+	}
+	if config.SMTP.Username != "" {
+		err = client.Auth(smtp.PlainAuth("", config.SMTP.Username, config.SMTP.Password, config.SMTP.Host))
+		if err != nil {
+			return err
+		}
+	}
+	err = client.Mail(config.SMTP.From)
+	if err != nil {
+		return err
+	}
+	for _, recipient := range to {
+		err = client.Rcpt(recipient)
+		if err != nil {
+			return err
+		}
+	}
+	dataWriter, err := client.Data()
+	if err != nil {
+		return err
+	}
+	_, err = dataWriter.Write(message)
+	if err != nil {
+		return err
+	}
+	err = dataWriter.Close()
+	if err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// Emails the report, with the JSON report attached, to the contacts for the utility and status, logging a warning on failure
+func SendEmailReport(configFilename string, utility string, timestamp string, status string, reportFilename string, jsonReportFilename string) {
+	config, err := ReadEmailConfig(configFilename)
+	if err != nil {
+		gplog.Warn("Unable to send email report: %v", err)
+		return
+	}
+	recipients := config.GetRecipients(utility, status)
+	if len(recipients) == 0 {
+		gplog.Verbose("No %s contacts in %s for status %s; email report will not be sent", utility, configFilename, status)
+		return
+	}
+	body, err := operating.System.ReadFile(reportFilename)
+	if err != nil {
+		gplog.Warn("Unable to send email report: %v", err)
+		return
+	}
+	attachment, err := operating.System.ReadFile(jsonReportFilename)
+	if err != nil {
+		gplog.Warn("Unable to attach JSON report %s to email report: %v", jsonReportFilename, err)
+		attachment = nil
+	}
+	hostname, _ := operating.System.Hostname()
+	subject := fmt.Sprintf("%s %s on %s completed", utility, timestamp, hostname)
+	message, err := ConstructMIMEMessage(config.SMTP.From, recipients, subject, string(body), path.Base(jsonReportFilename), attachment)
+	if err != nil {
+		gplog.Warn("Unable to send email report: %v", err)
+		return
+	}
+	gplog.Verbose("Sending email report to the following addresses: %s", strings.Join(recipients, " "))
+	err = config.SendMail(recipients, message)
+	if err != nil {
+		gplog.Warn("Unable to send email report: %v", err)
+	}
+}
@@ -0,0 +1,259 @@
+package utils_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/greenplum-db/gpbackup/utils"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+/*
+ * A minimal SMTP server that accepts a single session and records the
+ * commands and message data it receives.
+ */
+type fakeSMTPServer struct {
+	listener net.Listener
+	mutex    sync.Mutex
+	commands []string
+	data     string
+	done     chan struct{}
+}
+
+func newFakeSMTPServer() *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).ToNot(HaveOccurred())
+	server := &fakeSMTPServer{listener: listener, done: make(chan struct{})}
+	go server.serve()
+	return server
+}
+
+func (server *fakeSMTPServer) serve() {
+	defer close(server.done)
+	netConn, err := server.listener.Accept()
+	if err != nil {
+		return
+	}
+	conn := textproto.NewConn(netConn)
+	defer conn.Close()
+	_ = conn.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := conn.ReadLine()
+		if err != nil {
+			return
+		}
+		server.mutex.Lock()
+		server.commands = append(server.commands, line)
+		server.mutex.Unlock()
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch command {
+		case "EHLO":
+			_ = conn.PrintfLine("250-localhost\r\n250 AUTH PLAIN")
+		case "AUTH":
+			_ = conn.PrintfLine("235 Authentication successful")
+		case "DATA":
+			_ = conn.PrintfLine("354 Start mail input")
+			data, _ := conn.ReadDotBytes()
+			server.mutex.Lock()
+			server.data = string(data)
+			server.mutex.Unlock()
+			_ = conn.PrintfLine("250 OK")
+		case "QUIT":
+			_ = conn.PrintfLine("221 Bye")
+			return
+		default:
+			_ = conn.PrintfLine("250 OK")
+		}
+	}
+}
+
+func (server *fakeSMTPServer) Port() int {
+	return server.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (server *fakeSMTPServer) Close() {
+	_ = server.listener.Close()
+	<-server.done
+}
+
+var _ = Describe("utils/email tests", func() {
+	setFiles := func(files map[string]string) {
+		operating.System.ReadFile = func(filename string) ([]byte, error) {
+			if contents, ok := files[filename]; ok {
+				return []byte(contents), nil
+			}
+			return nil, errors.Errorf("open %s: no such file or directory", filename)
+		}
+	}
+	BeforeEach(func() {
+		operating.System.Hostname = func() (string, error) {
+			return "localhost", nil
+		}
+		operating.System.Now = func() time.Time {
+			return time.Date(2017, 1, 1, 1, 1, 1, 0, time.UTC)
+		}
+	})
+	AfterEach(func() {
+		operating.System = operating.InitializeSystemFunctions()
+	})
+	Describe("ReadEmailConfig", func() {
+		It("reads the SMTP settings and contacts and uses a default port", func() {
+			setFiles(map[string]string{"/home/gpadmin/email.yaml": `smtp:
+  host: smtp.example.com
+  starttls: true
+  username: gpadmin
+  password: secret
+  from: gpadmin@example.com
+contacts:
+  gpbackup:
+  - address: contact1@example.com
+    status:
+      success: true`})
+
+			config, err := utils.ReadEmailConfig("/home/gpadmin/email.yaml")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.SMTP).To(Equal(utils.SMTPConfig{Host: "smtp.example.com", Port: 25, StartTLS: true, Username: "gpadmin", Password: "secret", From: "gpadmin@example.com"}))
+			Expect(config.Contacts["gpbackup"]).To(Equal([]utils.EmailContact{{Address: "contact1@example.com", Status: map[string]bool{"success": true}}}))
+		})
+		It("returns an error if the SMTP host is missing", func() {
+			setFiles(map[string]string{"/home/gpadmin/email.yaml": `smtp:
+  from: gpadmin@example.com`})
+
+			_, err := utils.ReadEmailConfig("/home/gpadmin/email.yaml")
+
+			Expect(err).To(MatchError("The smtp section of email config file /home/gpadmin/email.yaml must contain a host and a from address"))
+		})
+		It("returns an error for an unknown field", func() {
+			setFiles(map[string]string{"/home/gpadmin/email.yaml": `smtp:
+  hostname: smtp.example.com`})
+
+			_, err := utils.ReadEmailConfig("/home/gpadmin/email.yaml")
+
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unable to parse email config file /home/gpadmin/email.yaml"))
+		})
+	})
+	Describe("GetRecipients", func() {
+		It("returns only the contacts for the given utility and status", func() {
+			config := utils.EmailConfig{Contacts: map[string][]utils.EmailContact{
+				"gpbackup": {
+					{Address: "contact1@example.com", Status: map[string]bool{"success": true, "failure": true}},
+					{Address: "contact2@example.com", Status: map[string]bool{"failure": true}},
+				},
+				"gprestore": {
+					{Address: "contact3@example.com", Status: map[string]bool{"success": true}},
+				},
+			}}
+
+			Expect(config.GetRecipients("gpbackup", "success")).To(Equal([]string{"contact1@example.com"}))
+		})
+	})
+	Describe("ConstructMIMEMessage", func() {
+		It("puts the report in the body and attaches the JSON report", func() {
+			body := `Greenplum Database Backup Report
+Backup Error: ERROR: relation "public.foo" does not exist`
+
+			message, err := utils.ConstructMIMEMessage("gpadmin@example.com", []string{"contact1@example.com", "contact2@example.com"},
+				"gpbackup 20170101010101 on localhost completed", body, "gpbackup_20170101010101_report.json", []byte(`{"Status": "failure"}`))
+			Expect(err).ToNot(HaveOccurred())
+
+			parsed, err := mail.ReadMessage(bytes.NewReader(message))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed.Header.Get("From")).To(Equal("gpadmin@example.com"))
+			Expect(parsed.Header.Get("To")).To(Equal("contact1@example.com, contact2@example.com"))
+			Expect(parsed.Header.Get("Subject")).To(Equal("gpbackup 20170101010101 on localhost completed"))
+			mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mediaType).To(Equal("multipart/mixed"))
+
+			reader := multipart.NewReader(parsed.Body, params["boundary"])
+			bodyPart, err := reader.NextPart()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(bodyPart.Header.Get("Content-Type")).To(Equal("text/plain; charset=utf-8"))
+			bodyContents, _ := ioutil.ReadAll(bodyPart) // quoted-printable is decoded by the reader
+			Expect(strings.Replace(string(bodyContents), "\r\n", "\n", -1)).To(Equal(body))
+
+			attachmentPart, err := reader.NextPart()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(attachmentPart.Header.Get("Content-Type")).To(Equal("application/json"))
+			Expect(attachmentPart.FileName()).To(Equal("gpbackup_20170101010101_report.json"))
+			encoded, _ := ioutil.ReadAll(attachmentPart)
+			decoded, err := base64.StdEncoding.DecodeString(strings.Replace(string(encoded), "\r\n", "", -1))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(decoded)).To(Equal(`{"Status": "failure"}`))
+		})
+	})
+	Describe("SendEmailReport", func() {
+		var server *fakeSMTPServer
+		BeforeEach(func() {
+			server = newFakeSMTPServer()
+		})
+		AfterEach(func() {
+			server.Close()
+		})
+		It("sends the report to the SMTP server with authentication", func() {
+			setFiles(map[string]string{
+				"/home/gpadmin/email.yaml": fmt.Sprintf(`smtp:
+  host: 127.0.0.1
+  port: %d
+  username: gpadmin
+  password: secret
+  from: gpadmin@example.com
+contacts:
+  gpbackup:
+  - address: contact1@example.com
+    status:
+      success: true
+  - address: contact2@example.com
+    status:
+      failure: true`, server.Port()),
+				"/backups/report":      "Greenplum Database Backup Report",
+				"/backups/report.json": `{"Status": "success"}`,
+			})
+
+			utils.SendEmailReport("/home/gpadmin/email.yaml", "gpbackup", "20170101010101", "success", "/backups/report", "/backups/report.json")
+			server.Close()
+
+			Expect(server.commands).To(ContainElement("AUTH PLAIN " + base64.StdEncoding.EncodeToString([]byte("\x00gpadmin\x00secret"))))
+			Expect(server.commands).To(ContainElement("MAIL FROM:<gpadmin@example.com>"))
+			Expect(server.commands).To(ContainElement("RCPT TO:<contact1@example.com>"))
+			Expect(server.commands).ToNot(ContainElement("RCPT TO:<contact2@example.com>"))
+			Expect(server.data).To(ContainSubstring("Greenplum Database Backup Report"))
+			Expect(server.data).To(ContainSubstring("filename=report.json"))
+		})
+		It("logs a warning if the SMTP server is unreachable", func() {
+			port := server.Port()
+			server.Close()
+			setFiles(map[string]string{
+				"/home/gpadmin/email.yaml": fmt.Sprintf(`smtp:
+  host: 127.0.0.1
+  port: %d
+  from: gpadmin@example.com
+contacts:
+  gprestore:
+  - address: contact1@example.com
+    status:
+      failure: true`, port),
+				"/backups/report": "Greenplum Database Restore Report",
+			})
+
+			utils.SendEmailReport("/home/gpadmin/email.yaml", "gprestore", "20170101010101", "failure", "/backups/report", "/backups/report.json")
+
+			Expect(string(logfile.Contents())).To(ContainSubstring("Unable to send email report"))
+		})
+	})
+})
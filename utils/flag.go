@@ -5,20 +5,26 @@ package utils
  */
 
 import (
+	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gp-common-go-libs/operating"
 	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
 )
 
 const (
 	BACKUP_DIR            = "backup-dir"
 	COMPRESSION_LEVEL     = "compression-level"
+	CONFIG                = "config"
 	DATA_ONLY             = "data-only"
 	DBNAME                = "dbname"
 	DEBUG                 = "debug"
+	EMAIL_CONFIG          = "email-config"
 	EXCLUDE_RELATION      = "exclude-table"
 	EXCLUDE_RELATION_FILE = "exclude-table-file"
 	EXCLUDE_SCHEMA        = "exclude-schema"
@@ -28,18 +34,35 @@ const (
 	INCLUDE_SCHEMA        = "include-schema"
 	INCREMENTAL           = "incremental"
 	JOBS                  = "jobs"
+	LABEL                 = "label"
 	LEAF_PARTITION_DATA   = "leaf-partition-data"
+	METADATA_DIRECTORY    = "metadata-directory"
 	METADATA_ONLY         = "metadata-only"
+	METRICS_FILE          = "metrics-file"
 	NO_COMPRESSION        = "no-compression"
+	NOTIFICATION_CONFIG   = "notification-config"
 	PLUGIN_CONFIG         = "plugin-config"
 	QUIET                 = "quiet"
+	RETENTION             = "retention"
+	RETENTION_DRY_RUN     = "retention-dry-run"
 	SINGLE_DATA_FILE      = "single-data-file"
 	VERBOSE               = "verbose"
+	WITH_CHECKSUMS        = "with-checksums"
 	WITH_STATS            = "with-stats"
+	BEFORE                = "before"
 	CREATE_DB             = "create-db"
+	LATEST                = "latest"
+	MAX_RETRIES           = "max-retries"
 	ON_ERROR_CONTINUE     = "on-error-continue"
+	PRINT_SQL             = "print-sql"
 	REDIRECT_DB           = "redirect-db"
+	REDIRECT_SCHEMA       = "redirect-schema"
+	RESIZE_CLUSTER        = "resize-cluster"
+	TABLE_MAP_FILE        = "table-map-file"
 	TIMESTAMP             = "timestamp"
+	TRUNCATE_TABLE        = "truncate-table"
+	UPSERT                = "upsert"
+	WITH_DEPENDENCIES     = "with-dependencies"
 	WITH_GLOBALS          = "with-globals"
 )
 
@@ -60,10 +83,42 @@ func CheckExclusiveFlags(flags *pflag.FlagSet, flagNames ...string) {
 	}
 }
 
+// All of the flags passed to this function must be set
+func CheckMandatoryFlags(flags *pflag.FlagSet, flagNames ...string) {
+	for _, name := range flagNames {
+		if !flags.Changed(name) {
+			gplog.Fatal(errors.Errorf("Flag --%s is required", name), "")
+		}
+	}
+}
+
 /*
  * Functions for validating flag values
  */
 
+/*
+ * Parses labels in key=value format into a map, returning an error if a label
+ * is malformed or if the same key is given more than once.
+ */
+func ParseLabels(labelStrs []string) (map[string]string, error) {
+	if len(labelStrs) == 0 {
+		return nil, nil
+	}
+	labels := make(map[string]string, len(labelStrs))
+	for _, labelStr := range labelStrs {
+		keyValue := strings.SplitN(labelStr, "=", 2)
+		if len(keyValue) != 2 || strings.TrimSpace(keyValue[0]) == "" {
+			return nil, errors.Errorf("Label %s is invalid.  Labels must be in the format key=value.", labelStr)
+		}
+		key := strings.TrimSpace(keyValue[0])
+		if _, ok := labels[key]; ok {
+			return nil, errors.Errorf("Label %s is specified more than once", key)
+		}
+		labels[key] = keyValue[1]
+	}
+	return labels, nil
+}
+
 /*
  * Convert arguments that contain a single dash to double dashes for backward
  * compatibility.
@@ -107,3 +162,86 @@ func MustGetFlagStringArray(cmdFlags *pflag.FlagSet, flagName string) []string {
 	gplog.FatalOnError(err)
 	return value
 }
+
+/*
+ * Functions for reading flag values from a configuration file
+ */
+
+/*
+ * Sets each flag in the YAML configuration file that was not set on the
+ * command line.  Keys are flag names without the leading dashes, and a list
+ * value sets a flag that can be specified multiple times once per element.
+ * Values equal to a flag's default are ignored.
+ * Flags set from the file are marked as changed, so that they are validated
+ * in the same way as flags set on the command line.
+ */
+func ApplyConfigFile(flags *pflag.FlagSet, filename string) error {
+	contents, err := operating.System.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	config := make(map[string]interface{}, 0)
+	err = yaml.Unmarshal(contents, &config)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("Unable to parse config file %s", filename))
+	}
+	names := make([]string, 0)
+	for name := range config {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if flags.Lookup(name) == nil || name == CONFIG || name == "help" || name == "version" {
+			return errors.Errorf("Config file %s contains unknown flag %s", filename, name)
+		}
+		if flags.Changed(name) {
+			gplog.Verbose("Ignoring --%s in config file %s, as it was specified on the command line", name, filename)
+			continue
+		}
+		values, isList := config[name].([]interface{})
+		if !isList {
+			// Setting a flag to its default would make it conflict with flags given on the command line for no reason
+			if config[name] == nil || fmt.Sprintf("%v", config[name]) == flags.Lookup(name).DefValue {
+				continue
+			}
+			values = []interface{}{config[name]}
+		}
+		for _, value := range values {
+			err = flags.Set(name, fmt.Sprintf("%v", value))
+			if err != nil {
+				return errors.Wrap(err, fmt.Sprintf("Invalid value for %s in config file %s", name, filename))
+			}
+		}
+	}
+	return nil
+}
+
+/*
+ * Returns every flag that was set, either on the command line or in a config
+ * file, in a form that could be passed back to the utility on the command line.
+ */
+func GetEffectiveFlags(flags *pflag.FlagSet) []string {
+	effectiveFlags := make([]string, 0)
+	flags.Visit(func(flag *pflag.Flag) {
+		var values []string
+		switch flag.Value.Type() {
+		case "stringSlice":
+			values, _ = flags.GetStringSlice(flag.Name)
+		case "stringArray":
+			values, _ = flags.GetStringArray(flag.Name)
+		case "bool":
+			if flag.Value.String() == "true" {
+				effectiveFlags = append(effectiveFlags, fmt.Sprintf("--%s", flag.Name))
+			} else {
+				effectiveFlags = append(effectiveFlags, fmt.Sprintf("--%s=false", flag.Name))
+			}
+			return
+		default:
+			values = []string{flag.Value.String()}
+		}
+		for _, value := range values {
+			effectiveFlags = append(effectiveFlags, fmt.Sprintf("--%s=%s", flag.Name, value))
+		}
+	})
+	return effectiveFlags
+}
@@ -3,6 +3,7 @@ package utils_test
 import (
 	"flag"
 
+	"github.com/greenplum-db/gp-common-go-libs/operating"
 	"github.com/greenplum-db/gp-common-go-libs/testhelper"
 	"github.com/greenplum-db/gpbackup/utils"
 	"github.com/spf13/pflag"
@@ -19,6 +20,12 @@ var _ = Describe("utils/flag tests", func() {
 			_ = flagSet.String("stringFlag", "", "This is a sample string flag.")
 			_ = flagSet.Bool("boolFlag", false, "This is a sample bool flag.")
 			_ = flagSet.Int("intFlag", 0, "This is a sample int flag.")
+			_ = flagSet.StringSlice("sliceFlag", []string{}, "This is a sample string slice flag.")
+			_ = flagSet.StringArray("arrayFlag", []string{}, "This is a sample string array flag.")
+			_ = flagSet.String(utils.CONFIG, "", "This is the config file flag.")
+		})
+		AfterEach(func() {
+			operating.System = operating.InitializeSystemFunctions()
 		})
 		Context("CheckExclusiveFlags", func() {
 			It("does not panic if no flags in the argument list are set", func() {
@@ -39,6 +46,106 @@ var _ = Describe("utils/flag tests", func() {
 				utils.CheckExclusiveFlags(flagSet, "stringFlag", "boolFlag")
 			})
 		})
+		Context("CheckMandatoryFlags", func() {
+			It("does not panic if all flags in the argument list are set", func() {
+				Expect(flagSet.Parse([]string{"--stringFlag", "foo", "--intFlag", "42"})).To(Succeed())
+				utils.CheckMandatoryFlags(flagSet, "stringFlag", "intFlag")
+			})
+			It("panics if a flag in the argument list is not set", func() {
+				Expect(flagSet.Parse([]string{"--stringFlag", "foo"})).To(Succeed())
+				defer testhelper.ShouldPanicWithMessage("Flag --intFlag is required")
+				utils.CheckMandatoryFlags(flagSet, "stringFlag", "intFlag")
+			})
+		})
+		Context("ApplyConfigFile", func() {
+			setConfigFile := func(contents string) {
+				operating.System.ReadFile = func(filename string) ([]byte, error) {
+					return []byte(contents), nil
+				}
+			}
+			It("sets flags that were not set on the command line", func() {
+				Expect(flagSet.Parse([]string{"--stringFlag", "foo"})).To(Succeed())
+				setConfigFile(`stringFlag: bar
+intFlag: 42
+boolFlag: true
+sliceFlag: [public, pg_catalog]
+arrayFlag:
+- public.foo
+- public.bar`)
+
+				err := utils.ApplyConfigFile(flagSet, "/home/gpadmin/gpbackup.yaml")
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(utils.MustGetFlagString(flagSet, "stringFlag")).To(Equal("foo"))
+				Expect(utils.MustGetFlagInt(flagSet, "intFlag")).To(Equal(42))
+				Expect(utils.MustGetFlagBool(flagSet, "boolFlag")).To(BeTrue())
+				Expect(utils.MustGetFlagStringSlice(flagSet, "sliceFlag")).To(Equal([]string{"public", "pg_catalog"}))
+				Expect(utils.MustGetFlagStringArray(flagSet, "arrayFlag")).To(Equal([]string{"public.foo", "public.bar"}))
+				Expect(flagSet.Changed("intFlag")).To(BeTrue())
+			})
+			It("does not set flags to their default values", func() {
+				setConfigFile(`boolFlag: false
+intFlag: 0`)
+
+				err := utils.ApplyConfigFile(flagSet, "/home/gpadmin/gpbackup.yaml")
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(flagSet.Changed("boolFlag")).To(BeFalse())
+				Expect(flagSet.Changed("intFlag")).To(BeFalse())
+			})
+			It("returns an error for an unknown flag", func() {
+				setConfigFile(`unknownFlag: foo`)
+
+				err := utils.ApplyConfigFile(flagSet, "/home/gpadmin/gpbackup.yaml")
+
+				Expect(err).To(MatchError("Config file /home/gpadmin/gpbackup.yaml contains unknown flag unknownFlag"))
+			})
+			It("returns an error if the config file sets the config flag", func() {
+				setConfigFile(`config: /home/gpadmin/other.yaml`)
+
+				err := utils.ApplyConfigFile(flagSet, "/home/gpadmin/gpbackup.yaml")
+
+				Expect(err).To(MatchError("Config file /home/gpadmin/gpbackup.yaml contains unknown flag config"))
+			})
+			It("returns an error for an invalid value", func() {
+				setConfigFile(`intFlag: foo`)
+
+				err := utils.ApplyConfigFile(flagSet, "/home/gpadmin/gpbackup.yaml")
+
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Invalid value for intFlag in config file /home/gpadmin/gpbackup.yaml"))
+			})
+		})
+		Context("GetEffectiveFlags", func() {
+			It("returns each flag that was set in sorted order", func() {
+				Expect(flagSet.Parse([]string{"--stringFlag", "foo", "--boolFlag", "--arrayFlag", "public.foo", "--arrayFlag", "public.bar", "--sliceFlag", "public"})).To(Succeed())
+
+				Expect(utils.GetEffectiveFlags(flagSet)).To(Equal([]string{"--arrayFlag=public.foo", "--arrayFlag=public.bar", "--boolFlag", "--sliceFlag=public", "--stringFlag=foo"}))
+			})
+		})
+		Context("ParseLabels", func() {
+			It("parses labels in key=value format", func() {
+				labels, err := utils.ParseLabels([]string{"release=pre-5.1", "note=a=b"})
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(labels).To(Equal(map[string]string{"release": "pre-5.1", "note": "a=b"}))
+			})
+			It("returns an error for a label without a value", func() {
+				_, err := utils.ParseLabels([]string{"release"})
+
+				Expect(err).To(MatchError("Label release is invalid.  Labels must be in the format key=value."))
+			})
+			It("returns an error for a label without a key", func() {
+				_, err := utils.ParseLabels([]string{"=pre-5.1"})
+
+				Expect(err).To(MatchError("Label =pre-5.1 is invalid.  Labels must be in the format key=value."))
+			})
+			It("returns an error for a key specified more than once", func() {
+				_, err := utils.ParseLabels([]string{"release=pre-5.1", "release=pre-5.2"})
+
+				Expect(err).To(MatchError("Label release is specified more than once"))
+			})
+		})
 		Context("HandleSingleDashes", func() {
 			It("replaces single dash at beginning of command", func() {
 				result := utils.HandleSingleDashes([]string{"-some_flag", "some_argument"})
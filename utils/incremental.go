@@ -0,0 +1,127 @@
+package utils
+
+/*
+ * This file contains functions related to checking that every backup in an
+ * incremental backup chain can still be used for restore.
+ */
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/greenplum-db/gp-common-go-libs/cluster"
+	"github.com/greenplum-db/gp-common-go-libs/iohelper"
+	"github.com/greenplum-db/gpbackup/backup_filepath"
+	"github.com/greenplum-db/gpbackup/backup_history"
+)
+
+/*
+ * Checks the config and table of contents files of each backup in the chain
+ * and the number of data files each backup has on every segment, returning a
+ * description of every missing piece so that a broken chain can be reported
+ * in full before any data is restored.  Backups stored through a plugin have
+ * their master files retrieved with the plugin, but their data files cannot
+ * be listed and so are not counted.  Data files are also not counted if
+ * countDataFiles is false, such as when the backups were taken on a cluster
+ * with a different number of segments.
+ */
+func ValidateBackupChain(c *cluster.Cluster, fpInfoList []backup_filepath.FilePathInfo, plugin *PluginConfig, countDataFiles bool) []string {
+	problems := make([]string, 0)
+	fpInfosToCount := make([]backup_filepath.FilePathInfo, 0)
+	expectedCounts := make([]int, 0)
+	for _, fpInfo := range fpInfoList {
+		configFilename := fpInfo.GetConfigFilePath()
+		tocFilename := fpInfo.GetTOCFilePath()
+		hasConfig := isBackupFileAvailable(configFilename, plugin)
+		if !hasConfig {
+			problems = append(problems, fmt.Sprintf("Backup %s is missing config file %s", fpInfo.Timestamp, configFilename))
+		}
+		hasTOC := isBackupFileAvailable(tocFilename, plugin)
+		if !hasTOC {
+			problems = append(problems, fmt.Sprintf("Backup %s is missing table of contents file %s", fpInfo.Timestamp, tocFilename))
+		}
+		if !hasConfig {
+			continue
+		}
+		config := backup_history.ReadConfigFile(configFilename)
+		if !config.IsSuccessful() {
+			problems = append(problems, fmt.Sprintf("Backup %s did not complete successfully.  Backup status: %s", fpInfo.Timestamp, config.Status))
+		}
+		if plugin != nil || !countDataFiles || !hasTOC || config.MetadataOnly {
+			continue
+		}
+		fpInfosToCount = append(fpInfosToCount, fpInfo)
+		expectedCounts = append(expectedCounts, GetExpectedDataFileCount(config, tocFilename))
+	}
+	if len(fpInfosToCount) > 0 {
+		problems = append(problems, checkDataFileCounts(c, fpInfosToCount, expectedCounts)...)
+	}
+	return problems
+}
+
+func isBackupFileAvailable(filename string, plugin *PluginConfig) bool {
+	if plugin != nil {
+		return plugin.RestoreFile(filename) == nil
+	}
+	return iohelper.FileExistsAndIsReadable(filename)
+}
+
+// Each segment of a multiple data file backup has one data file per table
+func GetExpectedDataFileCount(config *backup_history.BackupConfig, tocFilename string) int {
+	if config.SingleDataFile {
+		return 2 // 1 for the data file, 1 for the segment TOC file
+	}
+	return len(NewTOC(tocFilename).DataEntries)
+}
+
+/*
+ * Every segment counts the files of all backups in a single command, printing
+ * one count per backup in order.  A missing directory is counted as empty.
+ * Returns the counts of each segment in the order of fpInfoList.
+ */
+func CountDataFilesOnSegments(c *cluster.Cluster, fpInfoList []backup_filepath.FilePathInfo) map[int][]int {
+	remoteOutput := c.GenerateAndExecuteCommand("Verifying backup file counts", func(contentID int) string {
+		counts := make([]string, 0)
+		for _, fpInfo := range fpInfoList {
+			counts = append(counts, fmt.Sprintf("find %s -type f 2>/dev/null | wc -l", fpInfo.GetDirForContent(contentID)))
+		}
+		return strings.Join(counts, "; ")
+	}, cluster.ON_SEGMENTS)
+	c.CheckClusterError(remoteOutput, "Could not verify backup file counts", func(contentID int) string {
+		return "Could not verify backup file counts"
+	})
+
+	counts := make(map[int][]int, 0)
+	for _, contentID := range c.ContentIDs {
+		if contentID == -1 {
+			continue
+		}
+		numsFound := strings.Fields(remoteOutput.Stdouts[contentID])
+		counts[contentID] = make([]int, len(fpInfoList))
+		for i := range fpInfoList {
+			if i < len(numsFound) {
+				counts[contentID][i], _ = strconv.Atoi(numsFound[i])
+			}
+		}
+	}
+	return counts
+}
+
+func checkDataFileCounts(c *cluster.Cluster, fpInfoList []backup_filepath.FilePathInfo, expectedCounts []int) []string {
+	counts := CountDataFilesOnSegments(c, fpInfoList)
+	problems := make([]string, 0)
+	for _, contentID := range c.ContentIDs {
+		if contentID == -1 {
+			continue
+		}
+		for i, fpInfo := range fpInfoList {
+			numFound := counts[contentID][i]
+			if numFound != expectedCounts[i] {
+				problems = append(problems, fmt.Sprintf("Backup %s has %d data files in %s on segment %d, but should have %d",
+					fpInfo.Timestamp, numFound, fpInfo.GetDirForContent(contentID), contentID, expectedCounts[i]))
+			}
+		}
+	}
+	return problems
+}
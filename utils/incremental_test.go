@@ -0,0 +1,108 @@
+package utils_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/greenplum-db/gp-common-go-libs/cluster"
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/greenplum-db/gpbackup/backup_filepath"
+	"github.com/greenplum-db/gpbackup/backup_history"
+	"github.com/greenplum-db/gpbackup/utils"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+)
+
+var _ = Describe("utils/incremental tests", func() {
+	var (
+		directory    string
+		testCluster  *cluster.Cluster
+		testExecutor *testhelper.TestExecutor
+		fpInfoList   []backup_filepath.FilePathInfo
+	)
+	writeYAMLFile := func(filename string, contents interface{}) {
+		Expect(os.MkdirAll(path.Dir(filename), 0755)).To(Succeed())
+		bytes, err := yaml.Marshal(contents)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ioutil.WriteFile(filename, bytes, 0644)).To(Succeed())
+	}
+	writeBackupFiles := func(fpInfo backup_filepath.FilePathInfo, config backup_history.BackupConfig, numDataEntries int) {
+		writeYAMLFile(fpInfo.GetConfigFilePath(), config)
+		toc := utils.TOC{DataEntries: make([]utils.MasterDataEntry, numDataEntries)}
+		writeYAMLFile(fpInfo.GetTOCFilePath(), toc)
+	}
+	BeforeEach(func() {
+		var err error
+		directory, err = ioutil.TempDir("", "gpbackup_incremental_test")
+		Expect(err).ToNot(HaveOccurred())
+		testExecutor = &testhelper.TestExecutor{ClusterOutput: &cluster.RemoteOutput{Stdouts: map[int]string{0: "2\n1\n", 1: "2\n1\n"}}}
+		testCluster = cluster.NewCluster([]cluster.SegConfig{
+			{ContentID: -1, Hostname: "localhost", DataDir: path.Join(directory, "gpseg-1")},
+			{ContentID: 0, Hostname: "remotehost1", DataDir: path.Join(directory, "gpseg0")},
+			{ContentID: 1, Hostname: "remotehost2", DataDir: path.Join(directory, "gpseg1")},
+		})
+		testCluster.Executor = testExecutor
+		fpInfoList = []backup_filepath.FilePathInfo{
+			backup_filepath.NewFilePathInfo(testCluster, "", "20170101010101", ""),
+			backup_filepath.NewFilePathInfo(testCluster, "", "20170102010101", ""),
+		}
+	})
+	AfterEach(func() {
+		_ = os.RemoveAll(directory)
+	})
+	Describe("ValidateBackupChain", func() {
+		It("finds no problems if every backup in the chain is complete", func() {
+			writeBackupFiles(fpInfoList[0], backup_history.BackupConfig{Timestamp: "20170101010101"}, 2)
+			writeBackupFiles(fpInfoList[1], backup_history.BackupConfig{Timestamp: "20170102010101", Incremental: true}, 1)
+
+			problems := utils.ValidateBackupChain(testCluster, fpInfoList, nil, true)
+
+			Expect(problems).To(BeEmpty())
+			Expect(testExecutor.NumExecutions).To(Equal(1))
+			Expect(testExecutor.ClusterCommands[0][0]).To(ContainElement("find " + path.Join(directory, "gpseg0/backups/20170101/20170101010101") +
+				" -type f 2>/dev/null | wc -l; find " + path.Join(directory, "gpseg0/backups/20170102/20170102010101") + " -type f 2>/dev/null | wc -l"))
+		})
+		It("reports every missing file and incorrect data file count", func() {
+			writeBackupFiles(fpInfoList[1], backup_history.BackupConfig{Timestamp: "20170102010101", Incremental: true}, 1)
+			testExecutor.ClusterOutput.Stdouts = map[int]string{0: "1\n", 1: "0\n"}
+
+			problems := utils.ValidateBackupChain(testCluster, fpInfoList, nil, true)
+
+			Expect(problems).To(Equal([]string{
+				"Backup 20170101010101 is missing config file " + fpInfoList[0].GetConfigFilePath(),
+				"Backup 20170101010101 is missing table of contents file " + fpInfoList[0].GetTOCFilePath(),
+				"Backup 20170102010101 has 0 data files in " + fpInfoList[1].GetDirForContent(1) + " on segment 1, but should have 1",
+			}))
+		})
+		It("reports backups that did not complete successfully", func() {
+			writeBackupFiles(fpInfoList[0], backup_history.BackupConfig{Timestamp: "20170101010101", SingleDataFile: true}, 5)
+			writeBackupFiles(fpInfoList[1], backup_history.BackupConfig{Timestamp: "20170102010101", Status: backup_history.BackupStatusFailed}, 1)
+
+			problems := utils.ValidateBackupChain(testCluster, fpInfoList, nil, true)
+
+			Expect(problems).To(Equal([]string{"Backup 20170102010101 did not complete successfully.  Backup status: failed"}))
+		})
+		It("does not count data files if asked not to", func() {
+			writeBackupFiles(fpInfoList[0], backup_history.BackupConfig{Timestamp: "20170101010101"}, 2)
+			writeBackupFiles(fpInfoList[1], backup_history.BackupConfig{Timestamp: "20170102010101", Incremental: true}, 1)
+
+			problems := utils.ValidateBackupChain(testCluster, fpInfoList, nil, false)
+
+			Expect(problems).To(BeEmpty())
+			Expect(testExecutor.NumExecutions).To(Equal(0))
+		})
+		It("reports files that the plugin cannot retrieve without counting data files", func() {
+			plugin := &utils.PluginConfig{ExecutablePath: "false", ConfigPath: "/tmp/plugin_config.yaml"}
+
+			problems := utils.ValidateBackupChain(testCluster, fpInfoList[:1], plugin, true)
+
+			Expect(problems).To(Equal([]string{
+				"Backup 20170101010101 is missing config file " + fpInfoList[0].GetConfigFilePath(),
+				"Backup 20170101010101 is missing table of contents file " + fpInfoList[0].GetTOCFilePath(),
+			}))
+			Expect(testExecutor.NumExecutions).To(Equal(0))
+		})
+	})
+})
@@ -8,6 +8,7 @@ package utils
 import (
 	"fmt"
 	"io"
+	"regexp"
 	"strings"
 
 	"github.com/greenplum-db/gp-common-go-libs/dbconn"
@@ -34,6 +35,53 @@ func QuoteIdent(connectionPool *dbconn.DBConn, ident string) string {
 	return dbconn.MustSelectString(connectionPool, fmt.Sprintf(`SELECT quote_ident('%s')`, EscapeSingleQuotes(ident)))
 }
 
+/*
+ * Quotes an identifier the way quote_ident does, for when there is no database
+ * connection, so that statements printed without a connection match the ones
+ * executed with one.  Like quote_ident, it quotes every keyword that cannot be
+ * used as a column name without quotes.
+ */
+func QuoteIdentWithoutConnection(ident string) string {
+	if regexp.MustCompile(`^[a-z_][a-z0-9_]*$`).MatchString(ident) && !quotedKeywords[ident] {
+		return ident
+	}
+	return fmt.Sprintf(`"%s"`, strings.Replace(ident, `"`, `""`, -1))
+}
+
+// The reserved, column name, and type or function name keywords of GPDB
+var quotedKeywords = map[string]bool{
+	"all": true, "analyse": true, "analyze": true, "and": true, "any": true, "array": true,
+	"as": true, "asc": true, "asymmetric": true, "authorization": true, "between": true,
+	"bigint": true, "binary": true, "bit": true, "boolean": true, "both": true, "case": true,
+	"cast": true, "char": true, "character": true, "check": true, "coalesce": true, "collate": true,
+	"collation": true, "column": true, "concurrently": true, "constraint": true, "create": true,
+	"cross": true, "cube": true, "current_catalog": true, "current_date": true, "current_role": true,
+	"current_schema": true, "current_time": true, "current_timestamp": true, "current_user": true,
+	"dec": true, "decimal": true, "decode": true, "default": true, "deferrable": true, "desc": true,
+	"distinct": true, "distributed": true, "do": true, "else": true, "end": true, "except": true,
+	"exclude": true, "exists": true, "extract": true, "false": true, "fetch": true, "filter": true,
+	"float": true, "following": true, "for": true, "foreign": true, "freeze": true, "from": true,
+	"full": true, "grant": true, "greatest": true, "group": true, "grouping": true, "having": true,
+	"ilike": true, "in": true, "initially": true, "inner": true, "inout": true, "int": true,
+	"integer": true, "intersect": true, "interval": true, "into": true, "is": true, "isnull": true,
+	"join": true, "lateral": true, "leading": true, "least": true, "left": true, "like": true,
+	"limit": true, "localtime": true, "localtimestamp": true, "log": true, "national": true,
+	"natural": true, "nchar": true, "new": true, "none": true, "not": true, "notnull": true,
+	"null": true, "nullif": true, "numeric": true, "off": true, "offset": true, "old": true,
+	"on": true, "only": true, "or": true, "order": true, "out": true, "outer": true, "over": true,
+	"overlaps": true, "overlay": true, "partition": true, "placing": true, "position": true,
+	"preceding": true, "precision": true, "primary": true, "real": true, "references": true,
+	"returning": true, "right": true, "rollup": true, "row": true, "rows": true, "scatter": true,
+	"select": true, "session_user": true, "setof": true, "sets": true, "similar": true,
+	"smallint": true, "some": true, "substring": true, "symmetric": true, "table": true, "then": true,
+	"time": true, "timestamp": true, "to": true, "trailing": true, "treat": true, "trim": true,
+	"true": true, "unbounded": true, "union": true, "unique": true, "user": true, "using": true,
+	"values": true, "varchar": true, "variadic": true, "verbose": true, "when": true, "where": true,
+	"window": true, "with": true, "xmlattributes": true, "xmlconcat": true, "xmlelement": true,
+	"xmlexists": true, "xmlforest": true, "xmlparse": true, "xmlpi": true, "xmlroot": true,
+	"xmlserialize": true,
+}
+
 func SliceToQuotedString(slice []string) string {
 	quotedStrings := make([]string, len(slice))
 	for i, str := range slice {
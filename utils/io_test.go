@@ -45,6 +45,24 @@ var _ = Describe("utils/io tests", func() {
 			Expect(resultString).To(Equal(`"test`))
 		})
 	})
+	Describe("QuoteIdentWithoutConnection", func() {
+		It("does not quote an identifier that does not need quoting", func() {
+			Expect(utils.QuoteIdentWithoutConnection("sales_archive")).To(Equal("sales_archive"))
+		})
+		It("quotes a keyword", func() {
+			Expect(utils.QuoteIdentWithoutConnection("user")).To(Equal(`"user"`))
+		})
+		It("does not quote a keyword that can be used as a name", func() {
+			Expect(utils.QuoteIdentWithoutConnection("schema")).To(Equal("schema"))
+		})
+		It("quotes an identifier with uppercase or special characters", func() {
+			Expect(utils.QuoteIdentWithoutConnection("New DB")).To(Equal(`"New DB"`))
+			Expect(utils.QuoteIdentWithoutConnection("sales$1")).To(Equal(`"sales$1"`))
+		})
+		It("escapes double quotes", func() {
+			Expect(utils.QuoteIdentWithoutConnection(`"test`)).To(Equal(`"""test"`))
+		})
+	})
 	Describe("SliceToQuotedString", func() {
 		It("quotes and joins a slice of strings into a single string", func() {
 			inputStrings := []string{"string1", "string2", "string3"}
@@ -0,0 +1,136 @@
+package utils
+
+/*
+ * This file contains structs and functions related to storing backup metadata
+ * in directory format, where each TOC entry is written to its own file
+ * instead of being addressed by byte offsets into a single metadata file.
+ */
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gp-common-go-libs/iohelper"
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/pkg/errors"
+)
+
+var (
+	invalidPathCharacters = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+	maxPathComponentLen   = 64
+)
+
+/*
+ * Object names may contain any character, including slashes, so only a
+ * conservative subset is kept for file names.  The entry's index within its
+ * section is prepended to the file name, so names that sanitize to the same
+ * string cannot collide.
+ */
+func sanitizePathComponent(name string) string {
+	sanitized := strings.Trim(invalidPathCharacters.ReplaceAllString(name, "_"), "._")
+	if len(sanitized) > maxPathComponentLen {
+		sanitized = sanitized[:maxPathComponentLen]
+	}
+	if sanitized == "" {
+		sanitized = "_"
+	}
+	return sanitized
+}
+
+func GetMetadataEntryFilePath(section string, index int, entry MetadataEntry) string {
+	schemaDir := "_global"
+	if entry.Schema != "" {
+		schemaDir = sanitizePathComponent(entry.Schema)
+	}
+	typeDir := sanitizePathComponent(strings.ToLower(entry.ObjectType))
+	filename := fmt.Sprintf("%06d_%s.sql", index, sanitizePathComponent(entry.Name))
+	return path.Join(section, schemaDir, typeDir, filename)
+}
+
+/*
+ * Writes each entry of the given sections to its own file under directory,
+ * recording the relative path of each file in the TOC.  The byte offsets are
+ * left unchanged so they still identify entries uniquely within a section.
+ */
+func (toc *TOC) WriteMetadataDirectory(metadataFile io.ReaderAt, directory string, sections ...string) {
+	for _, section := range sections {
+		entries := *toc.metadataEntryMap[section]
+		for i := range entries {
+			entries[i].FilePath = GetMetadataEntryFilePath(section, i, entries[i])
+			contents := make([]byte, entries[i].EndByte-entries[i].StartByte)
+			_, err := metadataFile.ReadAt(contents, int64(entries[i].StartByte))
+			gplog.FatalOnError(err)
+
+			entryFilename := path.Join(directory, entries[i].FilePath)
+			err = operating.System.MkdirAll(path.Dir(entryFilename), 0755)
+			gplog.FatalOnError(err, fmt.Sprintf("Unable to create metadata directory %s", path.Dir(entryFilename)))
+			entryFile := iohelper.MustOpenFileForWriting(entryFilename)
+			MustPrintBytes(entryFile, contents)
+			err = entryFile.Close()
+			gplog.FatalOnError(err)
+			err = operating.System.Chmod(entryFilename, 0444)
+			gplog.FatalOnError(err)
+		}
+	}
+}
+
+/*
+ * Splits a metadata file written by gpbackup into directory format and removes
+ * the original file once all of its entries have been written.
+ */
+func (toc *TOC) SplitMetadataFileIntoDirectory(metadataFilename string, directory string, sections ...string) {
+	metadataFile := iohelper.MustOpenFileForReading(metadataFilename)
+	toc.WriteMetadataDirectory(metadataFile, directory, sections...)
+	_ = metadataFile.Close()
+	err := operating.System.Remove(metadataFilename)
+	gplog.FatalOnError(err, fmt.Sprintf("Unable to remove metadata file %s", metadataFilename))
+}
+
+/*
+ * MetadataDirectoryReader allows GetSQLStatementForObjectTypes to read
+ * statements from a metadata directory in the same way it reads them from a
+ * single metadata file, by mapping each entry's starting byte offset to the
+ * file that holds the entry.
+ */
+type MetadataDirectoryReader struct {
+	directory string
+	entryMap  map[uint64]MetadataEntry
+}
+
+func NewMetadataDirectoryReader(directory string, toc *TOC, section string) *MetadataDirectoryReader {
+	reader := &MetadataDirectoryReader{directory: directory, entryMap: make(map[uint64]MetadataEntry, 0)}
+	for _, entry := range *toc.metadataEntryMap[section] {
+		if entry.EndByte > entry.StartByte {
+			reader.entryMap[entry.StartByte] = entry
+		}
+	}
+	return reader
+}
+
+func (reader *MetadataDirectoryReader) ReadAt(contents []byte, offset int64) (int, error) {
+	if len(contents) == 0 {
+		return 0, nil
+	}
+	entry, ok := reader.entryMap[uint64(offset)]
+	if !ok || entry.FilePath == "" {
+		return 0, errors.Errorf("No metadata file found in %s for entry at offset %d", reader.directory, offset)
+	}
+	entryFilename := path.Join(reader.directory, entry.FilePath)
+	statement, err := operating.System.ReadFile(entryFilename)
+	if err != nil {
+		return 0, err
+	}
+	if len(statement) != len(contents) {
+		return 0, errors.Errorf("Metadata file %s is %d bytes, expected %d bytes", entryFilename, len(statement), len(contents))
+	}
+	return copy(contents, statement), nil
+}
+
+func MetadataDirectoryExists(directory string) bool {
+	info, err := operating.System.Stat(directory)
+	return err == nil && info.IsDir()
+}
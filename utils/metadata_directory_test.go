@@ -0,0 +1,121 @@
+package utils_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/greenplum-db/gpbackup/testutils"
+	"github.com/greenplum-db/gpbackup/utils"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("utils/metadata_directory tests", func() {
+	schemaStatement := "CREATE SCHEMA schema1;\n"
+	tableStatement := "CREATE TABLE schema1.table1 (i int);\n"
+	functionStatement := "CREATE FUNCTION schema1.\"my/func\"() RETURNS integer AS $$ SELECT 1 $$ LANGUAGE sql;\n"
+	indexStatement := "CREATE INDEX idx1 ON schema1.table1 USING btree (i);\n"
+
+	var metadataFile *bytes.Reader
+	var directory string
+	BeforeEach(func() {
+		toc, backupfile = testutils.InitializeTestTOC(buffer, "metadata")
+		start := uint64(0)
+		end := uint64(len(schemaStatement))
+		toc.AddMetadataEntry("predata", utils.MetadataEntry{Schema: "schema1", Name: "schema1", ObjectType: "SCHEMA"}, start, end)
+		start, end = end, end+uint64(len(tableStatement))
+		toc.AddMetadataEntry("predata", utils.MetadataEntry{Schema: "schema1", Name: "table1", ObjectType: "TABLE"}, start, end)
+		start, end = end, end+uint64(len(functionStatement))
+		toc.AddMetadataEntry("predata", utils.MetadataEntry{Schema: "schema1", Name: "my/func", ObjectType: "FUNCTION"}, start, end)
+		start, end = end, end+uint64(len(indexStatement))
+		toc.AddMetadataEntry("postdata", utils.MetadataEntry{Schema: "schema1", Name: "idx1", ObjectType: "INDEX", ReferenceObject: "schema1.table1"}, start, end)
+		metadataFile = bytes.NewReader([]byte(schemaStatement + tableStatement + functionStatement + indexStatement))
+
+		var err error
+		directory, err = ioutil.TempDir("", "gpbackup_metadata_test")
+		Expect(err).ToNot(HaveOccurred())
+	})
+	AfterEach(func() {
+		_ = os.RemoveAll(directory)
+	})
+	Describe("GetMetadataEntryFilePath", func() {
+		It("groups entries by section, schema, and object type", func() {
+			entry := utils.MetadataEntry{Schema: "schema1", Name: "table1", ObjectType: "TABLE"}
+			Expect(utils.GetMetadataEntryFilePath("predata", 3, entry)).To(Equal("predata/schema1/table/000003_table1.sql"))
+		})
+		It("uses a placeholder directory for objects without a schema", func() {
+			entry := utils.MetadataEntry{Schema: "", Name: "testrole", ObjectType: "ROLE GRANT"}
+			Expect(utils.GetMetadataEntryFilePath("global", 12, entry)).To(Equal("global/_global/role_grant/000012_testrole.sql"))
+		})
+		It("replaces characters that are not safe in file names", func() {
+			entry := utils.MetadataEntry{Schema: "\"my schema\"", Name: "../my/func(integer, text)", ObjectType: "FUNCTION"}
+			Expect(utils.GetMetadataEntryFilePath("predata", 0, entry)).To(Equal("predata/my_schema/function/000000_my_func_integer_text.sql"))
+		})
+	})
+	Describe("WriteMetadataDirectory", func() {
+		It("writes one file per entry and records its path in the TOC", func() {
+			toc.WriteMetadataDirectory(metadataFile, directory, "predata", "postdata")
+
+			Expect(toc.PredataEntries[1].FilePath).To(Equal("predata/schema1/table/000001_table1.sql"))
+			Expect(toc.PredataEntries[2].FilePath).To(Equal("predata/schema1/function/000002_my_func.sql"))
+			Expect(toc.PostdataEntries[0].FilePath).To(Equal("postdata/schema1/index/000000_idx1.sql"))
+			contents, err := ioutil.ReadFile(path.Join(directory, "predata/schema1/table/000001_table1.sql"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(contents)).To(Equal(tableStatement))
+			contents, err = ioutil.ReadFile(path.Join(directory, "postdata/schema1/index/000000_idx1.sql"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(contents)).To(Equal(indexStatement))
+		})
+	})
+	Describe("MetadataDirectoryReader", func() {
+		var noObj, noSchema, noRelation []string
+		BeforeEach(func() {
+			toc.WriteMetadataDirectory(metadataFile, directory, "predata", "postdata")
+		})
+		It("returns the same statements as the single metadata file", func() {
+			reader := utils.NewMetadataDirectoryReader(directory, toc, "predata")
+			statements := toc.GetSQLStatementForObjectTypes("predata", reader, noObj, noObj, noSchema, noSchema, noRelation, noRelation)
+			expectedStatements := toc.GetSQLStatementForObjectTypes("predata", metadataFile, noObj, noObj, noSchema, noSchema, noRelation, noRelation)
+
+			Expect(statements).To(Equal(expectedStatements))
+			Expect(statements[2].Statement).To(Equal(functionStatement))
+		})
+		It("applies object filters to the directory entries", func() {
+			reader := utils.NewMetadataDirectoryReader(directory, toc, "postdata")
+			statements := toc.GetSQLStatementForObjectTypes("postdata", reader, noObj, noObj, noSchema, noSchema, []string{"schema1.table1"}, noRelation)
+
+			Expect(statements).To(HaveLen(1))
+			Expect(statements[0].Statement).To(Equal(indexStatement))
+		})
+		It("returns an error if an entry file has been modified", func() {
+			entryFilename := path.Join(directory, toc.PredataEntries[1].FilePath)
+			_ = os.Chmod(entryFilename, 0644)
+			Expect(ioutil.WriteFile(entryFilename, []byte("DROP TABLE schema1.table1;\n"), 0644)).To(Succeed())
+			reader := utils.NewMetadataDirectoryReader(directory, toc, "predata")
+			contents := make([]byte, len(tableStatement))
+
+			_, err := reader.ReadAt(contents, int64(toc.PredataEntries[1].StartByte))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("is 27 bytes, expected 37 bytes"))
+		})
+	})
+	Describe("MetadataDirectoryExists", func() {
+		var originalStat func(string) (os.FileInfo, error)
+		BeforeEach(func() {
+			originalStat = operating.System.Stat
+			operating.System.Stat = os.Stat
+		})
+		AfterEach(func() {
+			operating.System.Stat = originalStat
+		})
+		It("returns true for an existing directory", func() {
+			Expect(utils.MetadataDirectoryExists(directory)).To(BeTrue())
+		})
+		It("returns false for a missing directory", func() {
+			Expect(utils.MetadataDirectoryExists(path.Join(directory, "missing"))).To(BeFalse())
+		})
+	})
+})
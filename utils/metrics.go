@@ -0,0 +1,301 @@
+package utils
+
+/*
+ * This file contains structs and functions related to writing metrics files
+ * in the Prometheus text exposition format, for use with the textfile
+ * collector of node_exporter.
+ */
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/greenplum-db/gp-common-go-libs/cluster"
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gp-common-go-libs/iohelper"
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/greenplum-db/gpbackup/backup_filepath"
+)
+
+type MetricSample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+type Metric struct {
+	Name    string
+	Help    string
+	Samples []MetricSample
+}
+
+func escapeLabelValue(value string) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	value = strings.Replace(value, `"`, `\"`, -1)
+	return strings.Replace(value, "\n", `\n`, -1)
+}
+
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	names := make([]string, 0)
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf(`%s="%s"`, name, escapeLabelValue(labels[name]))
+	}
+	return fmt.Sprintf("{%s}", strings.Join(pairs, ","))
+}
+
+func FormatMetrics(metrics []Metric) string {
+	metricStr := ""
+	for _, metric := range metrics {
+		metricStr += fmt.Sprintf("# HELP %s %s\n# TYPE %s gauge\n", metric.Name, metric.Help, metric.Name)
+		for _, sample := range metric.Samples {
+			metricStr += fmt.Sprintf("%s%s %s\n", metric.Name, formatLabels(sample.Labels), strconv.FormatFloat(sample.Value, 'f', -1, 64))
+		}
+	}
+	return metricStr
+}
+
+func boolToFloat(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+/*
+ * A metrics file is overwritten by every run, but the time of the last
+ * successful run must survive failed runs and runs against other databases,
+ * so the existing samples for that metric are carried over from the old file.
+ */
+func GetPreviousLastSuccessSamples(filename string, metricName string) map[string]float64 {
+	samples := make(map[string]float64, 0)
+	contents, err := operating.System.ReadFile(filename)
+	if err != nil {
+		return samples
+	}
+	prefix := fmt.Sprintf(`%s{database="`, metricName)
+	for _, line := range strings.Split(string(contents), "\n") {
+		if !strings.HasPrefix(line, prefix) {
+			continue
+		}
+		labelEnd := strings.LastIndex(line, `"} `)
+		if labelEnd < len(prefix) {
+			continue
+		}
+		value, err := strconv.ParseFloat(line[labelEnd+3:], 64)
+		if err != nil {
+			continue
+		}
+		// Label values use a subset of the escape sequences in Go string literals
+		database, err := strconv.Unquote(fmt.Sprintf(`"%s"`, line[len(prefix):labelEnd]))
+		if err != nil {
+			continue
+		}
+		samples[database] = value
+	}
+	return samples
+}
+
+func constructLastSuccessMetric(filename string, utility string, database string, status string, endTime time.Time) Metric {
+	metricName := fmt.Sprintf("%s_last_success_timestamp_seconds", utility)
+	lastSuccess := GetPreviousLastSuccessSamples(filename, metricName)
+	if status != "failure" {
+		lastSuccess[database] = float64(endTime.Unix())
+	}
+	databases := make([]string, 0)
+	for db := range lastSuccess {
+		databases = append(databases, db)
+	}
+	sort.Strings(databases)
+	metric := Metric{Name: metricName, Help: fmt.Sprintf("Time of the last successful %s run for each database", utility)}
+	for _, db := range databases {
+		metric.Samples = append(metric.Samples, MetricSample{Labels: map[string]string{"database": db}, Value: lastSuccess[db]})
+	}
+	return metric
+}
+
+func newGauge(name string, help string, labels map[string]string, value float64) Metric {
+	return Metric{Name: name, Help: help, Samples: []MetricSample{{Labels: labels, Value: value}}}
+}
+
+func countTableResults(tables []TableDataReport) (rows float64, bytes float64, errors float64) {
+	for _, table := range tables {
+		rows += float64(table.Rows)
+		bytes += float64(table.RelationSize)
+		if table.Error != "" {
+			errors++
+		}
+	}
+	return rows, bytes, errors
+}
+
+/*
+ * The duration of the data backup is measured from the start of the first
+ * table copy to the end of the last one, so that throughput is not diluted
+ * by the time spent backing up metadata.
+ */
+func getDataDuration(tables []TableDataReport) float64 {
+	var start, end time.Time
+	for _, table := range tables {
+		tableStart, err := time.Parse(time.RFC3339, table.StartTime)
+		if err != nil {
+			continue
+		}
+		tableEnd, err := time.Parse(time.RFC3339, table.EndTime)
+		if err != nil {
+			continue
+		}
+		if start.IsZero() || tableStart.Before(start) {
+			start = tableStart
+		}
+		if tableEnd.After(end) {
+			end = tableEnd
+		}
+	}
+	return end.Sub(start).Seconds()
+}
+
+func ConstructBackupMetrics(filename string, report BackupJSONReport, segmentBytes map[int]int64) []Metric {
+	labels := map[string]string{"database": report.DatabaseName}
+	rows, _, errors := countTableResults(report.Tables)
+	if report.Status == "failure" && errors == 0 {
+		errors = 1
+	}
+	endTime, _ := time.Parse(time.RFC3339, report.EndTime)
+	metrics := []Metric{
+		newGauge("gpbackup_duration_seconds", "Duration of the most recent backup", labels, report.DurationSeconds),
+		newGauge("gpbackup_success", "Whether the most recent backup succeeded", labels, boolToFloat(report.Status != "failure")),
+		newGauge("gpbackup_incremental", "Whether the most recent backup was incremental", labels, boolToFloat(report.Incremental)),
+		newGauge("gpbackup_tables", "Number of tables whose data was backed up", labels, float64(len(report.Tables))),
+		newGauge("gpbackup_rows", "Number of rows backed up", labels, rows),
+		newGauge("gpbackup_errors", "Number of errors encountered during the most recent backup", labels, errors),
+	}
+
+	objectMetric := Metric{Name: "gpbackup_objects", Help: "Number of database objects backed up, by type"}
+	objectTypes := make([]string, 0)
+	for objectType := range report.ObjectCounts {
+		objectTypes = append(objectTypes, objectType)
+	}
+	sort.Strings(objectTypes)
+	for _, objectType := range objectTypes {
+		objectMetric.Samples = append(objectMetric.Samples, MetricSample{
+			Labels: map[string]string{"database": report.DatabaseName, "type": objectType},
+			Value:  float64(report.ObjectCounts[objectType]),
+		})
+	}
+	if len(objectMetric.Samples) > 0 {
+		metrics = append(metrics, objectMetric)
+	}
+
+	// Segment file sizes are unavailable when the data was sent to a plugin
+	if len(segmentBytes) > 0 {
+		var totalBytes int64
+		contentIDs := make([]int, 0)
+		for contentID, bytes := range segmentBytes {
+			totalBytes += bytes
+			contentIDs = append(contentIDs, contentID)
+		}
+		sort.Ints(contentIDs)
+		metrics = append(metrics, newGauge("gpbackup_bytes_written", "Number of bytes of data files written across all segments", labels, float64(totalBytes)))
+
+		dataDuration := getDataDuration(report.Tables)
+		segmentMetric := Metric{Name: "gpbackup_segment_bytes_written", Help: "Number of bytes of data files written by each segment"}
+		throughputMetric := Metric{Name: "gpbackup_segment_throughput_bytes_per_second", Help: "Rate at which each segment wrote data files"}
+		for _, contentID := range contentIDs {
+			segmentLabels := map[string]string{"database": report.DatabaseName, "segment": strconv.Itoa(contentID)}
+			segmentMetric.Samples = append(segmentMetric.Samples, MetricSample{Labels: segmentLabels, Value: float64(segmentBytes[contentID])})
+			if dataDuration > 0 {
+				throughputMetric.Samples = append(throughputMetric.Samples, MetricSample{Labels: segmentLabels, Value: float64(segmentBytes[contentID]) / dataDuration})
+			}
+		}
+		metrics = append(metrics, segmentMetric)
+		if len(throughputMetric.Samples) > 0 {
+			metrics = append(metrics, throughputMetric)
+		}
+	}
+
+	return append(metrics, constructLastSuccessMetric(filename, "gpbackup", report.DatabaseName, report.Status, endTime))
+}
+
+func ConstructRestoreMetrics(filename string, report RestoreJSONReport) []Metric {
+	labels := map[string]string{"database": report.DatabaseName}
+	rows, bytes, errors := countTableResults(report.Tables)
+	errors += float64(len(report.FailedStatements))
+	if report.Status == "failure" && errors == 0 {
+		errors = 1
+	}
+	incremental := report.BackupConfig != nil && report.BackupConfig.Incremental
+	endTime, _ := time.Parse(time.RFC3339, report.EndTime)
+	metrics := []Metric{
+		newGauge("gprestore_duration_seconds", "Duration of the most recent restore", labels, report.DurationSeconds),
+		newGauge("gprestore_success", "Whether the most recent restore succeeded", labels, boolToFloat(report.Status != "failure")),
+		newGauge("gprestore_incremental", "Whether the most recent restore was of an incremental backup", labels, boolToFloat(incremental)),
+		newGauge("gprestore_tables", "Number of tables whose data was restored", labels, float64(len(report.Tables))),
+		newGauge("gprestore_rows", "Number of rows restored", labels, rows),
+		newGauge("gprestore_bytes", "Size in the database of the tables whose data was restored", labels, bytes),
+		newGauge("gprestore_errors", "Number of errors encountered during the most recent restore", labels, errors),
+	}
+	if dataDuration := getDataDuration(report.Tables); dataDuration > 0 {
+		metrics = append(metrics, newGauge("gprestore_throughput_bytes_per_second", "Rate at which table data was restored", labels, bytes/dataDuration))
+	}
+	return append(metrics, constructLastSuccessMetric(filename, "gprestore", report.DatabaseName, report.Status, endTime))
+}
+
+/*
+ * The textfile collector may read the file at any time, so the metrics are
+ * written to a temporary file that is then renamed over the old one.
+ */
+func WriteMetricsFile(filename string, metrics []Metric) {
+	tempFilename := fmt.Sprintf("%s.%d.tmp", filename, operating.System.Getpid())
+	metricsFile, err := iohelper.OpenFileForWriting(tempFilename)
+	if err != nil {
+		gplog.Error("Unable to open metrics file %s", tempFilename)
+		return
+	}
+	_, err = metricsFile.Write([]byte(FormatMetrics(metrics)))
+	_ = metricsFile.Close()
+	if err != nil {
+		gplog.Error("Unable to write metrics file %s", tempFilename)
+		return
+	}
+	err = os.Rename(tempFilename, filename)
+	if err != nil {
+		gplog.Error("Unable to rename metrics file %s to %s", tempFilename, filename)
+	}
+}
+
+/*
+ * Returns the total size of each segment's data files for a backup, or an
+ * empty map if the sizes could not be retrieved.  Errors are not fatal, as
+ * the sizes are only used for reporting.
+ */
+func GetSegmentDataFileSizes(c *cluster.Cluster, fpInfo backup_filepath.FilePathInfo) map[int]int64 {
+	sizes := make(map[int]int64, 0)
+	remoteOutput := c.GenerateAndExecuteCommand("Getting sizes of segment data files", func(contentID int) string {
+		dataFilePrefix := fpInfo.GetTableBackupFilePath(contentID, 0, "", true)
+		return fmt.Sprintf("du -cb %s* | tail -n 1 | cut -f1", dataFilePrefix)
+	}, cluster.ON_SEGMENTS)
+	if remoteOutput.NumErrors > 0 {
+		gplog.Verbose("Unable to retrieve sizes of segment data files for metrics")
+		return map[int]int64{}
+	}
+	for contentID, stdout := range remoteOutput.Stdouts {
+		size, err := strconv.ParseInt(strings.TrimSpace(stdout), 10, 64)
+		if err != nil {
+			gplog.Verbose("Unable to parse size of data files on segment %d: %s", contentID, stdout)
+			return map[int]int64{}
+		}
+		sizes[contentID] = size
+	}
+	return sizes
+}
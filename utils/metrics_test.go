@@ -0,0 +1,168 @@
+package utils_test
+
+import (
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/greenplum-db/gp-common-go-libs/cluster"
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/greenplum-db/gpbackup/backup_filepath"
+	"github.com/greenplum-db/gpbackup/backup_history"
+	"github.com/greenplum-db/gpbackup/utils"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+var _ = Describe("utils/metrics tests", func() {
+	endTime := time.Date(2017, 1, 1, 1, 2, 1, 0, time.UTC)
+	tables := []utils.TableDataReport{
+		{Schema: "public", Name: "foo", Rows: 10, RelationSize: 1000, StartTime: "2017-01-01T01:01:11Z", EndTime: "2017-01-01T01:01:21Z"},
+		{Schema: "public", Name: "bar", Rows: 5, RelationSize: 500, StartTime: "2017-01-01T01:01:16Z", EndTime: "2017-01-01T01:01:31Z"},
+	}
+	BeforeEach(func() {
+		operating.System.ReadFile = func(filename string) ([]byte, error) {
+			return nil, errors.New("file does not exist")
+		}
+	})
+	AfterEach(func() {
+		operating.System = operating.InitializeSystemFunctions()
+	})
+	Describe("FormatMetrics", func() {
+		It("formats metrics with sorted and escaped labels", func() {
+			metrics := []utils.Metric{
+				{Name: "gpbackup_rows", Help: "Number of rows backed up", Samples: []utils.MetricSample{
+					{Labels: map[string]string{"database": `test"db`, "segment": "0"}, Value: 15},
+				}},
+				{Name: "gpbackup_duration_seconds", Help: "Duration of the most recent backup", Samples: []utils.MetricSample{
+					{Labels: map[string]string{}, Value: 1.5},
+				}},
+			}
+
+			Expect(utils.FormatMetrics(metrics)).To(Equal(`# HELP gpbackup_rows Number of rows backed up
+# TYPE gpbackup_rows gauge
+gpbackup_rows{database="test\"db",segment="0"} 15
+# HELP gpbackup_duration_seconds Duration of the most recent backup
+# TYPE gpbackup_duration_seconds gauge
+gpbackup_duration_seconds 1.5
+`))
+		})
+	})
+	Describe("ConstructBackupMetrics", func() {
+		report := utils.BackupJSONReport{
+			BackupConfig:    backup_history.BackupConfig{DatabaseName: "testdb", Incremental: true},
+			Status:          "success",
+			EndTime:         endTime.Format(time.RFC3339),
+			DurationSeconds: 60,
+			ObjectCounts:    map[string]int{"tables": 2, "sequences": 1},
+			Tables:          tables,
+		}
+		It("includes totals, object counts, and per-segment throughput", func() {
+			metrics := utils.FormatMetrics(utils.ConstructBackupMetrics("/tmp/gpbackup.prom", report, map[int]int64{0: 2000, 1: 4000}))
+
+			Expect(metrics).To(ContainSubstring(`gpbackup_duration_seconds{database="testdb"} 60` + "\n"))
+			Expect(metrics).To(ContainSubstring(`gpbackup_success{database="testdb"} 1` + "\n"))
+			Expect(metrics).To(ContainSubstring(`gpbackup_incremental{database="testdb"} 1` + "\n"))
+			Expect(metrics).To(ContainSubstring(`gpbackup_tables{database="testdb"} 2` + "\n"))
+			Expect(metrics).To(ContainSubstring(`gpbackup_rows{database="testdb"} 15` + "\n"))
+			Expect(metrics).To(ContainSubstring(`gpbackup_errors{database="testdb"} 0` + "\n"))
+			Expect(metrics).To(ContainSubstring(`gpbackup_objects{database="testdb",type="sequences"} 1` + "\n"))
+			Expect(metrics).To(ContainSubstring(`gpbackup_objects{database="testdb",type="tables"} 2` + "\n"))
+			Expect(metrics).To(ContainSubstring(`gpbackup_bytes_written{database="testdb"} 6000` + "\n"))
+			Expect(metrics).To(ContainSubstring(`gpbackup_segment_bytes_written{database="testdb",segment="1"} 4000` + "\n"))
+			Expect(metrics).To(ContainSubstring(`gpbackup_segment_throughput_bytes_per_second{database="testdb",segment="0"} 100` + "\n"))
+			Expect(metrics).To(ContainSubstring(`gpbackup_segment_throughput_bytes_per_second{database="testdb",segment="1"} 200` + "\n"))
+			Expect(metrics).To(ContainSubstring(`gpbackup_last_success_timestamp_seconds{database="testdb"} 1483232521` + "\n"))
+		})
+		It("omits segment metrics if segment data file sizes are unavailable", func() {
+			metrics := utils.FormatMetrics(utils.ConstructBackupMetrics("/tmp/gpbackup.prom", report, map[int]int64{}))
+
+			Expect(metrics).ToNot(ContainSubstring("gpbackup_bytes_written"))
+			Expect(metrics).ToNot(ContainSubstring("gpbackup_segment"))
+		})
+		It("keeps the previous last success time of each database after a failed backup", func() {
+			operating.System.ReadFile = func(filename string) ([]byte, error) {
+				return []byte(`# TYPE gpbackup_last_success_timestamp_seconds gauge
+gpbackup_last_success_timestamp_seconds{database="otherdb"} 1483000000
+gpbackup_last_success_timestamp_seconds{database="testdb"} 1483100000
+`), nil
+			}
+			failedReport := report
+			failedReport.Status = "failure"
+
+			metrics := utils.FormatMetrics(utils.ConstructBackupMetrics("/tmp/gpbackup.prom", failedReport, map[int]int64{}))
+
+			Expect(metrics).To(ContainSubstring(`gpbackup_success{database="testdb"} 0` + "\n"))
+			Expect(metrics).To(ContainSubstring(`gpbackup_errors{database="testdb"} 1` + "\n"))
+			Expect(metrics).To(ContainSubstring(`gpbackup_last_success_timestamp_seconds{database="otherdb"} 1483000000
+gpbackup_last_success_timestamp_seconds{database="testdb"} 1483100000
+`))
+		})
+	})
+	Describe("ConstructRestoreMetrics", func() {
+		It("counts failed tables and statements as errors", func() {
+			failedTable := utils.TableDataReport{Schema: "public", Name: "baz", Error: "permission denied"}
+			report := utils.RestoreJSONReport{
+				DatabaseName:     "testdb",
+				Status:           "success_with_errors",
+				EndTime:          endTime.Format(time.RFC3339),
+				DurationSeconds:  30,
+				BackupConfig:     &backup_history.BackupConfig{},
+				Tables:           append(tables, failedTable),
+				FailedStatements: []utils.FailedStatement{{Name: "foo_view", Error: "permission denied"}},
+			}
+
+			metrics := utils.FormatMetrics(utils.ConstructRestoreMetrics("/tmp/gprestore.prom", report))
+
+			Expect(metrics).To(ContainSubstring(`gprestore_duration_seconds{database="testdb"} 30` + "\n"))
+			Expect(metrics).To(ContainSubstring(`gprestore_success{database="testdb"} 1` + "\n"))
+			Expect(metrics).To(ContainSubstring(`gprestore_incremental{database="testdb"} 0` + "\n"))
+			Expect(metrics).To(ContainSubstring(`gprestore_tables{database="testdb"} 3` + "\n"))
+			Expect(metrics).To(ContainSubstring(`gprestore_bytes{database="testdb"} 1500` + "\n"))
+			Expect(metrics).To(ContainSubstring(`gprestore_errors{database="testdb"} 2` + "\n"))
+			Expect(metrics).To(ContainSubstring(`gprestore_throughput_bytes_per_second{database="testdb"} 75` + "\n"))
+			Expect(metrics).To(ContainSubstring(`gprestore_last_success_timestamp_seconds{database="testdb"} 1483232521` + "\n"))
+		})
+	})
+	Describe("WriteMetricsFile", func() {
+		It("replaces the metrics file", func() {
+			metricsFilename := "/tmp/gpbackup_metrics_test.prom"
+			defer os.Remove(metricsFilename)
+			metrics := []utils.Metric{{Name: "gpbackup_rows", Help: "Number of rows backed up", Samples: []utils.MetricSample{{Value: 15}}}}
+
+			utils.WriteMetricsFile(metricsFilename, metrics)
+
+			contents, err := ioutil.ReadFile(metricsFilename)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(contents)).To(Equal(utils.FormatMetrics(metrics)))
+		})
+	})
+	Describe("GetSegmentDataFileSizes", func() {
+		var testExecutor *testhelper.TestExecutor
+		var testCluster *cluster.Cluster
+		var fpInfo backup_filepath.FilePathInfo
+		BeforeEach(func() {
+			testExecutor = &testhelper.TestExecutor{ClusterOutput: &cluster.RemoteOutput{Stdouts: map[int]string{0: "2000\n", 1: "4000\n"}}}
+			testCluster = cluster.NewCluster([]cluster.SegConfig{
+				{ContentID: -1, Hostname: "localhost", DataDir: "/data/gpseg-1"},
+				{ContentID: 0, Hostname: "localhost", DataDir: "/data/gpseg0"},
+				{ContentID: 1, Hostname: "remotehost1", DataDir: "/data/gpseg1"},
+			})
+			testCluster.Executor = testExecutor
+			fpInfo = backup_filepath.NewFilePathInfo(testCluster, "", "20170101010101", "gpseg")
+		})
+		It("returns the size of the data files on each segment", func() {
+			sizes := utils.GetSegmentDataFileSizes(testCluster, fpInfo)
+
+			Expect(sizes).To(Equal(map[int]int64{0: 2000, 1: 4000}))
+			Expect(testExecutor.ClusterCommands[0][0]).To(ContainElement("du -cb /data/gpseg0/backups/20170101/20170101010101/gpbackup_0_20170101010101* | tail -n 1 | cut -f1"))
+		})
+		It("returns no sizes if any segment fails", func() {
+			testExecutor.ClusterOutput.NumErrors = 1
+
+			Expect(utils.GetSegmentDataFileSizes(testCluster, fpInfo)).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,150 @@
+package utils
+
+/*
+ * This file contains structs and functions related to sending HTTP webhook
+ * notifications when a backup or restore completes, as an alternative to
+ * email reports for hosts without a mail transfer agent.
+ */
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const defaultWebhookTimeout = 10
+
+/*
+ * Webhooks are listed per utility, in the same way as email contacts, and
+ * each webhook is only notified for the exit statuses set to true in its
+ * Status map.  Timeout and RetryDelay are in seconds, and Retries is the
+ * number of additional attempts made after a failed request.
+ */
+type NotificationConfig struct {
+	Webhooks   map[string][]WebhookTarget
+	Retries    int
+	Timeout    int
+	RetryDelay int `yaml:"retry_delay"`
+}
+
+type WebhookTarget struct {
+	URL     string
+	Status  map[string]bool
+	Headers map[string]string
+}
+
+type WebhookPayload struct {
+	Utility   string
+	Timestamp string
+	Hostname  string
+	Status    string
+	Report    interface{}
+}
+
+func ReadNotificationConfig(filename string) (*NotificationConfig, error) {
+	config := &NotificationConfig{}
+	contents, err := operating.System.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	err = yaml.UnmarshalStrict(contents, config)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("Unable to parse notification config file %s", filename))
+	}
+	for utility, targets := range config.Webhooks {
+		for _, target := range targets {
+			if target.URL == "" {
+				return nil, errors.Errorf("A webhook for %s in notification config file %s has no url", utility, filename)
+			}
+		}
+	}
+	if config.Retries < 0 || config.Timeout < 0 || config.RetryDelay < 0 {
+		return nil, errors.Errorf("The retries, timeout, and retry_delay values in notification config file %s must not be negative", filename)
+	}
+	if config.Timeout == 0 {
+		config.Timeout = defaultWebhookTimeout
+	}
+	return config, nil
+}
+
+func (config *NotificationConfig) GetWebhooks(utility string, status string) []WebhookTarget {
+	webhooks := make([]WebhookTarget, 0)
+	for _, target := range config.Webhooks[utility] {
+		if target.Status[status] {
+			webhooks = append(webhooks, target)
+		}
+	}
+	return webhooks
+}
+
+func postWebhook(client *http.Client, target WebhookTarget, payload []byte) error {
+	request, err := http.NewRequest("POST", target.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for name, value := range target.Headers {
+		request.Header.Set(name, value)
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, response.Body)
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return errors.Errorf("Received HTTP status %s", response.Status)
+	}
+	return nil
+}
+
+func (config *NotificationConfig) SendWebhook(target WebhookTarget, payload []byte) error {
+	client := &http.Client{Timeout: time.Duration(config.Timeout) * time.Second}
+	var err error
+	for attempt := 0; attempt <= config.Retries; attempt++ {
+		if attempt > 0 {
+			gplog.Verbose("Retrying webhook notification to %s after error: %v", target.URL, err)
+			time.Sleep(time.Duration(config.RetryDelay) * time.Second)
+		}
+		err = postWebhook(client, target, payload)
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// Posts the report to each webhook configured for the utility and status, logging a warning for each failure
+func SendWebhookNotifications(configFilename string, utility string, timestamp string, status string, report interface{}) {
+	config, err := ReadNotificationConfig(configFilename)
+	if err != nil {
+		gplog.Warn("Unable to send webhook notifications: %v", err)
+		return
+	}
+	webhooks := config.GetWebhooks(utility, status)
+	if len(webhooks) == 0 {
+		return
+	}
+	hostname, _ := operating.System.Hostname()
+	payload, err := json.Marshal(WebhookPayload{Utility: utility, Timestamp: timestamp, Hostname: hostname, Status: status, Report: report})
+	if err != nil {
+		gplog.Warn("Unable to send webhook notifications: %v", err)
+		return
+	}
+	for _, target := range webhooks {
+		gplog.Verbose("Sending webhook notification to %s", target.URL)
+		err = config.SendWebhook(target, payload)
+		if err != nil {
+			gplog.Warn("Unable to send webhook notification to %s: %v", target.URL, err)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package utils_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/greenplum-db/gpbackup/utils"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+var _ = Describe("utils/notification tests", func() {
+	var (
+		server        *httptest.Server
+		mutex         sync.Mutex
+		requestBodies [][]byte
+		headers       []http.Header
+		failures      int
+	)
+	BeforeEach(func() {
+		requestBodies = make([][]byte, 0)
+		headers = make([]http.Header, 0)
+		failures = 0
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			body, _ := ioutil.ReadAll(r.Body)
+			requestBodies = append(requestBodies, body)
+			headers = append(headers, r.Header)
+			if failures > 0 {
+				failures--
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		operating.System.Hostname = func() (string, error) {
+			return "localhost", nil
+		}
+	})
+	AfterEach(func() {
+		server.Close()
+		operating.System = operating.InitializeSystemFunctions()
+	})
+	setConfigFile := func(contents string) {
+		operating.System.ReadFile = func(filename string) ([]byte, error) {
+			return []byte(contents), nil
+		}
+	}
+	Describe("ReadNotificationConfig", func() {
+		It("reads webhooks and uses a default timeout", func() {
+			setConfigFile(`webhooks:
+  gpbackup:
+  - url: http://example.com/hook
+    status:
+      success: true
+      failure: true
+    headers:
+      Authorization: Bearer token
+retries: 2`)
+
+			config, err := utils.ReadNotificationConfig("/home/gpadmin/notifications.yaml")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.Retries).To(Equal(2))
+			Expect(config.Timeout).To(Equal(10))
+			Expect(config.Webhooks["gpbackup"]).To(Equal([]utils.WebhookTarget{{
+				URL:     "http://example.com/hook",
+				Status:  map[string]bool{"success": true, "failure": true},
+				Headers: map[string]string{"Authorization": "Bearer token"},
+			}}))
+		})
+		It("returns an error for a webhook without a url", func() {
+			setConfigFile(`webhooks:
+  gprestore:
+  - status:
+      success: true`)
+
+			_, err := utils.ReadNotificationConfig("/home/gpadmin/notifications.yaml")
+
+			Expect(err).To(MatchError("A webhook for gprestore in notification config file /home/gpadmin/notifications.yaml has no url"))
+		})
+		It("returns an error for an unknown field", func() {
+			setConfigFile(`webhook:
+  gpbackup: []`)
+
+			_, err := utils.ReadNotificationConfig("/home/gpadmin/notifications.yaml")
+
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unable to parse notification config file /home/gpadmin/notifications.yaml"))
+		})
+		It("returns an error if the file cannot be read", func() {
+			operating.System.ReadFile = func(filename string) ([]byte, error) {
+				return nil, errors.New("permission denied")
+			}
+
+			_, err := utils.ReadNotificationConfig("/home/gpadmin/notifications.yaml")
+
+			Expect(err).To(MatchError("permission denied"))
+		})
+	})
+	Describe("GetWebhooks", func() {
+		It("returns only the webhooks for the given utility and status", func() {
+			config := utils.NotificationConfig{Webhooks: map[string][]utils.WebhookTarget{
+				"gpbackup": {
+					{URL: "http://example.com/all", Status: map[string]bool{"success": true, "success_with_errors": true, "failure": true}},
+					{URL: "http://example.com/failure", Status: map[string]bool{"failure": true}},
+				},
+				"gprestore": {
+					{URL: "http://example.com/restore", Status: map[string]bool{"success": true}},
+				},
+			}}
+
+			webhooks := config.GetWebhooks("gpbackup", "success")
+
+			Expect(webhooks).To(HaveLen(1))
+			Expect(webhooks[0].URL).To(Equal("http://example.com/all"))
+		})
+	})
+	Describe("SendWebhookNotifications", func() {
+		It("posts the report as JSON to each matching webhook", func() {
+			setConfigFile(`webhooks:
+  gpbackup:
+  - url: ` + server.URL + `/first
+    status:
+      success: true
+    headers:
+      X-Api-Key: secret
+  - url: ` + server.URL + `/second
+    status:
+      failure: true`)
+
+			utils.SendWebhookNotifications("/home/gpadmin/notifications.yaml", "gpbackup", "20170101010101", "success", map[string]int{"tables": 1})
+
+			Expect(requestBodies).To(HaveLen(1))
+			Expect(headers[0].Get("Content-Type")).To(Equal("application/json"))
+			Expect(headers[0].Get("X-Api-Key")).To(Equal("secret"))
+			var payload map[string]interface{}
+			err := json.Unmarshal(requestBodies[0], &payload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(payload).To(Equal(map[string]interface{}{
+				"Utility":   "gpbackup",
+				"Timestamp": "20170101010101",
+				"Hostname":  "localhost",
+				"Status":    "success",
+				"Report":    map[string]interface{}{"tables": float64(1)},
+			}))
+		})
+		It("retries a webhook that returns an error status", func() {
+			failures = 1
+			setConfigFile(`webhooks:
+  gprestore:
+  - url: ` + server.URL + `
+    status:
+      failure: true
+retries: 1`)
+
+			utils.SendWebhookNotifications("/home/gpadmin/notifications.yaml", "gprestore", "20170101010101", "failure", nil)
+
+			Expect(requestBodies).To(HaveLen(2))
+			Expect(string(logfile.Contents())).ToNot(ContainSubstring("Unable to send webhook notification"))
+		})
+		It("logs a warning if a webhook fails after all retries", func() {
+			failures = 3
+			setConfigFile(`webhooks:
+  gpbackup:
+  - url: ` + server.URL + `
+    status:
+      failure: true
+retries: 1`)
+
+			utils.SendWebhookNotifications("/home/gpadmin/notifications.yaml", "gpbackup", "20170101010101", "failure", nil)
+
+			Expect(requestBodies).To(HaveLen(2))
+			Expect(string(logfile.Contents())).To(ContainSubstring("Unable to send webhook notification to " + server.URL + ": Received HTTP status 503 Service Unavailable"))
+		})
+	})
+})
@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/blang/semver"
@@ -66,13 +67,52 @@ func (plugin *PluginConfig) MustBackupFile(filenamePath string) {
 	gplog.FatalOnError(err)
 }
 
-func (plugin *PluginConfig) MustRestoreFile(filenamePath string) {
+func (plugin *PluginConfig) RestoreFile(filenamePath string) error {
 	directory, _ := filepath.Split(filenamePath)
 	err := operating.System.MkdirAll(directory, 0755)
-	gplog.FatalOnError(err)
+	if err != nil {
+		return err
+	}
 	command := fmt.Sprintf("%s restore_file %s %s", plugin.ExecutablePath, plugin.ConfigPath, filenamePath)
 	output, err := exec.Command("bash", "-c", command).CombinedOutput()
-	gplog.FatalOnError(err, string(output))
+	if err != nil {
+		return fmt.Errorf("Plugin failed to restore %s. %s", filenamePath, string(output))
+	}
+	return nil
+}
+
+func (plugin *PluginConfig) MustRestoreFile(filenamePath string) {
+	err := plugin.RestoreFile(filenamePath)
+	gplog.FatalOnError(err)
+}
+
+func (plugin *PluginConfig) DeleteBackup(c *cluster.Cluster, timestamp string) error {
+	command := fmt.Sprintf("%s delete_backup %s %s", plugin.ExecutablePath, plugin.ConfigPath, timestamp)
+	output, err := c.ExecuteLocalCommand(command)
+	if err != nil {
+		return fmt.Errorf("Plugin failed to delete backup %s. %s", timestamp, output)
+	}
+	return nil
+}
+
+/*
+ * The list_backups command prints the timestamps of the backups in plugin
+ * storage, one per line.  Lines that are not timestamps are ignored.
+ */
+func (plugin *PluginConfig) ListBackups(c *cluster.Cluster) ([]string, error) {
+	command := fmt.Sprintf("%s list_backups %s", plugin.ExecutablePath, plugin.ConfigPath)
+	output, err := c.ExecuteLocalCommand(command)
+	if err != nil {
+		return nil, fmt.Errorf("Plugin failed to list backups. %s", output)
+	}
+	timestamps := make([]string, 0)
+	for _, line := range strings.Split(output, "\n") {
+		if timestamp := strings.TrimSpace(line); backup_filepath.IsValidTimestamp(timestamp) {
+			timestamps = append(timestamps, timestamp)
+		}
+	}
+	sort.Strings(timestamps)
+	return timestamps, nil
 }
 
 func (plugin *PluginConfig) CheckPluginExistsOnAllHosts(c *cluster.Cluster) {
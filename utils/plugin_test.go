@@ -1,6 +1,7 @@
 package utils_test
 
 import (
+	"errors"
 	"strconv"
 
 	"github.com/blang/semver"
@@ -71,6 +72,26 @@ var _ = Describe("utils/plugin tests", func() {
 			Expect(cc[1][2]).To(Equal("scp /tmp/my_plugin_config.yml segment2:/tmp/."))
 		})
 	})
+	Describe("ListBackups", func() {
+		It("returns the sorted timestamps printed by the plugin", func() {
+			subject.ConfigPath = "/tmp/my_plugin_config.yml"
+			executor.LocalOutput = "20170102010101\n20170101010101\nsome warning\n"
+
+			timestamps, err := subject.ListBackups(testCluster)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(executor.LocalCommands).To(Equal([]string{"myPlugin list_backups /tmp/my_plugin_config.yml"}))
+			Expect(timestamps).To(Equal([]string{"20170101010101", "20170102010101"}))
+		})
+		It("returns an error if the plugin fails", func() {
+			executor.LocalOutput = "unknown command list_backups"
+			executor.LocalError = errors.New("exit status 1")
+
+			_, err := subject.ListBackups(testCluster)
+
+			Expect(err).To(MatchError("Plugin failed to list backups. unknown command list_backups"))
+		})
+	})
 	Describe("version validation", func() {
 		When("version is equal to requirement", func() {
 			It("succeeds", func() {
@@ -1,12 +1,14 @@
 package utils
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"gopkg.in/yaml.v2"
@@ -18,6 +20,7 @@ import (
 	"github.com/greenplum-db/gp-common-go-libs/iohelper"
 	"github.com/greenplum-db/gp-common-go-libs/operating"
 	"github.com/greenplum-db/gpbackup/backup_history"
+	"github.com/lib/pq"
 	"github.com/pkg/errors"
 )
 
@@ -29,6 +32,8 @@ import (
 type Report struct {
 	BackupParamsString string
 	DatabaseSize       string
+	EffectiveFlags     []string
+	RetentionSummary   string
 	backup_history.BackupConfig
 }
 
@@ -42,6 +47,18 @@ func ParseErrorMessage(errStr string) string {
 	return errMsg
 }
 
+/*
+ * When flags are read from a config file, the command line alone does not
+ * show how the utility was run, so the effective flags are shown as well.
+ */
+func getCommandLineString(effectiveFlags []string) string {
+	commandLine := strings.Join(os.Args, " ")
+	if len(effectiveFlags) > 0 {
+		commandLine += fmt.Sprintf("\nEffective Flags: %s", strings.Join(effectiveFlags, " "))
+	}
+	return commandLine
+}
+
 func (report *Report) ConstructBackupParamsString() {
 	filterStr := ""
 	if report.IncludeSchemaFiltered {
@@ -94,6 +111,18 @@ Data File Format: %s
 %s`
 	report.BackupParamsString = fmt.Sprintf(backupParamsTemplate, compressStr, pluginStr, sectionStr, filterStr,
 		statsStr, filesStr, report.constructIncrementalSection())
+	if len(report.Labels) > 0 {
+		report.BackupParamsString += fmt.Sprintf("\nLabels: %s", report.constructLabelsString())
+	}
+}
+
+func (report *Report) constructLabelsString() string {
+	labels := make([]string, 0)
+	for key, value := range report.Labels {
+		labels = append(labels, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(labels)
+	return strings.Join(labels, ", ")
 }
 
 func (report *Report) constructIncrementalSection() string {
@@ -130,9 +159,9 @@ End Time: %s
 Duration: %s
 
 Backup Status: %s
-%s`
+%s%s`
 
-	gpbackupCommandLine := strings.Join(os.Args, " ")
+	gpbackupCommandLine := getCommandLineString(report.EffectiveFlags)
 	start, end, duration := GetDurationInfo(timestamp, operating.System.Now())
 	backupStatus := "Success"
 	if errMsg != "" {
@@ -142,12 +171,16 @@ Backup Status: %s
 	if report.DatabaseSize != "" {
 		dbSizeStr = fmt.Sprintf("\nDatabase Size: %s", report.DatabaseSize)
 	}
+	retentionStr := ""
+	if report.RetentionSummary != "" {
+		retentionStr = fmt.Sprintf("\n%s", report.RetentionSummary)
+	}
 
 	_, err = fmt.Fprintf(reportFile, reportFileTemplate,
 		timestamp, report.DatabaseVersion, report.BackupVersion,
 		report.DatabaseName, gpbackupCommandLine, report.BackupParamsString,
 		start, end, duration,
-		backupStatus, dbSizeStr)
+		backupStatus, dbSizeStr, retentionStr)
 	if err != nil {
 		gplog.Error("Unable to write backup report file %s", reportFilename)
 		return
@@ -157,7 +190,8 @@ Backup Status: %s
 	_ = operating.System.Chmod(reportFilename, 0444)
 }
 
-func WriteRestoreReportFile(reportFilename string, backupTimestamp string, startTimestamp string, connectionPool *dbconn.DBConn, restoreVersion string, errMsg string) {
+func WriteRestoreReportFile(reportFilename string, backupTimestamp string, startTimestamp string, connectionPool *dbconn.DBConn, restoreVersion string, effectiveFlags []string,
+	tables []TableDataReport, failedStatements []FailedStatement, errorsFilename string, errMsg string) {
 	reportFile, err := iohelper.OpenFileForWriting(reportFilename)
 	if err != nil {
 		gplog.Error("Unable to open restore report file %s", reportFilename)
@@ -178,7 +212,7 @@ Duration: %s
 
 Restore Status: %s`
 
-	gprestoreCommandLine := strings.Join(os.Args, " ")
+	gprestoreCommandLine := getCommandLineString(effectiveFlags)
 	start, end, duration := GetDurationInfo(startTimestamp, operating.System.Now())
 	restoreStatus := "Success"
 	errorCode := gplog.GetErrorCode()
@@ -196,7 +230,345 @@ Restore Status: %s`
 		gplog.Error("Unable to write restore report file %s", reportFilename)
 		return
 	}
+	_, err = fmt.Fprint(reportFile, constructRestoreErrorsSection(tables, failedStatements, errorsFilename))
+	if err != nil {
+		gplog.Error("Unable to write restore report file %s", reportFilename)
+		return
+	}
+
+	_ = operating.System.Chmod(reportFilename, 0444)
+}
+
+func constructRestoreErrorsSection(tables []TableDataReport, failedStatements []FailedStatement, errorsFilename string) string {
+	section := ""
+	if len(failedStatements) > 0 {
+		section += fmt.Sprintf("\n\nFailed Statements: %d", len(failedStatements))
+		for _, statement := range failedStatements {
+			section += fmt.Sprintf("\n%s %s", statement.ObjectType, statement.GetObjectName())
+			if statement.SQLState != "" {
+				section += fmt.Sprintf(" (SQLSTATE %s)", statement.SQLState)
+			}
+			section += fmt.Sprintf(": %s", statement.Error)
+		}
+	}
+	failedTables := make([]TableDataReport, 0)
+	for _, table := range tables {
+		if table.Error != "" {
+			failedTables = append(failedTables, table)
+		}
+	}
+	if len(failedTables) > 0 {
+		section += fmt.Sprintf("\n\nFailed Table Data: %d", len(failedTables))
+		for _, table := range failedTables {
+			section += fmt.Sprintf("\n%s (%d rows expected, %d rows restored)", MakeFQN(table.Schema, table.Name), table.RowsExpected, table.Rows)
+			if table.SQLState != "" {
+				section += fmt.Sprintf(" (SQLSTATE %s)", table.SQLState)
+			}
+			section += fmt.Sprintf(": %s", table.Error)
+		}
+	}
+	if errorsFilename != "" {
+		section += fmt.Sprintf("\n\nStatements to re-run the failed statements and table data restores: %s", errorsFilename)
+	}
+	return section
+}
+
+/*
+ * The JSON report files contain the same information as the text report files,
+ * plus per-table and per-statement details, in a form that can be parsed by
+ * other programs.  Times are in RFC 3339 format and durations are in seconds.
+ */
+type BackupJSONReport struct {
+	backup_history.BackupConfig
+	CommandLine      string
+	EffectiveFlags   []string `json:",omitempty"`
+	DatabaseSize     string
+	RetentionSummary string `json:",omitempty"`
+	Status           string
+	Error            string
+	StartTime        string
+	EndTime          string
+	DurationSeconds  float64
+	ObjectCounts     map[string]int
+	Tables           []TableDataReport
+}
+
+type RestoreJSONReport struct {
+	Timestamp        string
+	RestoreTimestamp string
+	DatabaseName     string
+	DatabaseVersion  string
+	RestoreVersion   string
+	CommandLine      string
+	EffectiveFlags   []string `json:",omitempty"`
+	Status           string
+	Error            string
+	StartTime        string
+	EndTime          string
+	DurationSeconds  float64
+	BackupConfig     *backup_history.BackupConfig
+	Tables           []TableDataReport
+	FailedTables     []string
+	FailedStatements []FailedStatement
+}
+
+/*
+ * RelationSize is the size in bytes of the table in the database, from
+ * pg_relation_size after its data was backed up or restored, not the number of
+ * bytes written to or read from its data files.  For a data-only restore that
+ * appends to a table, it includes the rows that were already in the table.
+ */
+type TableDataReport struct {
+	Schema          string
+	Name            string
+	Rows            int64
+	RowsExpected    int64 `json:",omitempty"`
+	RelationSize    int64
+	StartTime       string
+	EndTime         string
+	DurationSeconds float64
+	Error           string `json:",omitempty"`
+	SQLState        string `json:",omitempty"`
+}
+
+type FailedStatement struct {
+	Schema     string
+	Name       string
+	ObjectType string
+	Statement  string
+	Error      string
+	SQLState   string `json:",omitempty"`
+}
+
+func (statement FailedStatement) GetObjectName() string {
+	return MakeObjectName(statement.Schema, statement.Name)
+}
+
+// Returns the SQLSTATE of an error reported by the database, or "" for any other error
+func GetSQLState(err error) string {
+	if pqErr, ok := errors.Cause(err).(*pq.Error); ok {
+		return string(pqErr.Code)
+	}
+	return ""
+}
+
+/*
+ * Tables are backed up and restored by multiple goroutines at once, so these
+ * lists synchronize their additions.  The zero value of each is ready to use.
+ */
+type TableDataReportList struct {
+	mutex  sync.Mutex
+	tables []TableDataReport
+}
+
+func (list *TableDataReportList) AddTable(schema string, name string, rows int64, startTime time.Time, endTime time.Time, err error) {
+	list.AddRestoredTable(schema, name, rows, 0, startTime, endTime, err)
+}
+
+// Restored tables also record the number of rows in the backup, to compare with the number restored
+func (list *TableDataReportList) AddRestoredTable(schema string, name string, rows int64, rowsExpected int64, startTime time.Time, endTime time.Time, err error) {
+	table := TableDataReport{
+		Schema:          schema,
+		Name:            name,
+		Rows:            rows,
+		RowsExpected:    rowsExpected,
+		StartTime:       startTime.Format(time.RFC3339),
+		EndTime:         endTime.Format(time.RFC3339),
+		DurationSeconds: endTime.Sub(startTime).Seconds(),
+	}
+	if err != nil {
+		table.Error = err.Error()
+		table.SQLState = GetSQLState(err)
+	}
+	list.mutex.Lock()
+	list.tables = append(list.tables, table)
+	list.mutex.Unlock()
+}
+
+// Sizes are keyed by table FQN
+func (list *TableDataReportList) SetTableSizes(sizes map[string]int64) {
+	list.mutex.Lock()
+	defer list.mutex.Unlock()
+	for i, table := range list.tables {
+		list.tables[i].RelationSize = sizes[MakeFQN(table.Schema, table.Name)]
+	}
+}
+
+func (list *TableDataReportList) GetTables() []TableDataReport {
+	list.mutex.Lock()
+	defer list.mutex.Unlock()
+	tables := make([]TableDataReport, len(list.tables))
+	copy(tables, list.tables)
+	sort.Slice(tables, func(i int, j int) bool {
+		return MakeFQN(tables[i].Schema, tables[i].Name) < MakeFQN(tables[j].Schema, tables[j].Name)
+	})
+	return tables
+}
+
+type FailedStatementList struct {
+	mutex      sync.Mutex
+	statements []FailedStatement
+}
+
+func (list *FailedStatementList) AddStatement(statement StatementWithType, err error) {
+	list.mutex.Lock()
+	list.statements = append(list.statements, FailedStatement{
+		Schema:     statement.Schema,
+		Name:       statement.Name,
+		ObjectType: statement.ObjectType,
+		Statement:  strings.TrimSpace(statement.Statement),
+		Error:      err.Error(),
+		SQLState:   GetSQLState(err),
+	})
+	list.mutex.Unlock()
+}
+
+func (list *FailedStatementList) GetStatements() []FailedStatement {
+	list.mutex.Lock()
+	defer list.mutex.Unlock()
+	statements := make([]FailedStatement, len(list.statements))
+	copy(statements, list.statements)
+	return statements
+}
+
+/*
+ * Table sizes are only needed for the JSON report, so they are retrieved for
+ * all tables at once after the data has been backed up or restored.  The
+ * sizes are keyed by FQN, with schema and table names quoted as in the TOC.
+ */
+func GetTableSizes(connectionPool *dbconn.DBConn, tableOids []uint32) map[string]int64 {
+	if len(tableOids) == 0 {
+		return make(map[string]int64, 0)
+	}
+	oidStrs := make([]string, len(tableOids))
+	for i, oid := range tableOids {
+		oidStrs[i] = fmt.Sprintf("%d", oid)
+	}
+	query := fmt.Sprintf(`
+SELECT quote_ident(n.nspname) || '.' || quote_ident(c.relname) AS fqn,
+	pg_relation_size(c.oid) AS size
+FROM pg_class c
+JOIN pg_namespace n ON c.relnamespace = n.oid
+WHERE c.oid IN (%s)`, strings.Join(oidStrs, ", "))
+	return selectTableSizes(connectionPool, query)
+}
+
+// Restored tables do not keep the oids they had in the backup, so they are looked up by name
+func GetTableSizesByName(connectionPool *dbconn.DBConn, tables []TableDataReport) map[string]int64 {
+	if len(tables) == 0 {
+		return make(map[string]int64, 0)
+	}
+	tableNames := make([]string, len(tables))
+	for i, table := range tables {
+		tableNames[i] = fmt.Sprintf("('%s', '%s')", EscapeSingleQuotes(table.Schema), EscapeSingleQuotes(table.Name))
+	}
+	query := fmt.Sprintf(`
+SELECT t.schemaname || '.' || t.tablename AS fqn,
+	pg_relation_size(c.oid) AS size
+FROM pg_class c
+JOIN pg_namespace n ON c.relnamespace = n.oid
+JOIN (VALUES %s) AS t(schemaname, tablename)
+	ON quote_ident(n.nspname) = t.schemaname AND quote_ident(c.relname) = t.tablename`, strings.Join(tableNames, ", "))
+	return selectTableSizes(connectionPool, query)
+}
 
+func selectTableSizes(connectionPool *dbconn.DBConn, query string) map[string]int64 {
+	sizes := make(map[string]int64, 0)
+	results := make([]struct {
+		FQN  string
+		Size int64
+	}, 0)
+	err := connectionPool.Select(&results, query)
+	if err != nil {
+		gplog.Verbose("Unable to retrieve table sizes for report: %v", err)
+		return sizes
+	}
+	for _, result := range results {
+		sizes[result.FQN] = result.Size
+	}
+	return sizes
+}
+
+/*
+ * These statuses match the ones used in the email contacts file, so that the
+ * same values can be used when processing either.
+ */
+func GetExitStatus(errMsg string) string {
+	errorCode := gplog.GetErrorCode()
+	if errMsg != "" || errorCode == 2 {
+		return "failure"
+	} else if errorCode == 1 {
+		return "success_with_errors"
+	}
+	return "success"
+}
+
+func (report *Report) ConstructBackupJSONReport(timestamp string, objectCounts map[string]int, tables []TableDataReport, errMsg string) BackupJSONReport {
+	startTime, _ := time.ParseInLocation("20060102150405", timestamp, operating.System.Local)
+	endTime := operating.System.Now()
+	return BackupJSONReport{
+		BackupConfig:     report.BackupConfig,
+		CommandLine:      strings.Join(os.Args, " "),
+		EffectiveFlags:   report.EffectiveFlags,
+		DatabaseSize:     report.DatabaseSize,
+		RetentionSummary: report.RetentionSummary,
+		Status:           GetExitStatus(errMsg),
+		Error:            errMsg,
+		StartTime:        startTime.Format(time.RFC3339),
+		EndTime:          endTime.Format(time.RFC3339),
+		DurationSeconds:  endTime.Sub(startTime).Seconds(),
+		ObjectCounts:     objectCounts,
+		Tables:           tables,
+	}
+}
+
+func ConstructRestoreJSONReport(backupConfig *backup_history.BackupConfig, backupTimestamp string, startTimestamp string, connectionPool *dbconn.DBConn,
+	restoreVersion string, effectiveFlags []string, tables []TableDataReport, failedStatements []FailedStatement, errMsg string) RestoreJSONReport {
+	startTime, _ := time.ParseInLocation("20060102150405", startTimestamp, operating.System.Local)
+	endTime := operating.System.Now()
+	failedTables := make([]string, 0)
+	for _, table := range tables {
+		if table.Error != "" {
+			failedTables = append(failedTables, MakeFQN(table.Schema, table.Name))
+		}
+	}
+	return RestoreJSONReport{
+		Timestamp:        backupTimestamp,
+		RestoreTimestamp: startTimestamp,
+		DatabaseName:     connectionPool.DBName,
+		DatabaseVersion:  connectionPool.Version.VersionString,
+		RestoreVersion:   restoreVersion,
+		CommandLine:      strings.Join(os.Args, " "),
+		EffectiveFlags:   effectiveFlags,
+		Status:           GetExitStatus(errMsg),
+		Error:            errMsg,
+		StartTime:        startTime.Format(time.RFC3339),
+		EndTime:          endTime.Format(time.RFC3339),
+		DurationSeconds:  endTime.Sub(startTime).Seconds(),
+		BackupConfig:     backupConfig,
+		Tables:           tables,
+		FailedTables:     failedTables,
+		FailedStatements: failedStatements,
+	}
+}
+
+func WriteJSONReportFile(reportFilename string, report interface{}) {
+	contents, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		gplog.Error("Unable to construct report file %s: %v", reportFilename, err)
+		return
+	}
+	reportFile, err := iohelper.OpenFileForWriting(reportFilename)
+	if err != nil {
+		gplog.Error("Unable to open report file %s", reportFilename)
+		return
+	}
+	_, err = reportFile.Write(append(contents, '\n'))
+	if err != nil {
+		gplog.Error("Unable to write report file %s", reportFilename)
+		return
+	}
+	_ = reportFile.Close()
 	_ = operating.System.Chmod(reportFilename, 0444)
 }
 
@@ -283,13 +655,7 @@ func GetContacts(filename string, utility string) string {
 		return ""
 	}
 
-	errorCode := gplog.GetErrorCode()
-	exitStatus := "success"
-	if errorCode == 1 {
-		exitStatus = "success_with_errors"
-	} else if errorCode == 2 {
-		exitStatus = "failure"
-	}
+	exitStatus := GetExitStatus("")
 
 	contactList := make([]string, 0)
 	for _, contact := range contactFile.Contacts[utility] {
@@ -308,7 +674,7 @@ Content-Type: text/html
 Content-Disposition: inline
 <html>
 <body>
-<pre style=\"font: monospace\">
+<pre style="font: monospace">
 `, contactList, utility, timestamp, hostname)
 	emailFooter := `
 </pre>
@@ -341,7 +707,9 @@ func EmailReport(c *cluster.Cluster, timestamp string, reportFilePath string, ut
 	}
 	message := ConstructEmailMessage(timestamp, contactList, reportFilePath, utility)
 	gplog.Verbose("Sending email report to the following addresses: %s", contactList)
-	output, sendErr := c.ExecuteLocalCommand(fmt.Sprintf(`echo "%s" | sendmail -t`, message))
+	// Single-quote the message so the shell does not interpret quotes, $, or ` in the report
+	quotedMessage := strings.Replace(message, `'`, `'"'"'`, -1)
+	output, sendErr := c.ExecuteLocalCommand(fmt.Sprintf(`echo '%s' | sendmail -t`, quotedMessage))
 	if sendErr != nil {
 		gplog.Warn("Unable to send email report: %s", output)
 	}
@@ -1,10 +1,12 @@
 package utils_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"regexp"
 	"time"
 
 	"github.com/greenplum-db/gpbackup/options"
@@ -21,11 +23,13 @@ import (
 	"github.com/greenplum-db/gpbackup/backup_history"
 	"github.com/greenplum-db/gpbackup/testutils"
 	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/lib/pq"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gbytes"
 	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
 	"gopkg.in/yaml.v2"
 )
 
@@ -157,6 +161,14 @@ sequences                    1
 tables                       42
 types                        1000`))
 		})
+		It("writes a report with the effective flags of a backup run with a config file", func() {
+			backupReport.EffectiveFlags = []string{"--config=/home/gpadmin/gpbackup.yaml", "--dbname=testdb", "--jobs=4"}
+			backupReport.WriteBackupReportFile("filename", timestamp, objectCounts, "")
+			Expect(buffer).To(gbytes.Say(`Database Name: testdb
+Command Line: .*
+Effective Flags: --config=/home/gpadmin/gpbackup\.yaml --dbname=testdb --jobs=4
+Compression: gzip`))
+		})
 	})
 	Describe("WriteRestoreReportFile", func() {
 		timestamp := "20170101010101"
@@ -186,7 +198,7 @@ types                        1000`))
 
 		It("writes a report for a failed restore", func() {
 			gplog.SetErrorCode(2)
-			utils.WriteRestoreReportFile("filename", timestamp, restoreStartTime, connectionPool, restoreVersion, "Cannot access /tmp/backups: Permission denied")
+			utils.WriteRestoreReportFile("filename", timestamp, restoreStartTime, connectionPool, restoreVersion, nil, nil, nil, "", "Cannot access /tmp/backups: Permission denied")
 			Expect(buffer).To(gbytes.Say(`Greenplum Database Restore Report
 
 Timestamp Key: 20170101010101
@@ -205,7 +217,7 @@ Restore Error: Cannot access /tmp/backups: Permission denied`))
 		})
 		It("writes a report for a successful restore", func() {
 			gplog.SetErrorCode(0)
-			utils.WriteRestoreReportFile("filename", timestamp, restoreStartTime, connectionPool, restoreVersion, "")
+			utils.WriteRestoreReportFile("filename", timestamp, restoreStartTime, connectionPool, restoreVersion, nil, nil, nil, "", "")
 			Expect(buffer).To(gbytes.Say(`Greenplum Database Restore Report
 
 Timestamp Key: 20170101010101
@@ -223,7 +235,7 @@ Restore Status: Success`))
 		})
 		It("writes a report for a successful restore with errors", func() {
 			gplog.SetErrorCode(1)
-			utils.WriteRestoreReportFile("filename", timestamp, restoreStartTime, connectionPool, restoreVersion, "")
+			utils.WriteRestoreReportFile("filename", timestamp, restoreStartTime, connectionPool, restoreVersion, nil, nil, nil, "", "")
 			Expect(buffer).To(gbytes.Say(`Greenplum Database Restore Report
 
 Timestamp Key: 20170101010101
@@ -239,6 +251,177 @@ Duration: 4:03:01
 
 Restore Status: Success but non-fatal errors occurred. See log file .+ for details.`))
 		})
+		It("lists the failed statements and table data restores of a restore with errors", func() {
+			gplog.SetErrorCode(1)
+			tables := []utils.TableDataReport{
+				{Schema: "public", Name: "foo", Rows: 10, RowsExpected: 10},
+				{Schema: "public", Name: "bar", Rows: 0, RowsExpected: 10, Error: "Error loading data into table public.bar: pq: could not open file", SQLState: "58P01"},
+			}
+			failedStatements := []utils.FailedStatement{
+				{Schema: "public", Name: "baz", ObjectType: "VIEW", Statement: "CREATE VIEW public.baz AS SELECT 1;", Error: "pq: permission denied for schema public", SQLState: "42501"},
+				{Name: "plpythonu", ObjectType: "LANGUAGE", Statement: "CREATE LANGUAGE plpythonu;", Error: "connection refused"},
+			}
+			utils.WriteRestoreReportFile("filename", timestamp, restoreStartTime, connectionPool, restoreVersion, nil, tables, failedStatements,
+				"/data/gprestore_20170101010101_20170101010102_errors.sql", "")
+			Expect(buffer).To(gbytes.Say(regexp.QuoteMeta(`Restore Status: Success but non-fatal errors occurred. See log file`)))
+			Expect(buffer).To(gbytes.Say(regexp.QuoteMeta(`
+
+Failed Statements: 2
+VIEW public.baz (SQLSTATE 42501): pq: permission denied for schema public
+LANGUAGE plpythonu: connection refused
+
+Failed Table Data: 1
+public.bar (10 rows expected, 0 rows restored) (SQLSTATE 58P01): Error loading data into table public.bar: pq: could not open file
+
+Statements to re-run the failed statements and table data restores: /data/gprestore_20170101010101_20170101010102_errors.sql`)))
+		})
+	})
+	Describe("JSON reports", func() {
+		startTime := time.Date(2017, 1, 1, 1, 1, 1, 0, time.Local)
+		endTime := time.Date(2017, 1, 1, 5, 4, 3, 0, time.Local)
+		tables := []utils.TableDataReport{{Schema: "public", Name: "foo", Rows: 10, RelationSize: 32768, StartTime: startTime.Format(time.RFC3339),
+			EndTime: endTime.Format(time.RFC3339), DurationSeconds: endTime.Sub(startTime).Seconds()}}
+		BeforeEach(func() {
+			operating.System.Now = func() time.Time {
+				return endTime
+			}
+		})
+		AfterEach(func() {
+			gplog.SetErrorCode(0)
+		})
+		Describe("ConstructBackupJSONReport", func() {
+			It("includes the backup configuration, status, object counts, and tables", func() {
+				backupReport := &utils.Report{DatabaseSize: "42 MB", BackupConfig: backup_history.BackupConfig{DatabaseName: "testdb", Timestamp: "20170101010101", Compressed: true}}
+				objectCounts := map[string]int{"tables": 42}
+
+				jsonReport := backupReport.ConstructBackupJSONReport("20170101010101", objectCounts, tables, "")
+
+				structmatcher.ExpectStructsToMatch(&backupReport.BackupConfig, &jsonReport.BackupConfig)
+				Expect(jsonReport.DatabaseSize).To(Equal("42 MB"))
+				Expect(jsonReport.Status).To(Equal("success"))
+				Expect(jsonReport.Error).To(Equal(""))
+				Expect(jsonReport.StartTime).To(Equal(startTime.Format(time.RFC3339)))
+				Expect(jsonReport.EndTime).To(Equal(endTime.Format(time.RFC3339)))
+				Expect(jsonReport.DurationSeconds).To(Equal(float64(14582)))
+				Expect(jsonReport.ObjectCounts).To(Equal(objectCounts))
+				Expect(jsonReport.Tables).To(Equal(tables))
+			})
+			It("includes the error for a failed backup", func() {
+				backupReport := &utils.Report{}
+
+				jsonReport := backupReport.ConstructBackupJSONReport("20170101010101", map[string]int{}, nil, "Cannot access /tmp/backups: Permission denied")
+
+				Expect(jsonReport.Status).To(Equal("failure"))
+				Expect(jsonReport.Error).To(Equal("Cannot access /tmp/backups: Permission denied"))
+			})
+		})
+		Describe("ConstructRestoreJSONReport", func() {
+			connectionPool := &dbconn.DBConn{DBName: "testdb", Version: dbconn.GPDBVersion{VersionString: "5.0.0 build test"}}
+			backupConfig := &backup_history.BackupConfig{DatabaseName: "olddb", Timestamp: "20170101010101"}
+			It("lists failed tables and statements for a restore with errors", func() {
+				gplog.SetErrorCode(1)
+				failedTable := utils.TableDataReport{Schema: "public", Name: "bar", Error: "Expected to restore 10 rows to table public.bar, but restored 0 instead"}
+				failedStatements := []utils.FailedStatement{{Schema: "public", Name: "baz", ObjectType: "VIEW", Statement: "CREATE VIEW public.baz AS SELECT 1;", Error: "permission denied"}}
+
+				jsonReport := utils.ConstructRestoreJSONReport(backupConfig, "20170101010101", "20170101010101", connectionPool, "0.1.0", nil,
+					append(tables, failedTable), failedStatements, "")
+
+				Expect(jsonReport.Timestamp).To(Equal("20170101010101"))
+				Expect(jsonReport.DatabaseName).To(Equal("testdb"))
+				Expect(jsonReport.DatabaseVersion).To(Equal("5.0.0 build test"))
+				Expect(jsonReport.RestoreVersion).To(Equal("0.1.0"))
+				Expect(jsonReport.Status).To(Equal("success_with_errors"))
+				Expect(jsonReport.BackupConfig).To(Equal(backupConfig))
+				Expect(jsonReport.Tables).To(HaveLen(2))
+				Expect(jsonReport.FailedTables).To(Equal([]string{"public.bar"}))
+				Expect(jsonReport.FailedStatements).To(Equal(failedStatements))
+			})
+		})
+		Describe("WriteJSONReportFile", func() {
+			It("writes the report as JSON", func() {
+				reportBuffer := gbytes.NewBuffer()
+				operating.System.OpenFileWrite = func(name string, flag int, perm os.FileMode) (io.WriteCloser, error) {
+					return reportBuffer, nil
+				}
+				operating.System.Chmod = func(name string, mode os.FileMode) error {
+					return nil
+				}
+				backupReport := &utils.Report{BackupConfig: backup_history.BackupConfig{DatabaseName: "testdb"}}
+				jsonReport := backupReport.ConstructBackupJSONReport("20170101010101", map[string]int{"tables": 1}, tables, "")
+
+				utils.WriteJSONReportFile("filename", jsonReport)
+
+				var result utils.BackupJSONReport
+				err := json.Unmarshal(reportBuffer.Contents(), &result)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.DatabaseName).To(Equal("testdb"))
+				Expect(result.ObjectCounts).To(Equal(map[string]int{"tables": 1}))
+				Expect(result.Tables).To(Equal(tables))
+			})
+		})
+		Describe("TableDataReportList", func() {
+			It("returns tables sorted by name with their sizes", func() {
+				list := utils.TableDataReportList{}
+				list.AddTable("public", "foo", 10, startTime, endTime, nil)
+				list.AddTable("public", "bar", 0, startTime, endTime, errors.New("permission denied"))
+				list.SetTableSizes(map[string]int64{"public.foo": 32768})
+
+				result := list.GetTables()
+
+				Expect(result).To(HaveLen(2))
+				Expect(result[0].Name).To(Equal("bar"))
+				Expect(result[0].Error).To(Equal("permission denied"))
+				Expect(result[0].RelationSize).To(Equal(int64(0)))
+				Expect(result[1]).To(Equal(tables[0]))
+			})
+			It("records the expected rows and SQLSTATE of a restored table", func() {
+				list := utils.TableDataReportList{}
+				loadErr := errors.Wrap(&pq.Error{Code: "58P01", Message: "could not open file"}, "Error loading data into table public.foo")
+				list.AddRestoredTable("public", "foo", 0, 10, startTime, endTime, loadErr)
+
+				result := list.GetTables()
+
+				Expect(result[0].Rows).To(Equal(int64(0)))
+				Expect(result[0].RowsExpected).To(Equal(int64(10)))
+				Expect(result[0].Error).To(Equal("Error loading data into table public.foo: pq: could not open file"))
+				Expect(result[0].SQLState).To(Equal("58P01"))
+			})
+		})
+		Describe("GetTableSizes", func() {
+			It("looks up the sizes of the tables by oid", func() {
+				mock.ExpectQuery(regexp.QuoteMeta("WHERE c.oid IN (16384, 16390)")).
+					WillReturnRows(sqlmock.NewRows([]string{"fqn", "size"}).AddRow("public.foo", 32768).AddRow(`public."Bar"`, 0))
+
+				Expect(utils.GetTableSizes(connectionPool, []uint32{16384, 16390})).To(Equal(map[string]int64{"public.foo": 32768, `public."Bar"`: 0}))
+			})
+			It("returns no sizes if they cannot be retrieved", func() {
+				mock.ExpectQuery("SELECT quote_ident").WillReturnError(errors.New("connection lost"))
+
+				Expect(utils.GetTableSizes(connectionPool, []uint32{16384})).To(BeEmpty())
+			})
+		})
+		Describe("GetTableSizesByName", func() {
+			It("looks up the sizes of the tables by name", func() {
+				tables := []utils.TableDataReport{{Schema: "public", Name: "foo"}, {Schema: "public", Name: `"Bar's"`}}
+				mock.ExpectQuery(regexp.QuoteMeta(`JOIN (VALUES ('public', 'foo'), ('public', '"Bar''s"')) AS t(schemaname, tablename)`)).
+					WillReturnRows(sqlmock.NewRows([]string{"fqn", "size"}).AddRow("public.foo", 32768))
+
+				Expect(utils.GetTableSizesByName(connectionPool, tables)).To(Equal(map[string]int64{"public.foo": 32768}))
+			})
+		})
+		Describe("FailedStatementList", func() {
+			It("records the SQLSTATE of statements that failed in the database", func() {
+				list := utils.FailedStatementList{}
+				view := utils.StatementWithType{Schema: "public", Name: "baz", ObjectType: "VIEW", Statement: "\n\nCREATE VIEW public.baz AS SELECT 1;\n"}
+				list.AddStatement(view, &pq.Error{Code: "42501", Message: "permission denied for schema public"})
+				list.AddStatement(view, errors.New("connection refused"))
+
+				Expect(list.GetStatements()).To(Equal([]utils.FailedStatement{
+					{Schema: "public", Name: "baz", ObjectType: "VIEW", Statement: "CREATE VIEW public.baz AS SELECT 1;", Error: "pq: permission denied for schema public", SQLState: "42501"},
+					{Schema: "public", Name: "baz", ObjectType: "VIEW", Statement: "CREATE VIEW public.baz AS SELECT 1;", Error: "connection refused"},
+				}))
+			})
+		})
 	})
 	Describe("SetBackupParamFromFlags", func() {
 		AfterEach(func() {
@@ -274,6 +457,19 @@ Restore Status: Success but non-fatal errors occurred. See log file .+ for detai
 				IncludeTableFiltered: true,
 			}, backupConfig)
 		})
+		It("stores labels in the backup config", func() {
+			backupCmdFlags := pflag.NewFlagSet("gpbackup", pflag.ExitOnError)
+			backup.SetFlagDefaults(backupCmdFlags)
+			backup.SetCmdFlags(backupCmdFlags)
+			Expect(backupCmdFlags.Set(utils.LABEL, "release=pre-5.1")).To(Succeed())
+			Expect(backupCmdFlags.Set(utils.LABEL, "owner=dba")).To(Succeed())
+			opts, err := options.NewOptions(backupCmdFlags)
+			Expect(err).ToNot(HaveOccurred())
+
+			backupConfig := backup.NewBackupConfig("testdb", "5.0.0 build test", "0.1.0", "", "timestamp1", *opts)
+
+			Expect(backupConfig.Labels).To(Equal(map[string]string{"release": "pre-5.1", "owner": "dba"}))
+		})
 	})
 	Describe("GetDurationInfo", func() {
 		timestamp := "20170101010101"
@@ -468,7 +664,7 @@ Content-Type: text/html
 Content-Disposition: inline
 <html>
 <body>
-<pre style=\"font: monospace\">
+<pre style="font: monospace">
 Greenplum Database Backup Report
 
 Timestamp Key: 20170101010101
@@ -482,17 +678,17 @@ Timestamp Key: 20170101010101
 			var (
 				expectedHomeCmd   = "test -f home/gp_email_contacts.yaml"
 				expectedGpHomeCmd = "test -f gphome/bin/gp_email_contacts.yaml"
-				expectedMessage   = `echo "To: contact1@example.com
+				expectedMessage   = `echo 'To: contact1@example.com
 Subject: gpbackup 20170101010101 on localhost completed
 Content-Type: text/html
 Content-Disposition: inline
 <html>
 <body>
-<pre style=\"font: monospace\">
+<pre style="font: monospace">
 
 </pre>
 </body>
-</html>" | sendmail -t`
+</html>' | sendmail -t`
 			)
 			It("sends no email and raises a warning if no gp_email_contacts.yaml file is found", func() {
 				w.Write(contactsFileContents)
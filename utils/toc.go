@@ -1,9 +1,12 @@
 package utils
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/greenplum-db/gp-common-go-libs/gplog"
 	"github.com/greenplum-db/gp-common-go-libs/iohelper"
@@ -32,6 +35,18 @@ type MetadataEntry struct {
 	ReferenceObject string
 	StartByte       uint64
 	EndByte         uint64
+	FilePath        string
+	ID              UniqueID   `yaml:",omitempty"`
+	Dependencies    []UniqueID `yaml:",omitempty"`
+}
+
+/*
+ * Identifies a database object by the catalog table it is stored in and its
+ * oid.  Pre-data entries in the TOC record the objects they depend on by it.
+ */
+type UniqueID struct {
+	ClassID uint32
+	Oid     uint32
 }
 
 type MasterDataEntry struct {
@@ -41,6 +56,7 @@ type MasterDataEntry struct {
 	AttributeString string
 	RowsCopied      int64
 	PartitionRoot   string
+	Checksum        string `yaml:",omitempty"`
 }
 
 type SegmentDataEntry struct {
@@ -108,6 +124,12 @@ type StatementWithType struct {
 	ObjectType      string
 	ReferenceObject string
 	Statement       string
+	ID              UniqueID
+	Dependencies    []UniqueID
+}
+
+func (statement StatementWithType) GetObjectName() string {
+	return MakeObjectName(statement.Schema, statement.Name)
 }
 
 func GetIncludedPartitionRoots(tocDataEntries []MasterDataEntry, includeRelations []string) []string {
@@ -133,21 +155,66 @@ func GetIncludedPartitionRoots(tocDataEntries []MasterDataEntry, includeRelation
 }
 
 func (toc *TOC) GetSQLStatementForObjectTypes(section string, metadataFile io.ReaderAt, includeObjectTypes []string, excludeObjectTypes []string, includeSchemas []string, excludeSchemas []string, includeRelations []string, excludeRelations []string) []StatementWithType {
+	return toc.GetSQLStatementForObjectTypesAndDependencies(section, metadataFile, includeObjectTypes, excludeObjectTypes, includeSchemas, excludeSchemas, includeRelations, excludeRelations, nil)
+}
+
+/*
+ * Statements for the objects in the dependencies set are returned along with
+ * the statements matching the filters, regardless of their schema or name, as
+ * long as they are of an included object type.
+ */
+func (toc *TOC) GetSQLStatementForObjectTypesAndDependencies(section string, metadataFile io.ReaderAt, includeObjectTypes []string, excludeObjectTypes []string, includeSchemas []string, excludeSchemas []string, includeRelations []string, excludeRelations []string, dependencies map[UniqueID]bool) []StatementWithType {
 	entries := *toc.metadataEntryMap[section]
 
 	objectSet, schemaSet, relationSet := constructFilterSets(includeObjectTypes, excludeObjectTypes, includeSchemas, excludeSchemas, includeRelations, excludeRelations)
 	statements := make([]StatementWithType, 0)
 	for _, entry := range entries {
-		if shouldIncludeStatement(entry, objectSet, schemaSet, relationSet) {
+		if shouldIncludeStatement(entry, objectSet, schemaSet, relationSet) || (dependencies[entry.ID] && objectSet.MatchesFilter(entry.ObjectType)) {
 			contents := make([]byte, entry.EndByte-entry.StartByte)
 			_, err := metadataFile.ReadAt(contents, int64(entry.StartByte))
 			gplog.FatalOnError(err)
-			statements = append(statements, StatementWithType{Schema: entry.Schema, Name: entry.Name, ObjectType: entry.ObjectType, ReferenceObject: entry.ReferenceObject, Statement: string(contents)})
+			statements = append(statements, StatementWithType{Schema: entry.Schema, Name: entry.Name, ObjectType: entry.ObjectType, ReferenceObject: entry.ReferenceObject, Statement: string(contents), ID: entry.ID, Dependencies: entry.Dependencies})
 		}
 	}
 	return statements
 }
 
+/*
+ * Returns the pre-data objects that the given relations depend on, directly or
+ * through other objects, according to the dependencies recorded in the TOC at
+ * backup time.  The relations themselves are not included.
+ */
+func (toc *TOC) GetDependenciesOfRelations(relationFQNs []string) map[UniqueID]bool {
+	relationSet := NewIncludeSet(relationFQNs)
+	dependenciesForID := make(map[UniqueID][]UniqueID, 0)
+	selected := make(map[UniqueID]bool, 0)
+	queue := make([]UniqueID, 0)
+	for _, entry := range toc.PredataEntries {
+		if entry.ID == (UniqueID{}) {
+			continue
+		}
+		dependenciesForID[entry.ID] = entry.Dependencies
+		isRelation := entry.ObjectType == "TABLE" || entry.ObjectType == "VIEW" || entry.ObjectType == "SEQUENCE"
+		if isRelation && entry.ReferenceObject == "" && !selected[entry.ID] && relationSet.MatchesFilter(MakeFQN(entry.Schema, entry.Name)) {
+			selected[entry.ID] = true
+			queue = append(queue, entry.ID)
+		}
+	}
+
+	dependencies := make(map[UniqueID]bool, 0)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, dependency := range dependenciesForID[id] {
+			if _, inTOC := dependenciesForID[dependency]; inTOC && !selected[dependency] && !dependencies[dependency] {
+				dependencies[dependency] = true
+				queue = append(queue, dependency)
+			}
+		}
+	}
+	return dependencies
+}
+
 func constructFilterSets(includeObjectTypes []string, excludeObjectTypes []string, includeSchemas []string, excludeSchemas []string, includeRelations []string, excludeRelations []string) (*FilterSet, *FilterSet, *FilterSet) {
 	var objectSet, schemaSet, relationSet *FilterSet
 	if len(includeObjectTypes) > 0 {
@@ -243,6 +310,275 @@ func SubstituteRedirectDatabaseInStatements(statements []StatementWithType, oldQ
 	return statements
 }
 
+/*
+ * Returns the names of the pre-data and post-data objects in the given
+ * schemas, qualified by their schema, so that references to them can be
+ * told apart from column references qualified by a table with the same name
+ * as the schema.  Function and aggregate names are returned without their
+ * arguments.
+ */
+func (toc *TOC) GetObjectFQNsInSchemas(quotedSchemas []string) map[string]bool {
+	schemaSet := make(map[string]bool, len(quotedSchemas))
+	for _, schema := range quotedSchemas {
+		schemaSet[schema] = true
+	}
+	objectFQNs := make(map[string]bool, 0)
+	for _, entries := range [][]MetadataEntry{toc.PredataEntries, toc.PostdataEntries} {
+		for _, entry := range entries {
+			if !schemaSet[entry.Schema] {
+				continue
+			}
+			name := entry.Name
+			if entry.ObjectType == "FUNCTION" || entry.ObjectType == "AGGREGATE" {
+				name = name[:scanIdentifier(name, 0)]
+			}
+			objectFQNs[MakeFQN(entry.Schema, name)] = true
+		}
+	}
+	return objectFQNs
+}
+
+/*
+ * References to the objects in objectFQNs are changed to the new schema where
+ * they appear as identifiers, such as in column types, view definitions, and
+ * constraint expressions, and in literals cast to an object identifier type,
+ * such as a sequence name passed to nextval.  Other string literals, comments,
+ * and dollar-quoted function bodies are not changed, so a function body that
+ * refers to an old schema still refers to it after the restore and a warning
+ * is logged for it.
+ */
+func SubstituteRedirectSchemaInStatements(statements []StatementWithType, oldQuotedSchemas []string, newQuotedSchema string, objectFQNs map[string]bool) []StatementWithType {
+	oldSchemaSet := make(map[string]bool, len(oldQuotedSchemas))
+	for _, schema := range oldQuotedSchemas {
+		oldSchemaSet[schema] = true
+	}
+	for i := range statements {
+		if oldSchemaSet[statements[i].Schema] {
+			statements[i].Schema = newQuotedSchema
+		}
+		if schema, name := SplitFQN(statements[i].ReferenceObject); oldSchemaSet[schema] {
+			statements[i].ReferenceObject = MakeFQN(newQuotedSchema, name)
+		}
+		var skippedSchema string
+		statements[i].Statement, skippedSchema = replaceSchemaInIdentifiers(statements[i].Statement, oldSchemaSet, newQuotedSchema, objectFQNs)
+		if skippedSchema != "" {
+			gplog.Warn("The body of %s %s refers to schema %s, which will not be changed to %s", statements[i].ObjectType, statements[i].GetObjectName(), skippedSchema, newQuotedSchema)
+		}
+	}
+	return statements
+}
+
+var dollarQuoteTag = regexp.MustCompile(`^\$([A-Za-z_\x80-\xff][\w\x80-\xff]*)?\$`)
+
+/*
+ * Scans a statement token by token so that only schema-qualified names outside
+ * of literals and comments are replaced.  Returns the first old schema that
+ * appears in a dollar-quoted body, if any.
+ */
+func replaceSchemaInIdentifiers(str string, oldSchemaSet map[string]bool, newSchema string, objectFQNs map[string]bool) (string, string) {
+	var result bytes.Buffer
+	skippedSchema := ""
+	replaceQualifiedName := func(str string, i int) int {
+		end := scanIdentifier(str, i)
+		schema := str[i:end]
+		if end == i || !oldSchemaSet[schema] || end == len(str) || str[end] != '.' {
+			result.WriteString(schema)
+			return end
+		}
+		nameEnd := scanIdentifier(str, end+1)
+		if nameEnd == end+1 {
+			nameEnd = scanOperator(str, end+1)
+		}
+		if objectFQNs[MakeFQN(schema, str[end+1:nameEnd])] {
+			result.WriteString(newSchema + str[end:nameEnd])
+		} else {
+			result.WriteString(str[i:nameEnd])
+		}
+		return nameEnd
+	}
+	for i := 0; i < len(str); {
+		char := str[i]
+		switch {
+		case strings.HasPrefix(str[i:], "--"):
+			end := strings.Index(str[i:], "\n")
+			if end == -1 {
+				end = len(str) - i
+			}
+			result.WriteString(str[i : i+end])
+			i += end
+		case strings.HasPrefix(str[i:], "/*"):
+			end := strings.Index(str[i+2:], "*/")
+			if end == -1 {
+				end = len(str) - i
+			} else {
+				end += 4
+			}
+			result.WriteString(str[i : i+end])
+			i += end
+		case char == '\'':
+			escaped := i > 0 && (str[i-1] == 'E' || str[i-1] == 'e') && (i == 1 || !isIdentChar(str[i-2]))
+			end := scanStringLiteral(str, i, escaped)
+			if strings.HasPrefix(str[end:], "::reg") {
+				// Literals cast to regclass, regproc, and so on name an object
+				result.WriteByte('\'')
+				nameEnd := replaceQualifiedName(str[:end-1], i+1)
+				result.WriteString(str[nameEnd:end])
+			} else {
+				result.WriteString(str[i:end])
+			}
+			i = end
+		case char == '$' && (i == 0 || !isIdentChar(str[i-1])) && dollarQuoteTag.MatchString(str[i:]):
+			tag := dollarQuoteTag.FindString(str[i:])
+			end := strings.Index(str[i+len(tag):], tag)
+			if end == -1 {
+				end = len(str) - i
+			} else {
+				end += 2 * len(tag)
+			}
+			body := str[i : i+end]
+			for schema := range oldSchemaSet {
+				if skippedSchema == "" && strings.Contains(body, schema+".") {
+					skippedSchema = schema
+				}
+			}
+			result.WriteString(body)
+			i += end
+		case isIdentChar(char):
+			i = replaceQualifiedName(str, i)
+		default:
+			result.WriteByte(char)
+			i++
+		}
+	}
+	return result.String(), skippedSchema
+}
+
+func isIdentChar(char byte) bool {
+	return char == '_' || char == '$' || char == '"' || char >= 0x80 ||
+		(char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') || (char >= '0' && char <= '9')
+}
+
+// Returns the index just past the quoted or unquoted identifier starting at i
+func scanIdentifier(str string, i int) int {
+	if i < len(str) && str[i] == '"' {
+		for j := i + 1; j < len(str); j++ {
+			if str[j] == '"' {
+				if j+1 < len(str) && str[j+1] == '"' {
+					j++
+					continue
+				}
+				return j + 1
+			}
+		}
+		return len(str)
+	}
+	j := i
+	for j < len(str) && isIdentChar(str[j]) && str[j] != '"' {
+		j++
+	}
+	return j
+}
+
+func scanOperator(str string, i int) int {
+	j := i
+	for j < len(str) && strings.IndexByte("+-*/<>=~!@#%^&|`?", str[j]) != -1 {
+		j++
+	}
+	return j
+}
+
+// Returns the index just past the string literal starting at i
+func scanStringLiteral(str string, i int, escaped bool) int {
+	for j := i + 1; j < len(str); j++ {
+		if escaped && str[j] == '\\' {
+			j++
+		} else if str[j] == '\'' {
+			if j+1 < len(str) && str[j+1] == '\'' {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(str)
+}
+
+/*
+ * Renames each mapped table in its own statements and in the statements of the
+ * objects that depend on it.  Indexes and constraints are renamed after the new
+ * table, so that they do not conflict with those of the original table when
+ * both tables are in the same schema.
+ */
+func SubstituteTableMapInStatements(statements []StatementWithType, tableMap map[string]string) []StatementWithType {
+	for i := range statements {
+		oldFQN := statements[i].ReferenceObject
+		if oldFQN == "" {
+			oldFQN = MakeFQN(statements[i].Schema, statements[i].Name)
+		}
+		newFQN, ok := tableMap[oldFQN]
+		if !ok {
+			continue
+		}
+		newSchema, newTable := SplitFQN(newFQN)
+		statement := statements[i].Statement
+		if statements[i].ReferenceObject == "" {
+			statements[i].Schema = newSchema
+			statements[i].Name = newTable
+		} else {
+			statements[i].ReferenceObject = newFQN
+			if statements[i].ObjectType == "INDEX" || statements[i].ObjectType == "CONSTRAINT" {
+				_, oldTable := SplitFQN(oldFQN)
+				newName := getMappedObjectName(statements[i].Name, oldTable, newTable)
+				statement = replaceIdentifier(statement, MakeFQN(statements[i].Schema, statements[i].Name), MakeFQN(newSchema, newName))
+				statement = replaceIdentifier(statement, statements[i].Name, newName)
+				statements[i].Schema = newSchema
+				statements[i].Name = newName
+			}
+		}
+		statements[i].Statement = replaceIdentifier(statement, oldFQN, newFQN)
+	}
+	return statements
+}
+
+/*
+ * An object named after its table, such as orders_pkey, is renamed after the
+ * new table, and any other object is prefixed with the new table name.
+ */
+func getMappedObjectName(name string, oldTable string, newTable string) string {
+	unquotedName, unquotedOldTable, unquotedNewTable := UnquoteIdent(name), UnquoteIdent(oldTable), UnquoteIdent(newTable)
+	newName := unquotedNewTable + "_" + unquotedName
+	if strings.Contains(unquotedName, unquotedOldTable) {
+		newName = strings.Replace(unquotedName, unquotedOldTable, unquotedNewTable, 1)
+	}
+	if regexp.MustCompile(`^[a-z_][a-z0-9_$]*$`).MatchString(newName) {
+		return newName
+	}
+	return fmt.Sprintf(`"%s"`, strings.Replace(newName, `"`, `""`, -1))
+}
+
+// Replaces an identifier only where it is not part of a longer identifier
+func replaceIdentifier(str string, oldIdent string, newIdent string) string {
+	var result bytes.Buffer
+	start := 0
+	for {
+		i := strings.Index(str[start:], oldIdent)
+		if i == -1 {
+			break
+		}
+		i += start
+		end := i + len(oldIdent)
+		result.WriteString(str[start:i])
+		if (i == 0 || (!isIdentChar(str[i-1]) && str[i-1] != '.')) && (end == len(str) || !isIdentChar(str[end])) {
+			result.WriteString(newIdent)
+		} else {
+			result.WriteString(oldIdent)
+		}
+		start = end
+	}
+	result.WriteString(str[start:])
+	return result.String()
+}
+
 func RemoveActiveRole(activeUser string, statements []StatementWithType) []StatementWithType {
 	newStatements := make([]StatementWithType, 0)
 	for _, statement := range statements {
@@ -277,8 +613,26 @@ func (toc *TOC) AddMetadataEntry(section string, entry MetadataEntry, start, end
 	*toc.metadataEntryMap[section] = append(*toc.metadataEntryMap[section], entry)
 }
 
-func (toc *TOC) AddMasterDataEntry(schema string, name string, oid uint32, attributeString string, rowsCopied int64, PartitionRoot string) {
-	toc.DataEntries = append(toc.DataEntries, MasterDataEntry{schema, name, oid, attributeString, rowsCopied, PartitionRoot})
+/*
+ * Records the object for which every pre-data entry from index start onward was
+ * printed, along with the objects that it depends on, so that a restore can
+ * create objects in parallel once their dependencies have been created.
+ */
+func (toc *TOC) SetPredataDependencies(start int, id UniqueID, dependencies []UniqueID) {
+	sort.Slice(dependencies, func(i int, j int) bool {
+		if dependencies[i].ClassID != dependencies[j].ClassID {
+			return dependencies[i].ClassID < dependencies[j].ClassID
+		}
+		return dependencies[i].Oid < dependencies[j].Oid
+	})
+	for i := start; i < len(toc.PredataEntries); i++ {
+		toc.PredataEntries[i].ID = id
+		toc.PredataEntries[i].Dependencies = dependencies
+	}
+}
+
+func (toc *TOC) AddMasterDataEntry(schema string, name string, oid uint32, attributeString string, rowsCopied int64, PartitionRoot string, checksum string) {
+	toc.DataEntries = append(toc.DataEntries, MasterDataEntry{Schema: schema, Name: name, Oid: oid, AttributeString: attributeString, RowsCopied: rowsCopied, PartitionRoot: PartitionRoot, Checksum: checksum})
 }
 
 func (toc *SegmentTOC) AddSegmentDataEntry(oid uint, startByte uint64, endByte uint64) {
@@ -171,11 +171,11 @@ var _ = Describe("utils/toc tests", func() {
 	})
 	Describe("GetDataEntriesMatching", func() {
 		BeforeEach(func() {
-			toc.AddMasterDataEntry("schema1", "table1", 1, "(i)", 0, "")
-			toc.AddMasterDataEntry("schema2", "table2", 1, "(i)", 0, "")
-			toc.AddMasterDataEntry("schema3", "table3", 1, "(i)", 0, "")
-			toc.AddMasterDataEntry("schema3", "table3_partition1", 1, "(i)", 0, "table3")
-			toc.AddMasterDataEntry("schema3", "table3_partition2", 1, "(i)", 0, "table3")
+			toc.AddMasterDataEntry("schema1", "table1", 1, "(i)", 0, "", "")
+			toc.AddMasterDataEntry("schema2", "table2", 1, "(i)", 0, "", "")
+			toc.AddMasterDataEntry("schema3", "table3", 1, "(i)", 0, "", "")
+			toc.AddMasterDataEntry("schema3", "table3_partition1", 1, "(i)", 0, "table3", "")
+			toc.AddMasterDataEntry("schema3", "table3_partition2", 1, "(i)", 0, "table3", "")
 		})
 		Context("Non-empty restore plan", func() {
 			restorePlanTableFQNs := []string{"schema1.table1", "schema2.table2", "schema3.table3", "schema3.table3_partition1", "schema3.table3_partition2"}
@@ -317,6 +317,75 @@ var _ = Describe("utils/toc tests", func() {
 			})
 		})
 	})
+	Describe("SetPredataDependencies", func() {
+		typeID := utils.UniqueID{ClassID: 1247, Oid: 1}
+		funcID := utils.UniqueID{ClassID: 1255, Oid: 2}
+		tableID := utils.UniqueID{ClassID: 1259, Oid: 3}
+		It("sets the object and its sorted dependencies on every entry from the start index", func() {
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{Schema: "schema", Name: "type1", ObjectType: "TYPE"}, 0, 10)
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{Schema: "schema", Name: "table1", ObjectType: "TABLE"}, 10, 20)
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{Schema: "schema", Name: "table1", ObjectType: "TABLE"}, 20, 30)
+
+			toc.SetPredataDependencies(1, tableID, []utils.UniqueID{funcID, typeID})
+
+			Expect(toc.PredataEntries[0].ID).To(Equal(utils.UniqueID{}))
+			Expect(toc.PredataEntries[0].Dependencies).To(BeNil())
+			for _, entry := range toc.PredataEntries[1:] {
+				Expect(entry.ID).To(Equal(tableID))
+				Expect(entry.Dependencies).To(Equal([]utils.UniqueID{typeID, funcID}))
+			}
+		})
+		It("returns the dependencies of an entry with its statement", func() {
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{Schema: "schema", Name: "table1", ObjectType: "TABLE"}, 0, table1Len)
+			toc.SetPredataDependencies(0, tableID, []utils.UniqueID{typeID})
+			metadataFile := bytes.NewReader([]byte(table1.Statement))
+
+			statements := toc.GetSQLStatementForObjectTypes("predata", metadataFile, []string{}, []string{}, []string{}, []string{}, []string{}, []string{})
+
+			expectedStatement := table1
+			expectedStatement.ID = tableID
+			expectedStatement.Dependencies = []utils.UniqueID{typeID}
+			Expect(statements).To(Equal([]utils.StatementWithType{expectedStatement}))
+		})
+	})
+	Describe("GetDependenciesOfRelations", func() {
+		typeID := utils.UniqueID{ClassID: 1247, Oid: 1}
+		funcID := utils.UniqueID{ClassID: 1255, Oid: 2}
+		seqID := utils.UniqueID{ClassID: 1259, Oid: 3}
+		parentID := utils.UniqueID{ClassID: 1259, Oid: 4}
+		tableID := utils.UniqueID{ClassID: 1259, Oid: 5}
+		otherTableID := utils.UniqueID{ClassID: 1259, Oid: 6}
+		BeforeEach(func() {
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{Schema: "schema", Name: "type1", ObjectType: "TYPE", ID: typeID}, 0, 1)
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{Schema: "schema", Name: "func1(integer)", ObjectType: "FUNCTION", ID: funcID, Dependencies: []utils.UniqueID{typeID}}, 1, 2)
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{Schema: "schema", Name: "seq1", ObjectType: "SEQUENCE", ID: seqID}, 2, 3)
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{Schema: "schema", Name: "parent", ObjectType: "TABLE", ID: parentID}, 3, 4)
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{Schema: "schema", Name: "table1", ObjectType: "TABLE", ID: tableID, Dependencies: []utils.UniqueID{funcID, seqID, parentID}}, 4, 5)
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{Schema: "schema", Name: "table2", ObjectType: "TABLE", ID: otherTableID, Dependencies: []utils.UniqueID{typeID}}, 5, 6)
+		})
+		It("returns the objects that a relation depends on directly and indirectly", func() {
+			dependencies := toc.GetDependenciesOfRelations([]string{"schema.table1"})
+			Expect(dependencies).To(Equal(map[utils.UniqueID]bool{typeID: true, funcID: true, seqID: true, parentID: true}))
+		})
+		It("does not return relations that are already included", func() {
+			dependencies := toc.GetDependenciesOfRelations([]string{"schema.table1", "schema.parent"})
+			Expect(dependencies).To(Equal(map[utils.UniqueID]bool{typeID: true, funcID: true, seqID: true}))
+		})
+		It("returns no objects for a relation without dependencies", func() {
+			dependencies := toc.GetDependenciesOfRelations([]string{"schema.parent"})
+			Expect(dependencies).To(BeEmpty())
+		})
+		It("returns statements for the dependencies along with the included relations", func() {
+			metadataFile := bytes.NewReader([]byte("TFSPAB"))
+			dependencies := toc.GetDependenciesOfRelations([]string{"schema.table2"})
+
+			statements := toc.GetSQLStatementForObjectTypesAndDependencies("predata", metadataFile, []string{}, []string{}, []string{}, []string{}, []string{"schema.table2"}, []string{}, dependencies)
+
+			Expect(statements).To(HaveLen(2))
+			Expect(statements[0].Statement).To(Equal("T"))
+			Expect(statements[1].Statement).To(Equal("B"))
+		})
+	})
 	Describe("SubstituteRedirectDatabaseInStatements", func() {
 		create := utils.StatementWithType{Schema: "", Name: "somedatabase", ObjectType: "DATABASE", Statement: "CREATE DATABASE somedatabase TEMPLATE template0;\n"}
 		wrongCreate := utils.StatementWithType{ObjectType: "TABLE", Statement: "CREATE DATABASE somedatabase;\n"}
@@ -360,6 +429,138 @@ COMMENT ON DATABASE "db-special-chär$" IS 'this is a database comment';`}
 `))
 		})
 	})
+	Describe("GetObjectFQNsInSchemas", func() {
+		It("returns the objects in the given schemas without function arguments", func() {
+			toc.AddMetadataEntry("global", utils.MetadataEntry{Schema: "", Name: "sales", ObjectType: "SCHEMA"}, 0, 1)
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{Schema: "sales", Name: "orders", ObjectType: "TABLE"}, 1, 2)
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{Schema: "sales", Name: "count_orders(integer, \"time(1)\")", ObjectType: "FUNCTION"}, 2, 3)
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{Schema: "public", Name: "customers", ObjectType: "TABLE"}, 3, 4)
+			toc.AddMetadataEntry("postdata", utils.MetadataEntry{Schema: "sales", Name: "orders_idx", ObjectType: "INDEX", ReferenceObject: "sales.orders"}, 4, 5)
+			Expect(toc.GetObjectFQNsInSchemas([]string{"sales"})).To(Equal(map[string]bool{"sales.orders": true, "sales.count_orders": true, "sales.orders_idx": true}))
+		})
+	})
+	Describe("SubstituteRedirectSchemaInStatements", func() {
+		table := utils.StatementWithType{Schema: "sales", Name: "orders", ObjectType: "TABLE", Statement: `CREATE TABLE sales.orders (
+	id integer DEFAULT nextval('sales.orders_id_seq'::regclass) NOT NULL,
+	status sales.order_status
+) DISTRIBUTED BY (id);`}
+		view := utils.StatementWithType{Schema: "sales", Name: "open_orders", ObjectType: "VIEW", Statement: `CREATE VIEW sales.open_orders AS  SELECT orders.id
+   FROM sales.orders, presales.orders
+  WHERE (orders.status = 'open'::sales.order_status);`}
+		index := utils.StatementWithType{Schema: "sales", Name: "orders_idx", ObjectType: "INDEX", ReferenceObject: "sales.orders", Statement: "CREATE INDEX orders_idx ON sales.orders USING btree (id);"}
+		var objectFQNs map[string]bool
+		BeforeEach(func() {
+			predataEntries := []utils.MetadataEntry{
+				{Schema: "sales", Name: "order_status", ObjectType: "TYPE"},
+				{Schema: "sales", Name: "orders_id_seq", ObjectType: "SEQUENCE"},
+				{Schema: "sales", Name: "orders", ObjectType: "TABLE"},
+				{Schema: "sales", Name: "sales", ObjectType: "TABLE"},
+				{Schema: "sales", Name: "count_orders()", ObjectType: "FUNCTION"},
+				{Schema: "sales", Name: "open_orders", ObjectType: "VIEW"},
+				{Schema: "sales", Name: "sales_ids", ObjectType: "VIEW"},
+				{Schema: `"sales$1"`, Name: "orders", ObjectType: "TABLE"},
+			}
+			for i, entry := range predataEntries {
+				toc.AddMetadataEntry("predata", entry, uint64(i), uint64(i+1))
+			}
+			toc.AddMetadataEntry("postdata", utils.MetadataEntry{Schema: "sales", Name: "orders_idx", ObjectType: "INDEX", ReferenceObject: "sales.orders"}, 8, 9)
+			objectFQNs = toc.GetObjectFQNsInSchemas([]string{"sales", `"sales$1"`})
+		})
+		It("substitutes the schema of an object and the objects it references", func() {
+			statements := utils.SubstituteRedirectSchemaInStatements([]utils.StatementWithType{table}, []string{"sales"}, "archive", objectFQNs)
+			Expect(statements[0].Schema).To(Equal("archive"))
+			Expect(statements[0].Statement).To(Equal(`CREATE TABLE archive.orders (
+	id integer DEFAULT nextval('archive.orders_id_seq'::regclass) NOT NULL,
+	status archive.order_status
+) DISTRIBUTED BY (id);`))
+		})
+		It("substitutes the schema in a view body without changing schemas that end with the same name", func() {
+			statements := utils.SubstituteRedirectSchemaInStatements([]utils.StatementWithType{view}, []string{"sales"}, "archive", objectFQNs)
+			Expect(statements[0].Statement).To(Equal(`CREATE VIEW archive.open_orders AS  SELECT orders.id
+   FROM archive.orders, presales.orders
+  WHERE (orders.status = 'open'::archive.order_status);`))
+		})
+		It("substitutes the schema of the reference object", func() {
+			statements := utils.SubstituteRedirectSchemaInStatements([]utils.StatementWithType{index}, []string{"sales"}, "archive", objectFQNs)
+			Expect(statements[0].Schema).To(Equal("archive"))
+			Expect(statements[0].ReferenceObject).To(Equal("archive.orders"))
+			Expect(statements[0].Statement).To(Equal("CREATE INDEX orders_idx ON archive.orders USING btree (id);"))
+		})
+		It("substitutes schemas whose names contain special characters", func() {
+			special := utils.StatementWithType{Schema: `"sales$1"`, Name: "orders", ObjectType: "TABLE", Statement: `CREATE TABLE "sales$1".orders (id integer);`}
+			statements := utils.SubstituteRedirectSchemaInStatements([]utils.StatementWithType{special}, []string{`"sales$1"`}, `"archive$1"`, objectFQNs)
+			Expect(statements[0].Schema).To(Equal(`"archive$1"`))
+			Expect(statements[0].Statement).To(Equal(`CREATE TABLE "archive$1".orders (id integer);`))
+		})
+		It("does not substitute a column qualified by a table with the same name as the schema", func() {
+			salesView := utils.StatementWithType{Schema: "sales", Name: "sales_ids", ObjectType: "VIEW", Statement: "CREATE VIEW sales.sales_ids AS  SELECT sales.id\n   FROM sales.sales;"}
+			statements := utils.SubstituteRedirectSchemaInStatements([]utils.StatementWithType{salesView}, []string{"sales"}, "archive", objectFQNs)
+			Expect(statements[0].Statement).To(Equal("CREATE VIEW archive.sales_ids AS  SELECT sales.id\n   FROM archive.sales;"))
+		})
+		It("does not substitute the schema in string literals or comments", func() {
+			constraint := utils.StatementWithType{Schema: "sales", Name: "region_check", ObjectType: "CONSTRAINT", ReferenceObject: "sales.orders", Statement: "ALTER TABLE ONLY sales.orders ADD CONSTRAINT region_check CHECK (region <> 'sales.orders' AND region <> E'sales\\'s.orders');\n-- sales.orders\nCOMMENT ON CONSTRAINT region_check ON sales.orders IS 'sales.orders';"}
+			statements := utils.SubstituteRedirectSchemaInStatements([]utils.StatementWithType{constraint}, []string{"sales"}, "archive", objectFQNs)
+			Expect(statements[0].ReferenceObject).To(Equal("archive.orders"))
+			Expect(statements[0].Statement).To(Equal("ALTER TABLE ONLY archive.orders ADD CONSTRAINT region_check CHECK (region <> 'sales.orders' AND region <> E'sales\\'s.orders');\n-- sales.orders\nCOMMENT ON CONSTRAINT region_check ON archive.orders IS 'sales.orders';"))
+		})
+		It("substitutes the schema of a function but not in its body and logs a warning", func() {
+			function := utils.StatementWithType{Schema: "sales", Name: "count_orders()", ObjectType: "FUNCTION", Statement: "CREATE FUNCTION sales.count_orders() RETURNS sales.order_status AS $$SELECT count(*) FROM sales.orders$$\nLANGUAGE sql;"}
+			statements := utils.SubstituteRedirectSchemaInStatements([]utils.StatementWithType{function}, []string{"sales"}, "archive", objectFQNs)
+			Expect(statements[0].Schema).To(Equal("archive"))
+			Expect(statements[0].Statement).To(Equal("CREATE FUNCTION archive.count_orders() RETURNS archive.order_status AS $$SELECT count(*) FROM sales.orders$$\nLANGUAGE sql;"))
+			Expect(string(logfile.Contents())).To(ContainSubstring("[WARNING]:-The body of FUNCTION archive.count_orders() refers to schema sales, which will not be changed to archive"))
+		})
+		It("does not modify objects in schemas that are not redirected", func() {
+			other := utils.StatementWithType{Schema: "public", Name: "t", ObjectType: "TABLE", Statement: "CREATE TABLE public.t (id integer);"}
+			statements := utils.SubstituteRedirectSchemaInStatements([]utils.StatementWithType{other}, []string{"sales"}, "archive", objectFQNs)
+			Expect(statements[0]).To(Equal(other))
+		})
+	})
+	Describe("SubstituteTableMapInStatements", func() {
+		tableMap := map[string]string{"sales.orders": "sales.orders_recovered"}
+		It("renames a mapped table and the references to it in its own metadata", func() {
+			statements := []utils.StatementWithType{
+				{Schema: "sales", Name: "orders", ObjectType: "TABLE", Statement: "CREATE TABLE sales.orders (\n\tid integer DEFAULT nextval('sales.orders_id_seq'::regclass)\n) DISTRIBUTED BY (id);"},
+				{Schema: "sales", Name: "orders", ObjectType: "TABLE", Statement: "COMMENT ON TABLE sales.orders IS 'orders';\n\nREVOKE ALL ON TABLE sales.orders FROM PUBLIC;\nGRANT SELECT ON TABLE sales.orders TO reporting;"},
+			}
+			statements = utils.SubstituteTableMapInStatements(statements, tableMap)
+			Expect(statements[0].Name).To(Equal("orders_recovered"))
+			Expect(statements[0].Statement).To(Equal("CREATE TABLE sales.orders_recovered (\n\tid integer DEFAULT nextval('sales.orders_id_seq'::regclass)\n) DISTRIBUTED BY (id);"))
+			Expect(statements[1].Statement).To(Equal("COMMENT ON TABLE sales.orders_recovered IS 'orders';\n\nREVOKE ALL ON TABLE sales.orders_recovered FROM PUBLIC;\nGRANT SELECT ON TABLE sales.orders_recovered TO reporting;"))
+		})
+		It("renames indexes and constraints after the new table", func() {
+			statements := []utils.StatementWithType{
+				{Schema: "sales", Name: "orders_pkey", ObjectType: "CONSTRAINT", ReferenceObject: "sales.orders", Statement: "ALTER TABLE ONLY sales.orders ADD CONSTRAINT orders_pkey PRIMARY KEY (id);"},
+				{Schema: "sales", Name: "status_idx", ObjectType: "INDEX", ReferenceObject: "sales.orders", Statement: "CREATE INDEX status_idx ON sales.orders USING btree (status);\nCOMMENT ON INDEX sales.status_idx IS 'status';"},
+			}
+			statements = utils.SubstituteTableMapInStatements(statements, tableMap)
+			Expect(statements[0].Name).To(Equal("orders_recovered_pkey"))
+			Expect(statements[0].ReferenceObject).To(Equal("sales.orders_recovered"))
+			Expect(statements[0].Statement).To(Equal("ALTER TABLE ONLY sales.orders_recovered ADD CONSTRAINT orders_recovered_pkey PRIMARY KEY (id);"))
+			Expect(statements[1].Name).To(Equal("orders_recovered_status_idx"))
+			Expect(statements[1].Statement).To(Equal("CREATE INDEX orders_recovered_status_idx ON sales.orders_recovered USING btree (status);\nCOMMENT ON INDEX sales.orders_recovered_status_idx IS 'status';"))
+		})
+		It("moves a table and its dependent objects to the schema of the new table", func() {
+			statements := []utils.StatementWithType{
+				{Schema: "sales", Name: "orders", ObjectType: "TABLE", Statement: "CREATE TABLE sales.orders (id integer);"},
+				{Schema: "sales", Name: "orders_trigger", ObjectType: "TRIGGER", ReferenceObject: "sales.orders", Statement: "CREATE TRIGGER orders_trigger AFTER INSERT ON sales.orders FOR EACH ROW EXECUTE PROCEDURE sales.log_order();"},
+				{Schema: "sales", Name: "orders_idx", ObjectType: "INDEX", ReferenceObject: "sales.orders", Statement: "ALTER INDEX sales.orders_idx SET TABLESPACE fastspace;"},
+			}
+			statements = utils.SubstituteTableMapInStatements(statements, map[string]string{"sales.orders": `archive."Orders"`})
+			Expect(statements[0].Schema).To(Equal("archive"))
+			Expect(statements[0].Statement).To(Equal(`CREATE TABLE archive."Orders" (id integer);`))
+			Expect(statements[1].Schema).To(Equal("sales"))
+			Expect(statements[1].Statement).To(Equal(`CREATE TRIGGER orders_trigger AFTER INSERT ON archive."Orders" FOR EACH ROW EXECUTE PROCEDURE sales.log_order();`))
+			Expect(statements[2].Schema).To(Equal("archive"))
+			Expect(statements[2].Name).To(Equal(`"Orders_idx"`))
+			Expect(statements[2].Statement).To(Equal(`ALTER INDEX archive."Orders_idx" SET TABLESPACE fastspace;`))
+		})
+		It("does not modify statements of tables that are not mapped", func() {
+			other := utils.StatementWithType{Schema: "sales", Name: "orders_archive", ObjectType: "TABLE", Statement: "CREATE TABLE sales.orders_archive (LIKE sales.orders);"}
+			statements := utils.SubstituteTableMapInStatements([]utils.StatementWithType{other}, tableMap)
+			Expect(statements[0].Name).To(Equal("orders_archive"))
+		})
+	})
 	Describe("RemoveActiveRoles", func() {
 		user1 := utils.StatementWithType{Name: "user1", ObjectType: "ROLE", Statement: "CREATE ROLE user1 SUPERUSER;\n"}
 		user2 := utils.StatementWithType{Name: "user2", ObjectType: "ROLE", Statement: "CREATE ROLE user2;\n"}
@@ -376,22 +577,22 @@ COMMENT ON DATABASE "db-special-chär$" IS 'this is a database comment';`}
 	})
 	Describe("GetIncludedPartitionRoots", func() {
 		It("does not return anything if relations are not leaf partitions", func() {
-			toc.AddMasterDataEntry("schema0", "name0", 0, "attribute0", 1, "")
-			toc.AddMasterDataEntry("schema1", "name1", 1, "attribute0", 1, "")
+			toc.AddMasterDataEntry("schema0", "name0", 0, "attribute0", 1, "", "")
+			toc.AddMasterDataEntry("schema1", "name1", 1, "attribute0", 1, "", "")
 			roots := utils.GetIncludedPartitionRoots(toc.DataEntries, []string{"schema0.name0", "schema1.name1"})
 			Expect(roots).To(BeEmpty())
 		})
 		It("returns root parition of leaf partitions", func() {
-			toc.AddMasterDataEntry("schema0", "name0", 2, "attribute0", 1, "root0")
-			toc.AddMasterDataEntry("schema1", "name1", 3, "attribute0", 1, "root1")
+			toc.AddMasterDataEntry("schema0", "name0", 2, "attribute0", 1, "root0", "")
+			toc.AddMasterDataEntry("schema1", "name1", 3, "attribute0", 1, "root1", "")
 			roots := utils.GetIncludedPartitionRoots(toc.DataEntries, []string{"schema0.name0", "schema1.name1"})
 			Expect(roots).To(ConsistOf("schema0.root0", "schema1.root1"))
 		})
 		It("only returns root partitions of leaf partitions", func() {
-			toc.AddMasterDataEntry("schema0", "name0", 0, "attribute0", 1, "")
-			toc.AddMasterDataEntry("schema1", "name1", 1, "attribute0", 1, "")
-			toc.AddMasterDataEntry("schema2", "name2", 2, "attribute0", 1, "root2")
-			toc.AddMasterDataEntry("schema3", "name3", 3, "attribute0", 1, "root3")
+			toc.AddMasterDataEntry("schema0", "name0", 0, "attribute0", 1, "", "")
+			toc.AddMasterDataEntry("schema1", "name1", 1, "attribute0", 1, "", "")
+			toc.AddMasterDataEntry("schema2", "name2", 2, "attribute0", 1, "root2", "")
+			toc.AddMasterDataEntry("schema3", "name3", 3, "attribute0", 1, "root3", "")
 			roots := utils.GetIncludedPartitionRoots(toc.DataEntries, []string{"schema2.name2", "schema3.name3"})
 			Expect(roots).To(ConsistOf("schema2.root2", "schema3.root3"))
 		})
@@ -400,18 +601,18 @@ COMMENT ON DATABASE "db-special-chär$" IS 'this is a database comment';`}
 			Expect(roots).To(BeEmpty())
 		})
 		It("returns nothing if relation is not part of TOC data entries", func() {
-			toc.AddMasterDataEntry("schema0", "name0", 0, "attribute0", 1, "")
-			toc.AddMasterDataEntry("schema1", "name1", 1, "attribute0", 1, "")
-			toc.AddMasterDataEntry("schema2", "name2", 2, "attribute0", 1, "root2")
-			toc.AddMasterDataEntry("schema3", "name3", 3, "attribute0", 1, "root3")
+			toc.AddMasterDataEntry("schema0", "name0", 0, "attribute0", 1, "", "")
+			toc.AddMasterDataEntry("schema1", "name1", 1, "attribute0", 1, "", "")
+			toc.AddMasterDataEntry("schema2", "name2", 2, "attribute0", 1, "root2", "")
+			toc.AddMasterDataEntry("schema3", "name3", 3, "attribute0", 1, "root3", "")
 			roots := utils.GetIncludedPartitionRoots(toc.DataEntries, []string{"schema4.name4", "schema5.name5"})
 			Expect(roots).To(BeEmpty())
 		})
 		It("returns empty if no relations are passed in", func() {
-			toc.AddMasterDataEntry("schema0", "name0", 0, "attribute0", 1, "")
-			toc.AddMasterDataEntry("schema1", "name1", 1, "attribute0", 1, "")
-			toc.AddMasterDataEntry("schema2", "name2", 2, "attribute0", 1, "root2")
-			toc.AddMasterDataEntry("schema3", "name3", 3, "attribute0", 1, "root3")
+			toc.AddMasterDataEntry("schema0", "name0", 0, "attribute0", 1, "", "")
+			toc.AddMasterDataEntry("schema1", "name1", 1, "attribute0", 1, "", "")
+			toc.AddMasterDataEntry("schema2", "name2", 2, "attribute0", 1, "root2", "")
+			toc.AddMasterDataEntry("schema3", "name3", 3, "attribute0", 1, "root3", "")
 			roots := utils.GetIncludedPartitionRoots(toc.DataEntries, []string{})
 			Expect(roots).To(BeEmpty())
 		})
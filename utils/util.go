@@ -50,6 +50,14 @@ func MakeFQN(schema string, object string) string {
 	return fmt.Sprintf("%s.%s", schema, object)
 }
 
+// Global objects, such as roles and schemas, are not qualified by a schema
+func MakeObjectName(schema string, name string) string {
+	if schema == "" {
+		return name
+	}
+	return MakeFQN(schema, name)
+}
+
 func ValidateFQNs(fqns []string) {
 	unquotedIdentString := "[a-z_][a-z0-9_]*"
 	validIdentString := fmt.Sprintf("(?:\"(.*)\"|(%s))", unquotedIdentString)
@@ -62,6 +70,52 @@ func ValidateFQNs(fqns []string) {
 	}
 }
 
+// This function assumes that the FQN has already been validated with ValidateFQNs
+func SplitFQN(fqn string) (string, string) {
+	inQuotes := false
+	for i, char := range fqn {
+		if char == '"' {
+			inQuotes = !inQuotes
+		} else if char == '.' && !inQuotes {
+			return fqn[:i], fqn[i+1:]
+		}
+	}
+	return "", fqn
+}
+
+/*
+ * Each line of a table map file maps a table in the backup to the table to
+ * which it will be restored, in the format "schema.table -> schema.newtable".
+ * Blank lines are ignored.
+ */
+func ParseTableMap(lines []string) map[string]string {
+	tableMap := make(map[string]string, 0)
+	newFQNs := make(map[string]bool, 0)
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fqns := strings.Split(line, "->")
+		if len(fqns) != 2 {
+			gplog.Fatal(errors.Errorf("Line %d of the table map file is invalid.  Each line must be in the format schema.table -> schema.newtable.", i+1), "")
+		}
+		oldFQN, newFQN := strings.TrimSpace(fqns[0]), strings.TrimSpace(fqns[1])
+		ValidateFQNs([]string{oldFQN, newFQN})
+		if _, ok := tableMap[oldFQN]; ok {
+			gplog.Fatal(errors.Errorf("Table %s is mapped more than once in the table map file", oldFQN), "")
+		}
+		if newFQNs[newFQN] {
+			gplog.Fatal(errors.Errorf("More than one table is mapped to %s in the table map file", newFQN), "")
+		}
+		tableMap[oldFQN] = newFQN
+		newFQNs[newFQN] = true
+	}
+	if len(tableMap) == 0 {
+		gplog.Fatal(errors.Errorf("The table map file does not map any tables"), "")
+	}
+	return tableMap
+}
+
 func ValidateFullPath(path string) error {
 	if len(path) > 0 && !(strings.HasPrefix(path, "/") || strings.HasPrefix(path, "~")) {
 		return errors.Errorf("%s is not an absolute path.", path)
@@ -96,6 +150,40 @@ AND procpid <> pg_backend_pid()`, appName, copyFileName)
 	_, _ = connectionPool.Exec(query)
 }
 
+/*
+ * The checksum of a table is its row count followed by the sum of a hash of
+ * the text of each row, so that it does not depend on the order in which rows
+ * are stored or on the segment on which each row is stored.  Rows of child
+ * tables are only included for partition tables, as the data of inheritance
+ * children is backed up and restored separately.
+ */
+func GetTableChecksum(connectionPool *dbconn.DBConn, tableFQN string, whichConn int) (string, error) {
+	escapedFQN := EscapeSingleQuotes(tableFQN)
+	query := fmt.Sprintf(`SELECT count(*) || ':' || coalesce(sum(('x' || substr(md5(CAST(gpbackup_row AS text)), 1, 15))::bit(60)::bigint), 0) AS string
+FROM %s gpbackup_row
+WHERE gpbackup_row.tableoid = '%s'::regclass
+OR EXISTS (SELECT 1 FROM pg_partition WHERE parrelid = '%s'::regclass)`, tableFQN, escapedFQN, escapedFQN)
+	return dbconn.SelectString(connectionPool, query, whichConn)
+}
+
+/*
+ * The text of a row depends on these settings, so checksums are computed with
+ * the same settings at backup and restore time.  Settings that do not exist in
+ * older versions already produce the output that these settings select.
+ */
+func GetChecksumSessionSettings(connectionPool *dbconn.DBConn) []string {
+	settings := []string{
+		"SET DATESTYLE = ISO",
+		"SET TIMEZONE TO 'UTC'",
+		"SET extra_float_digits TO 0",
+		"SET lc_monetary TO 'C'",
+	}
+	if connectionPool.Version.AtLeast("6") {
+		settings = append(settings, "SET INTERVALSTYLE = POSTGRES", "SET bytea_output TO 'escape'")
+	}
+	return settings
+}
+
 func ValidateGPDBVersionCompatibility(connectionPool *dbconn.DBConn) {
 	if connectionPool.Version.Before(MINIMUM_GPDB4_VERSION) {
 		gplog.Fatal(errors.Errorf(`GPDB version %s is not supported. Please upgrade to GPDB %s.0 or later.`, connectionPool.Version.VersionString, MINIMUM_GPDB4_VERSION), "")
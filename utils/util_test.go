@@ -1,11 +1,13 @@
 package utils_test
 
 import (
+	"regexp"
 	"time"
 
 	"github.com/greenplum-db/gp-common-go-libs/operating"
 	"github.com/greenplum-db/gp-common-go-libs/testhelper"
 	"github.com/greenplum-db/gpbackup/utils"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -46,6 +48,14 @@ var _ = Describe("utils/util tests", func() {
 			Expect(actual).To(Equal(expected))
 		})
 	})
+	Describe("MakeObjectName", func() {
+		It("qualifies an object in a schema", func() {
+			Expect(utils.MakeObjectName("public", "foo")).To(Equal("public.foo"))
+		})
+		It("does not qualify an object that is not in a schema", func() {
+			Expect(utils.MakeObjectName("", "somerole")).To(Equal("somerole"))
+		})
+	})
 	Describe("ValidateFQNs", func() {
 		It("validates an unquoted string", func() {
 			testStrings := []string{`schemaname.tablename`}
@@ -100,6 +110,68 @@ var _ = Describe("utils/util tests", func() {
 
 		})
 	})
+	Describe("SplitFQN", func() {
+		It("splits an unquoted FQN", func() {
+			schema, name := utils.SplitFQN("sales.orders")
+			Expect(schema).To(Equal("sales"))
+			Expect(name).To(Equal("orders"))
+		})
+		It("splits an FQN with periods and quotes in its quoted identifiers", func() {
+			schema, name := utils.SplitFQN(`"sales.2018"."""orders.old"""`)
+			Expect(schema).To(Equal(`"sales.2018"`))
+			Expect(name).To(Equal(`"""orders.old"""`))
+		})
+	})
+	Describe("ParseTableMap", func() {
+		It("parses each line into a mapping from the old table to the new table", func() {
+			tableMap := utils.ParseTableMap([]string{"sales.orders -> sales.orders_recovered", "", `public."Customers"->archive.customers`})
+			Expect(tableMap).To(Equal(map[string]string{"sales.orders": "sales.orders_recovered", `public."Customers"`: "archive.customers"}))
+		})
+		It("panics if a line is not a mapping", func() {
+			defer testhelper.ShouldPanicWithMessage("Line 2 of the table map file is invalid.  Each line must be in the format schema.table -> schema.newtable.")
+			utils.ParseTableMap([]string{"sales.orders -> sales.orders_recovered", "sales.customers"})
+		})
+		It("panics if a table is not fully-qualified", func() {
+			defer testhelper.ShouldPanicWithMessage("Table orders_recovered is not correctly fully-qualified.")
+			utils.ParseTableMap([]string{"sales.orders -> orders_recovered"})
+		})
+		It("panics if a table is mapped more than once", func() {
+			defer testhelper.ShouldPanicWithMessage("Table sales.orders is mapped more than once in the table map file")
+			utils.ParseTableMap([]string{"sales.orders -> sales.orders1", "sales.orders -> sales.orders2"})
+		})
+		It("panics if two tables are mapped to the same table", func() {
+			defer testhelper.ShouldPanicWithMessage("More than one table is mapped to sales.orders_recovered in the table map file")
+			utils.ParseTableMap([]string{"sales.orders -> sales.orders_recovered", "sales.orders2 -> sales.orders_recovered"})
+		})
+		It("panics if no tables are mapped", func() {
+			defer testhelper.ShouldPanicWithMessage("The table map file does not map any tables")
+			utils.ParseTableMap([]string{""})
+		})
+	})
+	Describe("GetTableChecksum", func() {
+		It("queries the row count and summed row hashes of a table", func() {
+			query := regexp.QuoteMeta(`SELECT count(*) || ':' || coalesce(sum(('x' || substr(md5(CAST(gpbackup_row AS text)), 1, 15))::bit(60)::bigint), 0) AS string
+FROM public."foo's" gpbackup_row
+WHERE gpbackup_row.tableoid = 'public."foo''s"'::regclass
+OR EXISTS (SELECT 1 FROM pg_partition WHERE parrelid = 'public."foo''s"'::regclass)`)
+			mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"string"}).AddRow("10:123456789"))
+
+			checksum, err := utils.GetTableChecksum(connectionPool, `public."foo's"`, 0)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(checksum).To(Equal("10:123456789"))
+		})
+	})
+	Describe("GetChecksumSessionSettings", func() {
+		It("sets the output format of dates, times, floats, and money", func() {
+			testhelper.SetDBVersion(connectionPool, "5.1.0")
+			Expect(utils.GetChecksumSessionSettings(connectionPool)).To(Equal([]string{"SET DATESTYLE = ISO", "SET TIMEZONE TO 'UTC'", "SET extra_float_digits TO 0", "SET lc_monetary TO 'C'"}))
+		})
+		It("also sets the output format of intervals and bytea in GPDB 6", func() {
+			testhelper.SetDBVersion(connectionPool, "6.0.0")
+			Expect(utils.GetChecksumSessionSettings(connectionPool)).To(ConsistOf("SET DATESTYLE = ISO", "SET TIMEZONE TO 'UTC'", "SET extra_float_digits TO 0", "SET lc_monetary TO 'C'", "SET INTERVALSTYLE = POSTGRES", "SET bytea_output TO 'escape'"))
+		})
+	})
 	Describe("ValidateGPDBVersionCompatibility", func() {
 		It("panics if GPDB version is less than 4.3.17", func() {
 			testhelper.SetDBVersion(connectionPool, "4.3.14")